@@ -7,13 +7,14 @@ import (
 	"strings"
 	"syscall"
 
-	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/term"
 
+	"github.com/jafoor/carhub/libs/auth"
 	"github.com/jafoor/carhub/libs/config"
 	"github.com/jafoor/carhub/libs/database"
 	"github.com/jafoor/carhub/libs/logger"
 	"github.com/jafoor/carhub/libs/models"
+	libRepository "github.com/jafoor/carhub/libs/repository"
 	"github.com/jafoor/carhub/services/admin/repository"
 	"gorm.io/gorm"
 )
@@ -109,8 +110,24 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Apply the same password policy as every other credential path
+	passwords := auth.NewPasswordPolicy(libRepository.NewPasswordHistoryRepository())
+	subject := auth.PasswordSubject{
+		OwnerType: models.OwnerTypeAdmin,
+		Email:     emailLower,
+		FirstName: strings.TrimSpace(*firstName),
+		LastName:  strings.TrimSpace(*lastName),
+	}
+	if err := passwords.Validate(*password, subject); err != nil {
+		for _, violation := range auth.PasswordViolations(err) {
+			logger.Error().Str("rule", violation.Code).Msg(violation.Message)
+		}
+		logger.Error().Err(err).Msg("Password rejected")
+		os.Exit(1)
+	}
+
 	// Hash password
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(*password), bcrypt.DefaultCost)
+	passwordHash, err := auth.HashPassword(*password)
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to hash password")
 		os.Exit(1)
@@ -122,7 +139,8 @@ func main() {
 		LastName:       strings.TrimSpace(*lastName),
 		Email:          emailLower,
 		Phone:          getPhonePtr(*phone),
-		PasswordHash:   string(passwordHash),
+		PasswordHash:   passwordHash,
+		Status:         models.AdminStatusActive,
 		EmailVerified:  true,
 		IsActive:       true,
 		PasswordChanged: false,
@@ -148,6 +166,11 @@ func main() {
 			return fmt.Errorf("failed to assign super_admin role: %w", err)
 		}
 
+		subject.OwnerID = admin.ID
+		if err := passwords.Record(tx, subject, admin.PasswordHash); err != nil {
+			return fmt.Errorf("failed to record password history: %w", err)
+		}
+
 		return nil
 	})
 
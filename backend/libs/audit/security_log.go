@@ -0,0 +1,57 @@
+// libs/audit/security_log.go
+package audit
+
+import (
+	"encoding/json"
+
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/logger"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/libs/repository"
+)
+
+var securityEventRepo = repository.NewSecurityEventRepository()
+
+// SecurityEvent describes what happened, to whom and from where
+type SecurityEvent struct {
+	EventType string
+	OwnerType models.OwnerType
+	OwnerID   uint
+	IPAddress string
+	UserAgent string
+	Details   map[string]interface{}
+}
+
+// LogSecurityEvent writes the event to the application log and the
+// security_events table. Persistence failures are logged, never returned.
+func LogSecurityEvent(event SecurityEvent) {
+	logger.Warn().
+		Str("event_type", event.EventType).
+		Str("owner_type", string(event.OwnerType)).
+		Uint("owner_id", event.OwnerID).
+		Str("ip", event.IPAddress).
+		Interface("details", event.Details).
+		Msg("Security event")
+
+	if database.WriteDB == nil {
+		return
+	}
+
+	record := &models.SecurityEvent{
+		EventType: event.EventType,
+		OwnerType: event.OwnerType,
+		OwnerID:   event.OwnerID,
+		IPAddress: event.IPAddress,
+		UserAgent: event.UserAgent,
+		Details:   "{}",
+	}
+	if event.Details != nil {
+		if details, err := json.Marshal(event.Details); err == nil {
+			record.Details = string(details)
+		}
+	}
+
+	if err := securityEventRepo.Create(database.WriteDB, record); err != nil {
+		logger.Error().Err(err).Str("event_type", event.EventType).Msg("Failed to record security event")
+	}
+}
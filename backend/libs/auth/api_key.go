@@ -0,0 +1,86 @@
+// libs/auth/api_key.go
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/logger"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/libs/repository"
+	"gorm.io/gorm"
+)
+
+// Partner API keys look like chp_<12 hex>_<64 hex>. The part before the
+// second underscore is the public prefix used to look the key up.
+const partnerAPIKeyTag = "chp"
+
+// apiKeyTouchInterval limits last-used writes to one per key per interval
+const apiKeyTouchInterval = time.Minute
+
+// GeneratePartnerAPIKey returns a new key with the prefix and hash to store
+func GeneratePartnerAPIKey() (key, prefix, hash string, err error) {
+	id := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = partnerAPIKeyTag + "_" + hex.EncodeToString(id)
+	key = prefix + "_" + hex.EncodeToString(secret)
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the stored form of a key. Keys carry 256 random bits,
+// so a plain SHA-256 is enough; there is nothing to brute-force.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuthenticatePartnerAPIKey resolves a presented key to an active key of an
+// eligible partner and records its use. Every failure is invalid_api_key so
+// callers can't tell unknown, revoked and expired keys apart.
+func AuthenticatePartnerAPIKey(repo repository.PartnerAPIKeyRepository, key, clientIP string) (*models.PartnerAPIKey, error) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != partnerAPIKeyTag {
+		return nil, errors.New("invalid_api_key")
+	}
+
+	stored, err := repo.FindByPrefix(parts[0] + "_" + parts[1])
+	if err != nil {
+		return nil, errors.New("database_error")
+	}
+	if stored == nil || subtle.ConstantTimeCompare([]byte(stored.KeyHash), []byte(HashAPIKey(key))) != 1 {
+		return nil, errors.New("invalid_api_key")
+	}
+	if stored.CurrentState() != models.APIKeyActive {
+		return nil, errors.New("invalid_api_key")
+	}
+	if stored.Partner == nil || !stored.Partner.EmailVerified || stored.Partner.Status == models.StatusSuspended {
+		return nil, errors.New("invalid_api_key")
+	}
+
+	now := time.Now()
+	if stored.LastUsedAt == nil || now.Sub(*stored.LastUsedAt) >= apiKeyTouchInterval || stored.LastUsedIP != clientIP {
+		if err := database.ExecuteTransaction(func(tx *gorm.DB) error {
+			return repo.TouchLastUsed(tx, stored.ID, now, clientIP)
+		}); err != nil {
+			logger.Error().Err(err).Uint("api_key_id", stored.ID).Msg("Failed to record API key use")
+		} else {
+			stored.LastUsedAt = &now
+			stored.LastUsedIP = clientIP
+		}
+	}
+
+	return stored, nil
+}
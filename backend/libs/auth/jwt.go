@@ -6,64 +6,119 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/jafoor/carhub/libs/config"
 	"github.com/jafoor/carhub/libs/models"
 )
 
+// Audiences keep partner and admin tokens from being accepted in each other's place
+const (
+	PartnerAudience = "carhub-partner"
+	AdminAudience   = "carhub-admin"
+)
+
+// Actor is the "act" claim (RFC 8693) of an impersonation token: the super
+// admin really making the requests on the subject's behalf
+type Actor struct {
+	AdminID uint   `json:"admin_id"`
+	Email   string `json:"email"`
+}
+
 type PartnerClaims struct {
 	PartnerID  uint   `json:"partner_id"`
 	FirstName  string `json:"first_name"`
 	LastName   string `json:"last_name"`
 	TokenType  string `json:"token_type,omitempty"`
+	SessionID  string `json:"sid,omitempty"` // refresh-token session the token belongs to
+	Generation int    `json:"gen,omitempty"` // rotation counter, refresh tokens only
+	Act        *Actor `json:"act,omitempty"` // set on impersonation tokens only
 	jwt.RegisteredClaims
 }
 
 type AdminClaims struct {
-	AdminID   uint     `json:"admin_id"`
-	FirstName string   `json:"first_name"`
-	LastName  string   `json:"last_name"`
-	Roles     []string `json:"roles"` // Array of role names
-	TokenType string   `json:"token_type,omitempty"`
+	AdminID    uint     `json:"admin_id"`
+	FirstName  string   `json:"first_name"`
+	LastName   string   `json:"last_name"`
+	Roles      []string `json:"roles"` // Array of role names
+	TokenType  string   `json:"token_type,omitempty"`
+	SessionID  string   `json:"sid,omitempty"` // refresh-token session the token belongs to
+	Generation int      `json:"gen,omitempty"` // rotation counter, refresh tokens only
+	// PasswordChangeRequired is set while the admin still uses an initial password
+	PasswordChangeRequired bool `json:"password_change_required,omitempty"`
+	// TwoFactorSetupRequired is set when a role demands 2FA the admin hasn't enrolled in
+	TwoFactorSetupRequired bool `json:"two_factor_setup_required,omitempty"`
+	// AuthzVersion is the admin's authorization version when the token was issued
+	AuthzVersion int    `json:"azv"`
+	Act          *Actor `json:"act,omitempty"` // set on impersonation tokens only
 	jwt.RegisteredClaims
 }
 
+// Impersonating reports whether the token was minted for a super admin
+// acting as the partner
+func (c *PartnerClaims) Impersonating() bool {
+	return c.TokenType == "impersonation" && c.Act != nil
+}
+
+// Impersonating reports whether the token was minted for a super admin
+// acting as the admin
+func (c *AdminClaims) Impersonating() bool {
+	return c.TokenType == "impersonation" && c.Act != nil
+}
+
+// registeredClaims fills the standard claims every token carries: a unique
+// jti so it can be revoked, iat, iss, aud and the expiry
+func registeredClaims(audience string, ttl time.Duration) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		ID:        uuid.NewString(),
+		Issuer:    config.App.JWTIssuer,
+		Audience:  jwt.ClaimStrings{audience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+}
+
+// parserOptions are the checks applied on top of signature and expiry
+func parserOptions(audience string) []jwt.ParserOption {
+	return []jwt.ParserOption{
+		jwt.WithValidMethods(validMethods()),
+		jwt.WithIssuer(config.App.JWTIssuer),
+		jwt.WithAudience(audience),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	}
+}
+
 // GeneratePartnerAccessToken - short-lived (e.g., 15 minutes)
-func GeneratePartnerAccessToken(partner *models.Partner) (string, error) {
-	expirationTime := time.Now().Add(time.Minute * time.Duration(config.App.AccessTokenTTL))
+func GeneratePartnerAccessToken(partner *models.Partner, sessionID string) (string, error) {
 	claims := &PartnerClaims{
-		PartnerID: partner.ID,
-		FirstName: partner.FirstName,
-		LastName:  partner.LastName,
-		TokenType: "access",
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
+		PartnerID:        partner.ID,
+		FirstName:        partner.FirstName,
+		LastName:         partner.LastName,
+		TokenType:        "access",
+		SessionID:        sessionID,
+		RegisteredClaims: registeredClaims(PartnerAudience, time.Minute*time.Duration(config.App.AccessTokenTTL)),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.App.JWTSecret))
+	return signToken(claims)
 }
 
 // GeneratePartnerRefreshToken - long-lived (e.g., 7 days)
-func GeneratePartnerRefreshToken(partner *models.Partner) (string, error) {
+func GeneratePartnerRefreshToken(partner *models.Partner, sessionID string, generation int) (string, error) {
 	// ⚠️ CRITICAL: Use REFRESH_TOKEN_TTL (not AccessTokenTTL)
-	expirationTime := time.Now().Add(time.Hour * 24 * time.Duration(config.App.RefreshTokenTTL)) // e.g., 7 days
 	claims := &PartnerClaims{
-		PartnerID: partner.ID,
-		FirstName: partner.FirstName,
-		LastName:  partner.LastName,
-		TokenType: "refresh",
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
+		PartnerID:        partner.ID,
+		FirstName:        partner.FirstName,
+		LastName:         partner.LastName,
+		TokenType:        "refresh",
+		SessionID:        sessionID,
+		Generation:       generation,
+		RegisteredClaims: registeredClaims(PartnerAudience, time.Hour*24*time.Duration(config.App.RefreshTokenTTL)), // e.g., 7 days
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.App.JWTSecret))
+	return signToken(claims)
 }
 
 func VerifyPartnerToken(tokenStr string) (*PartnerClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &PartnerClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(config.App.JWTSecret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenStr, &PartnerClaims{}, keyFunc, parserOptions(PartnerAudience)...)
 	if err != nil {
 		return nil, errors.New("invalid token")
 	}
@@ -75,58 +130,147 @@ func VerifyPartnerToken(tokenStr string) (*PartnerClaims, error) {
 	return nil, errors.New("invalid token")
 }
 
+// GeneratePartnerImpersonationToken signs a short-lived access token for the
+// partner on behalf of actor. It has no session, so it can't be refreshed.
+func GeneratePartnerImpersonationToken(partner *models.Partner, actor *models.Admin) (string, *PartnerClaims, error) {
+	claims := &PartnerClaims{
+		PartnerID:        partner.ID,
+		FirstName:        partner.FirstName,
+		LastName:         partner.LastName,
+		TokenType:        "impersonation",
+		Act:              &Actor{AdminID: actor.ID, Email: actor.Email},
+		RegisteredClaims: registeredClaims(PartnerAudience, time.Minute*time.Duration(config.App.ImpersonationTTL)),
+	}
+	token, err := signToken(claims)
+	return token, claims, err
+}
+
+// GeneratePartnerMagicLinkToken signs the token carried by a sign-in link.
+// Like invite tokens it returns the jti and expiry so the token can be pinned.
+func GeneratePartnerMagicLinkToken(partner *models.Partner, ttl time.Duration) (string, string, time.Time, error) {
+	claims := &PartnerClaims{
+		PartnerID:        partner.ID,
+		FirstName:        partner.FirstName,
+		LastName:         partner.LastName,
+		TokenType:        "magic_link",
+		RegisteredClaims: registeredClaims(PartnerAudience, ttl),
+	}
+	token, err := signToken(claims)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return token, claims.ID, claims.ExpiresAt.Time, nil
+}
+
 // GenerateAdminAccessToken - short-lived (e.g., 30 minutes for admin)
-func GenerateAdminAccessToken(admin *models.Admin, roles []models.AdminRole) (string, error) {
-	expirationTime := time.Now().Add(time.Minute * time.Duration(config.App.AdminAccessTokenTTL))
-	
-	// Extract role names
-	roleNames := make([]string, len(roles))
-	for i, role := range roles {
-		roleNames[i] = role.Name
-	}
-	
+func GenerateAdminAccessToken(admin *models.Admin, roles []models.AdminRole, sessionID string) (string, error) {
+	roleNames := AdminRoleNames(roles)
+
+	claims := &AdminClaims{
+		AdminID:                admin.ID,
+		FirstName:              admin.FirstName,
+		LastName:               admin.LastName,
+		Roles:                  roleNames,
+		TokenType:              "access",
+		SessionID:              sessionID,
+		PasswordChangeRequired: !admin.PasswordChanged,
+		TwoFactorSetupRequired: !admin.TwoFactorEnabled && RolesRequireTwoFactor(roles),
+		AuthzVersion:           admin.AuthzVersion,
+		RegisteredClaims:       registeredClaims(AdminAudience, time.Minute*time.Duration(config.App.AdminAccessTokenTTL)),
+	}
+	return signToken(claims)
+}
+
+// AdminRoleNames returns the role names carried in admin tokens. RequireRoles
+// trusts them everywhere, so roles held only within a region or city are left
+// out; those act through permissions, which know their scope.
+func AdminRoleNames(roles []models.AdminRole) []string {
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if !role.ScopedOnly {
+			names = append(names, role.Name)
+		}
+	}
+	return names
+}
+
+// RolesRequireTwoFactor reports whether any of the roles demands 2FA
+func RolesRequireTwoFactor(roles []models.AdminRole) bool {
+	for _, role := range roles {
+		if role.RequiresTwoFactor {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateAdminChallengeToken - short-lived proof that the password step of a
+// two-factor sign-in succeeded; it grants no API access by itself
+func GenerateAdminChallengeToken(admin *models.Admin) (string, error) {
 	claims := &AdminClaims{
-		AdminID:   admin.ID,
-		FirstName: admin.FirstName,
-		LastName:  admin.LastName,
-		Roles:     roleNames,
-		TokenType: "access",
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
+		AdminID:          admin.ID,
+		FirstName:        admin.FirstName,
+		LastName:         admin.LastName,
+		TokenType:        "2fa_challenge",
+		RegisteredClaims: registeredClaims(AdminAudience, time.Minute*time.Duration(config.App.AdminTwoFactorChallengeTTL)),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.App.JWTSecret))
+	return signToken(claims)
+}
+
+// GenerateAdminInviteToken signs the token carried by an invitation link. It
+// returns the jti and expiry so the invitation row can pin this exact token;
+// issuing a new one makes older links useless.
+func GenerateAdminInviteToken(admin *models.Admin) (string, string, time.Time, error) {
+	claims := &AdminClaims{
+		AdminID:          admin.ID,
+		FirstName:        admin.FirstName,
+		LastName:         admin.LastName,
+		TokenType:        "invite",
+		RegisteredClaims: registeredClaims(AdminAudience, time.Hour*time.Duration(config.App.AdminInviteTTL)),
+	}
+	token, err := signToken(claims)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return token, claims.ID, claims.ExpiresAt.Time, nil
+}
+
+// GenerateAdminImpersonationToken signs a short-lived access token for the
+// target admin on behalf of actor. It has no session, so it can't be refreshed.
+func GenerateAdminImpersonationToken(target *models.Admin, roles []models.AdminRole, actor *models.Admin) (string, *AdminClaims, error) {
+	claims := &AdminClaims{
+		AdminID:          target.ID,
+		FirstName:        target.FirstName,
+		LastName:         target.LastName,
+		Roles:            AdminRoleNames(roles),
+		TokenType:        "impersonation",
+		AuthzVersion:     target.AuthzVersion,
+		Act:              &Actor{AdminID: actor.ID, Email: actor.Email},
+		RegisteredClaims: registeredClaims(AdminAudience, time.Minute*time.Duration(config.App.ImpersonationTTL)),
+	}
+	token, err := signToken(claims)
+	return token, claims, err
 }
 
 // GenerateAdminRefreshToken - long-lived (e.g., 30 days for admin)
-func GenerateAdminRefreshToken(admin *models.Admin, roles []models.AdminRole) (string, error) {
-	expirationTime := time.Now().Add(time.Hour * 24 * time.Duration(config.App.AdminRefreshTokenTTL))
-	
-	// Extract role names
-	roleNames := make([]string, len(roles))
-	for i, role := range roles {
-		roleNames[i] = role.Name
-	}
-	
+func GenerateAdminRefreshToken(admin *models.Admin, roles []models.AdminRole, sessionID string, generation int) (string, error) {
+	roleNames := AdminRoleNames(roles)
+
 	claims := &AdminClaims{
-		AdminID:   admin.ID,
-		FirstName: admin.FirstName,
-		LastName:  admin.LastName,
-		Roles:     roleNames,
-		TokenType: "refresh",
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
+		AdminID:          admin.ID,
+		FirstName:        admin.FirstName,
+		LastName:         admin.LastName,
+		Roles:            roleNames,
+		TokenType:        "refresh",
+		SessionID:        sessionID,
+		Generation:       generation,
+		RegisteredClaims: registeredClaims(AdminAudience, time.Hour*24*time.Duration(config.App.AdminRefreshTokenTTL)),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.App.JWTSecret))
+	return signToken(claims)
 }
 
 func VerifyAdminToken(tokenStr string) (*AdminClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &AdminClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(config.App.JWTSecret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenStr, &AdminClaims{}, keyFunc, parserOptions(AdminAudience)...)
 	if err != nil {
 		return nil, errors.New("invalid token")
 	}
@@ -136,4 +280,4 @@ func VerifyAdminToken(tokenStr string) (*AdminClaims, error) {
 	}
 
 	return nil, errors.New("invalid token")
-}
\ No newline at end of file
+}
@@ -0,0 +1,257 @@
+// libs/auth/keys.go
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jafoor/carhub/libs/config"
+)
+
+// defaultKeyID is the kid of the HS256 key built from JWT_SECRET. Tokens
+// signed before key ids existed carry no kid and are verified with it.
+const defaultKeyID = "default"
+
+// jwtKey is one key of the set. Only the active signing key needs the private half.
+type jwtKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.PrivateKey // nil for verification-only keys
+	public  crypto.PublicKey  // the HMAC secret for HS256
+}
+
+// KeySet holds the key new tokens are signed with and every key still
+// accepted for verification, indexed by kid.
+type KeySet struct {
+	signing   *jwtKey
+	verifying map[string]*jwtKey
+}
+
+var (
+	keySet     *KeySet
+	keySetOnce sync.Once
+)
+
+// LoadKeys reads the signing keys from config. Call it once at startup, after
+// config.LoadConfig; a broken key configuration stops the process.
+func LoadKeys() {
+	keySetOnce.Do(func() {
+		ks, err := loadKeySet(config.App.JWTKeysDir, config.App.JWTSigningKeyID, config.App.JWTSecret)
+		if err != nil {
+			log.Fatalf("Error loading JWT keys: %v", err)
+		}
+		keySet = ks
+	})
+}
+
+func currentKeySet() *KeySet {
+	LoadKeys()
+	return keySet
+}
+
+// loadKeySet builds the key set. Without a keys directory tokens are HS256
+// signed with the shared secret. Otherwise every "<kid>.pem" file in the
+// directory (RSA or Ed25519, private or public) is a verification key and
+// signingKID names the private key used for new tokens. Retiring a key means
+// deleting its file once the tokens it signed have expired. A secret set next
+// to the directory stays a verify-only HS256 key under defaultKeyID, so tokens
+// issued before the switch keep working; unset it once they have expired.
+func loadKeySet(dir, signingKID, secret string) (*KeySet, error) {
+	if dir == "" {
+		if secret == "" {
+			return nil, errors.New("JWT_SECRET is required when JWT_KEYS_DIR is not set")
+		}
+		if signingKID == "" {
+			signingKID = defaultKeyID
+		}
+		key := &jwtKey{
+			kid:     signingKID,
+			method:  jwt.SigningMethodHS256,
+			private: []byte(secret),
+			public:  []byte(secret),
+		}
+		return &KeySet{signing: key, verifying: map[string]*jwtKey{key.kid: key}}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{verifying: make(map[string]*jwtKey)}
+	for _, file := range files {
+		key, err := parseKeyFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		ks.verifying[key.kid] = key
+	}
+
+	if secret != "" {
+		if _, ok := ks.verifying[defaultKeyID]; ok {
+			return nil, fmt.Errorf("key file %q clashes with JWT_SECRET's key id", defaultKeyID)
+		}
+		ks.verifying[defaultKeyID] = &jwtKey{
+			kid:    defaultKeyID,
+			method: jwt.SigningMethodHS256,
+			public: []byte(secret),
+		}
+	}
+
+	active, ok := ks.verifying[signingKID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q not found in %s", signingKID, dir)
+	}
+	if active.private == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", signingKID)
+	}
+	ks.signing = active
+
+	return ks, nil
+}
+
+// parseKeyFile reads a PEM key; the file name without extension is its kid
+func parseKeyFile(path string) (*jwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	key := &jwtKey{kid: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key.private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key.private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key.public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key.public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if signer, ok := key.private.(crypto.Signer); ok {
+		key.public = signer.Public()
+	}
+
+	switch pub := key.public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, errors.New("only RSA and Ed25519 keys are supported")
+	}
+
+	return key, nil
+}
+
+// signToken signs the claims with the active key and stamps its kid
+func signToken(claims jwt.Claims) (string, error) {
+	key := currentKeySet().signing
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.private)
+}
+
+// keyFunc picks the verification key named by the token's kid. Tokens
+// without a kid are checked against the JWT_SECRET key, or the active
+// signing key when there is none.
+func keyFunc(token *jwt.Token) (interface{}, error) {
+	ks := currentKeySet()
+
+	key := ks.signing
+	if legacy, ok := ks.verifying[defaultKeyID]; ok {
+		key = legacy
+	}
+	if kid, ok := token.Header["kid"].(string); ok {
+		if key, ok = ks.verifying[kid]; !ok {
+			return nil, errors.New("unknown signing key")
+		}
+	}
+
+	// Never let the token choose how its own key is interpreted
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.public, nil
+}
+
+// validMethods lists the algorithms of all verification keys
+func validMethods() []string {
+	seen := make(map[string]bool)
+	var methods []string
+	for _, key := range currentKeySet().verifying {
+		if alg := key.method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// JWKS returns the public verification keys as a JSON Web Key Set. Symmetric
+// keys are never published, so with HS256 the set is empty.
+func JWKS() map[string]interface{} {
+	ks := currentKeySet()
+
+	kids := make([]string, 0, len(ks.verifying))
+	for kid := range ks.verifying {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	keys := make([]map[string]interface{}, 0, len(kids))
+	for _, kid := range kids {
+		key := ks.verifying[kid]
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, map[string]interface{}{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"alg": key.method.Alg(),
+				"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, map[string]interface{}{
+				"kty": "OKP",
+				"crv": "Ed25519",
+				"kid": kid,
+				"use": "sig",
+				"alg": key.method.Alg(),
+				"x":   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+
+	return map[string]interface{}{"keys": keys}
+}
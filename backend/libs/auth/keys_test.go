@@ -0,0 +1,310 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func writePEM(t *testing.T, dir, kid, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeEd25519Key(t *testing.T, dir, kid string) ed25519.PrivateKey {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, dir, kid, "PRIVATE KEY", der)
+	return private
+}
+
+func writeRSAKey(t *testing.T, dir, kid string, bits int) {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, dir, kid, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(private))
+}
+
+func writeEd25519PublicKey(t *testing.T, dir, kid string) {
+	t.Helper()
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, dir, kid, "PUBLIC KEY", der)
+}
+
+func TestLoadKeySet(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       func(t *testing.T, dir string)
+		useDir      bool
+		signingKID  string
+		secret      string
+		wantErr     bool
+		wantSigning string
+		wantKIDs    []string
+	}{
+		{
+			name:        "shared secret only",
+			secret:      "secret",
+			wantSigning: defaultKeyID,
+			wantKIDs:    []string{defaultKeyID},
+		},
+		{
+			name:        "shared secret with custom kid",
+			signingKID:  "2024",
+			secret:      "secret",
+			wantSigning: "2024",
+			wantKIDs:    []string{"2024"},
+		},
+		{
+			name:    "no secret and no directory",
+			wantErr: true,
+		},
+		{
+			name: "key files",
+			files: func(t *testing.T, dir string) {
+				writeEd25519Key(t, dir, "current")
+				writeEd25519PublicKey(t, dir, "retiring")
+			},
+			useDir:      true,
+			signingKID:  "current",
+			wantSigning: "current",
+			wantKIDs:    []string{"current", "retiring"},
+		},
+		{
+			name: "key files keep the secret for verification",
+			files: func(t *testing.T, dir string) {
+				writeEd25519Key(t, dir, "current")
+			},
+			useDir:      true,
+			signingKID:  "current",
+			secret:      "secret",
+			wantSigning: "current",
+			wantKIDs:    []string{"current", defaultKeyID},
+		},
+		{
+			name: "key file named like the secret's key",
+			files: func(t *testing.T, dir string) {
+				writeEd25519Key(t, dir, "current")
+				writeEd25519PublicKey(t, dir, defaultKeyID)
+			},
+			useDir:     true,
+			signingKID: "current",
+			secret:     "secret",
+			wantErr:    true,
+		},
+		{
+			name: "missing signing key",
+			files: func(t *testing.T, dir string) {
+				writeEd25519Key(t, dir, "current")
+			},
+			useDir:     true,
+			signingKID: "next",
+			wantErr:    true,
+		},
+		{
+			name: "signing key without private half",
+			files: func(t *testing.T, dir string) {
+				writeEd25519PublicKey(t, dir, "current")
+			},
+			useDir:     true,
+			signingKID: "current",
+			wantErr:    true,
+		},
+		{
+			name: "short RSA key",
+			files: func(t *testing.T, dir string) {
+				writeRSAKey(t, dir, "current", 1024)
+			},
+			useDir:     true,
+			signingKID: "current",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := ""
+			if tt.useDir {
+				dir = t.TempDir()
+				tt.files(t, dir)
+			}
+
+			ks, err := loadKeySet(dir, tt.signingKID, tt.secret)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ks.signing.kid != tt.wantSigning {
+				t.Errorf("signing kid = %q, want %q", ks.signing.kid, tt.wantSigning)
+			}
+			if len(ks.verifying) != len(tt.wantKIDs) {
+				t.Errorf("verifying %d keys, want %v", len(ks.verifying), tt.wantKIDs)
+			}
+			for _, kid := range tt.wantKIDs {
+				if _, ok := ks.verifying[kid]; !ok {
+					t.Errorf("missing verification key %q", kid)
+				}
+			}
+		})
+	}
+}
+
+// useKeySet makes ks the key set seen by signToken and keyFunc
+func useKeySet(t *testing.T, ks *KeySet) {
+	t.Helper()
+	keySetOnce.Do(func() {})
+	saved := keySet
+	keySet = ks
+	t.Cleanup(func() { keySet = saved })
+}
+
+func signWith(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, jwt.MapClaims{"exp": time.Now().Add(time.Minute).Unix()})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestKeyFunc(t *testing.T) {
+	dir := t.TempDir()
+	current := writeEd25519Key(t, dir, "current")
+	withSecret, err := loadKeySet(dir, "current", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withoutSecret, err := loadKeySet(dir, "current", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicBytes := []byte(current.Public().(ed25519.PublicKey))
+
+	tests := []struct {
+		name   string
+		keys   *KeySet
+		token  func(t *testing.T) string
+		wantOK bool
+	}{
+		{
+			name:   "active key",
+			keys:   withSecret,
+			token:  func(t *testing.T) string { return signWith(t, jwt.SigningMethodEdDSA, "current", current) },
+			wantOK: true,
+		},
+		{
+			name:   "token without kid from before the switch",
+			keys:   withSecret,
+			token:  func(t *testing.T) string { return signWith(t, jwt.SigningMethodHS256, "", []byte("secret")) },
+			wantOK: true,
+		},
+		{
+			name:   "token without kid once the secret is retired",
+			keys:   withoutSecret,
+			token:  func(t *testing.T) string { return signWith(t, jwt.SigningMethodHS256, "", []byte("secret")) },
+			wantOK: false,
+		},
+		{
+			name:   "wrong secret",
+			keys:   withSecret,
+			token:  func(t *testing.T) string { return signWith(t, jwt.SigningMethodHS256, "", []byte("guess")) },
+			wantOK: false,
+		},
+		{
+			name:   "unknown kid",
+			keys:   withSecret,
+			token:  func(t *testing.T) string { return signWith(t, jwt.SigningMethodEdDSA, "old", current) },
+			wantOK: false,
+		},
+		{
+			name: "HS256 with the public key as secret",
+			keys: withSecret,
+			token: func(t *testing.T) string {
+				return signWith(t, jwt.SigningMethodHS256, "current", publicBytes)
+			},
+			wantOK: false,
+		},
+		{
+			name:   "asymmetric alg without kid",
+			keys:   withSecret,
+			token:  func(t *testing.T) string { return signWith(t, jwt.SigningMethodEdDSA, "", current) },
+			wantOK: false,
+		},
+		{
+			name:   "HS256 naming the secret's kid",
+			keys:   withSecret,
+			token:  func(t *testing.T) string { return signWith(t, jwt.SigningMethodHS256, defaultKeyID, []byte("secret")) },
+			wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useKeySet(t, tt.keys)
+
+			_, err := jwt.Parse(tt.token(t), keyFunc, jwt.WithValidMethods(validMethods()))
+			if (err == nil) != tt.wantOK {
+				t.Errorf("parse error = %v, want ok %v", err, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSignTokenStampsActiveKey(t *testing.T) {
+	dir := t.TempDir()
+	writeEd25519Key(t, dir, "current")
+	ks, err := loadKeySet(dir, "current", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	useKeySet(t, ks)
+
+	signed, err := signToken(jwt.MapClaims{"exp": time.Now().Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Parse(signed, keyFunc, jwt.WithValidMethods(validMethods()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Header["kid"] != "current" || token.Method.Alg() != jwt.SigningMethodEdDSA.Alg() {
+		t.Errorf("signed with kid %v and alg %s, want current and EdDSA", token.Header["kid"], token.Method.Alg())
+	}
+}
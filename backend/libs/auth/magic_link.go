@@ -0,0 +1,80 @@
+// libs/auth/magic_link.go
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"github.com/jafoor/carhub/libs/config"
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/libs/repository"
+	"gorm.io/gorm"
+)
+
+// IssuePartnerMagicLink signs a sign-in token for the partner and stores its
+// jti as a magic_link OTP. The OTP policy for that purpose sets the lifetime,
+// resend cooldown and daily cap, and a new link burns the previous one.
+func IssuePartnerMagicLink(otpRepo repository.OTPRepository, partner *models.Partner) (*models.OTP, string, error) {
+	if err := checkOTPLimits(otpRepo, partner.ID, models.OwnerTypePartner, models.OTPPurposeMagicLink); err != nil {
+		return nil, "", err
+	}
+
+	ttl := time.Duration(config.OTPPolicyFor(models.OTPPurposeMagicLink).TTL) * time.Minute
+	token, jti, expiresAt, err := GeneratePartnerMagicLinkToken(partner, ttl)
+	if err != nil {
+		return nil, "", errors.New("database_error")
+	}
+
+	var otp *models.OTP
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		var err error
+		otp, err = storeOTP(tx, otpRepo, partner.ID, models.OwnerTypePartner, models.OTPPurposeMagicLink, jti, expiresAt)
+		return err
+	})
+	if err != nil {
+		return nil, "", errors.New("database_error")
+	}
+
+	return otp, token, nil
+}
+
+// RedeemPartnerMagicLink checks a link token's signature, expiry and that it
+// is the partner's latest link, then burns it. It returns the partner ID.
+func RedeemPartnerMagicLink(otpRepo repository.OTPRepository, token string) (uint, error) {
+	claims, err := VerifyPartnerToken(token)
+	if err != nil || claims.TokenType != "magic_link" {
+		return 0, errors.New("invalid_or_expired_link")
+	}
+
+	otp, err := otpRepo.FindActiveOTP(claims.PartnerID, models.OwnerTypePartner, models.OTPPurposeMagicLink)
+	if err != nil {
+		return 0, errors.New("database_error")
+	}
+	if otp == nil {
+		return 0, errors.New("invalid_or_expired_link")
+	}
+
+	presented, err := HashOTPCode(claims.PartnerID, models.OwnerTypePartner, models.OTPPurposeMagicLink, claims.ID)
+	if err != nil {
+		return 0, err
+	}
+	if subtle.ConstantTimeCompare([]byte(otp.CodeHash), []byte(presented)) != 1 {
+		return 0, errors.New("invalid_or_expired_link")
+	}
+
+	var consumed bool
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		consumed, err = otpRepo.Consume(tx, otp.ID)
+		return err
+	})
+	if err != nil {
+		return 0, errors.New("database_error")
+	}
+	if !consumed {
+		return 0, errors.New("invalid_or_expired_link")
+	}
+
+	return claims.PartnerID, nil
+}
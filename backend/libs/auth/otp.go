@@ -0,0 +1,258 @@
+// libs/auth/otp.go
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/jafoor/carhub/libs/config"
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/logger"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/libs/notifier"
+	"github.com/jafoor/carhub/libs/repository"
+	"gorm.io/gorm"
+)
+
+// minOTPLength keeps a misconfigured policy from issuing trivially guessable codes
+const minOTPLength = 4
+
+// ErrOTPHashKeyMissing is returned instead of hashing codes with an empty key
+var ErrOTPHashKeyMissing = errors.New("otp_hash_key_missing")
+
+// GenerateOTPCode returns a numeric code drawn from crypto/rand
+func GenerateOTPCode(length int) (string, error) {
+	const digits = "0123456789"
+	otp := make([]byte, length)
+	for i := range otp {
+		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(digits))))
+		if err != nil {
+			return "", err
+		}
+		otp[i] = digits[num.Int64()]
+	}
+	return string(otp), nil
+}
+
+// HashOTPCode returns the keyed hash stored in place of a code. The owner and
+// purpose are part of the MAC, so a leaked hash matches nothing else and the
+// short code space can't be brute-forced offline without the key. The key is
+// OTP_HASH_KEY, or JWT_SECRET when unset; with neither it refuses to hash.
+func HashOTPCode(ownerID uint, ownerType models.OwnerType, purpose, code string) (string, error) {
+	key := config.App.OTPHashKey
+	if key == "" {
+		key = config.App.JWTSecret
+	}
+	if key == "" {
+		return "", ErrOTPHashKeyMissing
+	}
+
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "%s:%d:%s:%s", ownerType, ownerID, purpose, code)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// CreateOTP stores a new code for the owner and purpose inside tx, replacing
+// any code still outstanding. It returns the plaintext code for delivery; only
+// its hash is persisted. Limits are not checked, see IssueOTP.
+func CreateOTP(
+	tx *gorm.DB,
+	otpRepo repository.OTPRepository,
+	ownerID uint,
+	ownerType models.OwnerType,
+	purpose string,
+) (*models.OTP, string, error) {
+	policy := config.OTPPolicyFor(purpose)
+
+	length := policy.Length
+	if length < minOTPLength {
+		length = minOTPLength
+	}
+	code, err := GenerateOTPCode(length)
+	if err != nil {
+		return nil, "", err
+	}
+
+	otp, err := storeOTP(tx, otpRepo, ownerID, ownerType, purpose, code, time.Now().Add(time.Duration(policy.TTL)*time.Minute))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return otp, code, nil
+}
+
+// storeOTP replaces any outstanding secret for the owner and purpose with
+// the hash of the given one
+func storeOTP(
+	tx *gorm.DB,
+	otpRepo repository.OTPRepository,
+	ownerID uint,
+	ownerType models.OwnerType,
+	purpose, secret string,
+	expiresAt time.Time,
+) (*models.OTP, error) {
+	hash, err := HashOTPCode(ownerID, ownerType, purpose, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := otpRepo.InvalidateActive(tx, ownerID, ownerType, purpose); err != nil {
+		return nil, err
+	}
+
+	otp := &models.OTP{
+		OwnerID:   ownerID,
+		OwnerType: ownerType,
+		CodeHash:  hash,
+		Purpose:   purpose,
+		ExpiresAt: expiresAt,
+		Used:      false,
+	}
+	if err := otpRepo.Create(tx, otp); err != nil {
+		return nil, err
+	}
+
+	return otp, nil
+}
+
+// IssueOTP creates a new OTP for the owner and purpose after enforcing the
+// purpose's resend cooldown and daily cap. A new code replaces the previous one.
+func IssueOTP(
+	otpRepo repository.OTPRepository,
+	ownerID uint,
+	ownerType models.OwnerType,
+	purpose string,
+) (*models.OTP, string, error) {
+	if err := checkOTPLimits(otpRepo, ownerID, ownerType, purpose); err != nil {
+		return nil, "", err
+	}
+
+	var otp *models.OTP
+	var code string
+	err := database.ExecuteTransaction(func(tx *gorm.DB) error {
+		var err error
+		otp, code, err = CreateOTP(tx, otpRepo, ownerID, ownerType, purpose)
+		return err
+	})
+	if err != nil {
+		return nil, "", errors.New("database_error")
+	}
+
+	return otp, code, nil
+}
+
+// checkOTPLimits enforces the purpose's resend cooldown and daily cap
+func checkOTPLimits(
+	otpRepo repository.OTPRepository,
+	ownerID uint,
+	ownerType models.OwnerType,
+	purpose string,
+) error {
+	policy := config.OTPPolicyFor(purpose)
+
+	if policy.ResendCooldown > 0 {
+		recent, err := otpRepo.CountRecentOTPs(ownerID, ownerType, purpose, time.Duration(policy.ResendCooldown)*time.Second)
+		if err != nil {
+			return errors.New("database_error")
+		}
+		if recent > 0 {
+			return errors.New("otp_resend_cooldown")
+		}
+	}
+
+	if policy.DailyCap > 0 {
+		today, err := otpRepo.CountRecentOTPs(ownerID, ownerType, purpose, 24*time.Hour)
+		if err != nil {
+			return errors.New("database_error")
+		}
+		if today >= int64(policy.DailyCap) {
+			return errors.New("otp_daily_limit_reached")
+		}
+	}
+
+	return nil
+}
+
+// VerifyOTP checks a code against the owner's outstanding OTP for the purpose.
+// Every wrong guess is counted and the OTP is burnt after the purpose's
+// MaxAttempts, so a code can't be enumerated within its lifetime. The caller
+// marks the returned OTP used once the action it guards has succeeded.
+func VerifyOTP(
+	otpRepo repository.OTPRepository,
+	ownerID uint,
+	ownerType models.OwnerType,
+	purpose, code string,
+) (*models.OTP, error) {
+	otp, err := otpRepo.FindActiveOTP(ownerID, ownerType, purpose)
+	if err != nil {
+		return nil, errors.New("database_error")
+	}
+	if otp == nil {
+		return nil, errors.New("invalid_or_expired_otp")
+	}
+
+	presented, err := HashOTPCode(ownerID, ownerType, purpose, code)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(otp.CodeHash), []byte(presented)) == 1 {
+		return otp, nil
+	}
+
+	maxAttempts := config.OTPPolicyFor(purpose).MaxAttempts
+	var attempts int
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		attempts, err = otpRepo.RecordFailedAttempt(tx, otp.ID, maxAttempts)
+		return err
+	})
+	if err != nil {
+		return nil, errors.New("database_error")
+	}
+	if attempts >= maxAttempts {
+		return nil, errors.New("otp_attempts_exceeded")
+	}
+
+	return nil, errors.New("invalid_or_expired_otp")
+}
+
+// SendOTP delivers a freshly issued code through the notifier. When delivery
+// fails the OTP is marked used so it can't be verified by anyone.
+func SendOTP(
+	n notifier.Notifier,
+	otpRepo repository.OTPRepository,
+	otp *models.OTP,
+	code string,
+	channel notifier.Channel,
+	to, firstName, templateName string,
+) error {
+	err := n.Notify(channel, to, templateName, map[string]interface{}{
+		"FirstName":        firstName,
+		"Code":             code,
+		"ExpiresInMinutes": int(time.Until(otp.ExpiresAt).Round(time.Minute).Minutes()),
+	})
+	if err == nil {
+		return nil
+	}
+
+	logger.Error().
+		Err(err).
+		Uint("owner_id", otp.OwnerID).
+		Str("owner_type", string(otp.OwnerType)).
+		Str("purpose", otp.Purpose).
+		Msg("OTP delivery failed")
+
+	if err := database.ExecuteTransaction(func(tx *gorm.DB) error {
+		return otpRepo.MarkAsUsed(tx, otp.ID)
+	}); err != nil {
+		logger.Error().Err(err).Uint("otp_id", otp.ID).Msg("Failed to invalidate undelivered OTP")
+	}
+
+	return errors.New("otp_delivery_failed")
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jafoor/carhub/libs/config"
+	"github.com/jafoor/carhub/libs/models"
+)
+
+func withOTPKeys(t *testing.T, otpHashKey, jwtSecret string) {
+	t.Helper()
+	saved := config.App
+	t.Cleanup(func() { config.App = saved })
+	config.App.OTPHashKey = otpHashKey
+	config.App.JWTSecret = jwtSecret
+}
+
+func TestHashOTPCodeKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		otpHashKey string
+		jwtSecret  string
+		wantErr    error
+	}{
+		{"otp hash key", "otp-key", "", nil},
+		{"jwt secret fallback", "", "jwt-secret", nil},
+		{"both set", "otp-key", "jwt-secret", nil},
+		{"no key", "", "", ErrOTPHashKeyMissing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withOTPKeys(t, tt.otpHashKey, tt.jwtSecret)
+
+			hash, err := HashOTPCode(1, models.OwnerTypePartner, models.OTPPurposePhoneSignin, "123456")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && hash != "" {
+				t.Errorf("hash = %q, want none", hash)
+			}
+			if tt.wantErr == nil && len(hash) != 64 {
+				t.Errorf("hash = %q, want 64 hex characters", hash)
+			}
+		})
+	}
+}
+
+func TestHashOTPCodePrefersOTPHashKey(t *testing.T) {
+	withOTPKeys(t, "otp-key", "jwt-secret")
+	withKey, _ := HashOTPCode(1, models.OwnerTypePartner, models.OTPPurposePhoneSignin, "123456")
+
+	withOTPKeys(t, "", "jwt-secret")
+	withSecret, _ := HashOTPCode(1, models.OwnerTypePartner, models.OTPPurposePhoneSignin, "123456")
+
+	if withKey == withSecret {
+		t.Error("OTP_HASH_KEY was ignored in favour of JWT_SECRET")
+	}
+}
+
+func TestHashOTPCodeBinding(t *testing.T) {
+	withOTPKeys(t, "otp-key", "")
+	base, _ := HashOTPCode(1, models.OwnerTypePartner, models.OTPPurposePhoneSignin, "123456")
+
+	tests := []struct {
+		name      string
+		ownerID   uint
+		ownerType models.OwnerType
+		purpose   string
+		code      string
+	}{
+		{"other owner", 2, models.OwnerTypePartner, models.OTPPurposePhoneSignin, "123456"},
+		{"other owner type", 1, models.OwnerTypeAdmin, models.OTPPurposePhoneSignin, "123456"},
+		{"other purpose", 1, models.OwnerTypePartner, models.OTPPurposePhoneVerification, "123456"},
+		{"other code", 1, models.OwnerTypePartner, models.OTPPurposePhoneSignin, "123457"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, _ := HashOTPCode(tt.ownerID, tt.ownerType, tt.purpose, tt.code)
+			if hash == base {
+				t.Errorf("hash collides with the base code's hash")
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jafoor/carhub/libs/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func withArgon2Params(t *testing.T, memory, iterations uint32, parallelism uint8) {
+	t.Helper()
+	saved := config.App
+	t.Cleanup(func() { config.App = saved })
+	config.App.Argon2Memory = memory
+	config.App.Argon2Iterations = iterations
+	config.App.Argon2Parallelism = parallelism
+}
+
+func TestDecodeArgon2Hash(t *testing.T) {
+	const salt, key = "c2FsdHNhbHRzYWx0c2FsdA", "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+
+	tests := []struct {
+		name       string
+		hash       string
+		wantParams argon2Params
+		wantErr    bool
+	}{
+		{"valid", "$argon2id$v=19$m=65536,t=3,p=2$" + salt + "$" + key, argon2Params{65536, 3, 2}, false},
+		{"too few parts", "$argon2id$v=19$m=65536,t=3,p=2$" + salt, argon2Params{}, true},
+		{"too many parts", "$argon2id$v=19$m=65536,t=3,p=2$" + salt + "$" + key + "$x", argon2Params{}, true},
+		{"old version", "$argon2id$v=16$m=65536,t=3,p=2$" + salt + "$" + key, argon2Params{}, true},
+		{"missing version", "$argon2id$m=65536,t=3,p=2$" + salt + "$" + key + "$" + key, argon2Params{}, true},
+		{"bad params", "$argon2id$v=19$m=lots,t=3,p=2$" + salt + "$" + key, argon2Params{}, true},
+		{"bad salt", "$argon2id$v=19$m=65536,t=3,p=2$not*base64$" + key, argon2Params{}, true},
+		{"bad key", "$argon2id$v=19$m=65536,t=3,p=2$" + salt + "$not*base64", argon2Params{}, true},
+		{"empty key", "$argon2id$v=19$m=65536,t=3,p=2$" + salt + "$", argon2Params{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, _, _, err := decodeArgon2Hash(tt.hash)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeArgon2Hash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && params != tt.wantParams {
+				t.Errorf("decodeArgon2Hash() params = %+v, want %+v", params, tt.wantParams)
+			}
+		})
+	}
+}
+
+func TestCurrentArgon2Params(t *testing.T) {
+	tests := []struct {
+		name        string
+		memory      uint32
+		iterations  uint32
+		parallelism uint8
+		want        argon2Params
+	}{
+		{"configured", 1024, 2, 1, argon2Params{1024, 2, 1}},
+		{"unset", 0, 0, 0, argon2Params{64 * 1024, 1, 1}},
+		{"memory below the parallelism minimum", 16, 2, 4, argon2Params{64 * 1024, 2, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withArgon2Params(t, tt.memory, tt.iterations, tt.parallelism)
+			if got := currentArgon2Params(); got != tt.want {
+				t.Errorf("currentArgon2Params() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyPassword(t *testing.T) {
+	// Small costs keep the test fast; only the comparison with config matters
+	withArgon2Params(t, 64, 1, 1)
+
+	current, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if !strings.HasPrefix(current, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("HashPassword() = %q, want current parameters", current)
+	}
+
+	rehashed := func(memory, iterations uint32, parallelism uint8) string {
+		withArgon2Params(t, memory, iterations, parallelism)
+		hash, err := HashPassword("correct horse")
+		if err != nil {
+			t.Fatalf("HashPassword: %v", err)
+		}
+		withArgon2Params(t, 64, 1, 1)
+		return hash
+	}
+	weakerMemory := rehashed(32, 1, 1)
+	moreIterations := rehashed(64, 2, 1)
+	otherParallelism := rehashed(64, 1, 2)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		hash            string
+		password        string
+		wantOK          bool
+		wantNeedsRehash bool
+	}{
+		{"argon2 current params", current, "correct horse", true, false},
+		{"argon2 wrong password", current, "battery staple", false, false},
+		{"argon2 memory changed", weakerMemory, "correct horse", true, true},
+		{"argon2 iterations changed", moreIterations, "correct horse", true, true},
+		{"argon2 parallelism changed", otherParallelism, "correct horse", true, true},
+		{"argon2 changed params wrong password", weakerMemory, "battery staple", false, false},
+		{"argon2 malformed", "$argon2id$v=19$m=64,t=1,p=1$", "correct horse", false, false},
+		{"bcrypt fallback", string(legacy), "correct horse", true, true},
+		{"bcrypt wrong password", string(legacy), "battery staple", false, false},
+		{"bcrypt 2y prefix", "$2y$" + strings.TrimPrefix(string(legacy), "$2a$"), "correct horse", true, true},
+		{"argon2i is not accepted", strings.Replace(current, "$argon2id$", "$argon2i$", 1), "correct horse", false, false},
+		{"plaintext", "correct horse", "correct horse", false, false},
+		{"empty hash", "", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash := VerifyPassword(tt.hash, tt.password)
+			if ok != tt.wantOK || needsRehash != tt.wantNeedsRehash {
+				t.Errorf("VerifyPassword() = %v, %v, want %v, %v", ok, needsRehash, tt.wantOK, tt.wantNeedsRehash)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+// libs/auth/password_policy.go
+package auth
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/jafoor/carhub/libs/config"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/libs/repository"
+	"gorm.io/gorm"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var (
+	commonPasswords     map[string]bool
+	commonPasswordsOnce sync.Once
+)
+
+// PasswordViolation is one failed rule, returned to clients as is
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError lists every rule a password broke. Its message is
+// "weak_password" so existing error switches keep working.
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "weak_password"
+}
+
+// PasswordViolations returns the violations carried by err, if any
+func PasswordViolations(err error) []PasswordViolation {
+	var policyErr *PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return policyErr.Violations
+	}
+	return nil
+}
+
+// PasswordSubject describes whose password is being set. OwnerID is zero for
+// accounts that don't exist yet, which skips the history check.
+type PasswordSubject struct {
+	OwnerType   models.OwnerType
+	OwnerID     uint
+	CurrentHash string // hash being replaced, if any
+	Email       string
+	FirstName   string
+	LastName    string
+}
+
+type PasswordPolicy interface {
+	Validate(password string, subject PasswordSubject) error
+	Record(tx *gorm.DB, subject PasswordSubject, hash string) error
+}
+
+type passwordPolicy struct {
+	history repository.PasswordHistoryRepository
+}
+
+func NewPasswordPolicy(history repository.PasswordHistoryRepository) PasswordPolicy {
+	return &passwordPolicy{history: history}
+}
+
+// Validate checks the PASSWORD_* rules, the common-password list, personal
+// details and the owner's recent passwords. It returns a PasswordPolicyError
+// listing every violation, or database_error if the history can't be read.
+func (p *passwordPolicy) Validate(password string, subject PasswordSubject) error {
+	violations := checkPasswordRules(password, subject)
+
+	// Reuse is only worth checking for otherwise acceptable passwords
+	if len(violations) == 0 && subject.OwnerID != 0 {
+		reused, err := p.reused(password, subject)
+		if err != nil {
+			return errors.New("database_error")
+		}
+		if reused {
+			violations = append(violations, PasswordViolation{
+				Code:    "password_reused",
+				Message: fmt.Sprintf("Password must differ from your last %d passwords", max(config.App.PasswordHistory, 1)),
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// Record stores the new hash in the owner's history and trims it to
+// PASSWORD_HISTORY entries. Call it in the transaction that sets the password.
+func (p *passwordPolicy) Record(tx *gorm.DB, subject PasswordSubject, hash string) error {
+	keep := config.App.PasswordHistory
+	if keep <= 0 {
+		return nil
+	}
+
+	entry := &models.PasswordHistory{
+		OwnerType:    subject.OwnerType,
+		OwnerID:      subject.OwnerID,
+		PasswordHash: hash,
+	}
+	if err := p.history.Create(tx, entry); err != nil {
+		return err
+	}
+	return p.history.Prune(tx, subject.OwnerType, subject.OwnerID, keep)
+}
+
+// reused compares against the current hash and the recorded history, so
+// accounts created before the history existed are covered too
+func (p *passwordPolicy) reused(password string, subject PasswordSubject) (bool, error) {
+	hashes := []string{}
+	if subject.CurrentHash != "" {
+		hashes = append(hashes, subject.CurrentHash)
+	}
+
+	if config.App.PasswordHistory > 0 {
+		entries, err := p.history.ListRecent(subject.OwnerType, subject.OwnerID, config.App.PasswordHistory)
+		if err != nil {
+			return false, err
+		}
+		for _, entry := range entries {
+			hashes = append(hashes, entry.PasswordHash)
+		}
+	}
+
+	for _, hash := range hashes {
+		if ok, _ := VerifyPassword(hash, password); ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func checkPasswordRules(password string, subject PasswordSubject) []PasswordViolation {
+	var violations []PasswordViolation
+	add := func(code, message string) {
+		violations = append(violations, PasswordViolation{Code: code, Message: message})
+	}
+
+	if len([]rune(password)) < config.App.PasswordMinLength {
+		add("too_short", fmt.Sprintf("Password must be at least %d characters", config.App.PasswordMinLength))
+	}
+	if config.App.PasswordMaxLength > 0 && len(password) > config.App.PasswordMaxLength {
+		add("too_long", fmt.Sprintf("Password must be at most %d bytes", config.App.PasswordMaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if config.App.PasswordRequireUppercase && !upper {
+		add("missing_uppercase", "Password must contain an uppercase letter")
+	}
+	if config.App.PasswordRequireLowercase && !lower {
+		add("missing_lowercase", "Password must contain a lowercase letter")
+	}
+	if config.App.PasswordRequireDigit && !digit {
+		add("missing_digit", "Password must contain a digit")
+	}
+	if config.App.PasswordRequireSymbol && !symbol {
+		add("missing_symbol", "Password must contain a symbol")
+	}
+
+	if isCommonPassword(password) {
+		add("too_common", "Password is too common")
+	}
+	if containsPersonalInfo(password, subject) {
+		add("contains_personal_info", "Password must not contain your name or email")
+	}
+
+	return violations
+}
+
+func isCommonPassword(password string) bool {
+	commonPasswordsOnce.Do(func() {
+		commonPasswords = make(map[string]bool)
+		scanner := bufio.NewScanner(strings.NewReader(commonPasswordList))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			commonPasswords[strings.ToLower(line)] = true
+		}
+	})
+	return commonPasswords[strings.ToLower(password)]
+}
+
+// containsPersonalInfo flags passwords built around the owner's name or the
+// local part of their email. Very short parts are ignored to avoid noise.
+func containsPersonalInfo(password string, subject PasswordSubject) bool {
+	lowered := strings.ToLower(password)
+
+	parts := []string{subject.FirstName, subject.LastName}
+	if local, _, ok := strings.Cut(subject.Email, "@"); ok {
+		parts = append(parts, local)
+	}
+
+	for _, part := range parts {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if len(part) >= 4 && strings.Contains(lowered, part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jafoor/carhub/libs/config"
+	"github.com/jafoor/carhub/libs/models"
+	"gorm.io/gorm"
+)
+
+func withPasswordPolicy(t *testing.T, history int) {
+	t.Helper()
+	saved := config.App
+	t.Cleanup(func() { config.App = saved })
+	config.App.PasswordMinLength = 10
+	config.App.PasswordMaxLength = 72
+	config.App.PasswordRequireUppercase = true
+	config.App.PasswordRequireLowercase = true
+	config.App.PasswordRequireDigit = true
+	config.App.PasswordRequireSymbol = true
+	config.App.PasswordHistory = history
+}
+
+func violationCodes(violations []PasswordViolation) []string {
+	codes := []string{}
+	for _, v := range violations {
+		codes = append(codes, v.Code)
+	}
+	return codes
+}
+
+func TestCheckPasswordRules(t *testing.T) {
+	withPasswordPolicy(t, 0)
+	subject := PasswordSubject{Email: "jane.doe@example.com", FirstName: "Jane", LastName: "Doe"}
+
+	tests := []struct {
+		name     string
+		password string
+		subject  PasswordSubject
+		want     []string
+	}{
+		{"acceptable", "Tr0ub4dor&Horse", subject, []string{}},
+		{"space counts as a symbol", "Tr0ub4dor Horse", subject, []string{}},
+		{"too short", "Tr0ub&Hor", subject, []string{"too_short"}},
+		{"length counts runes", "Ünïcödé1!x", subject, []string{}},
+		{"too long", "Tr0ub4dor&" + strings.Repeat("x", 63), subject, []string{"too_long"}},
+		{"missing uppercase", "tr0ub4dor&horse", subject, []string{"missing_uppercase"}},
+		{"missing lowercase", "TR0UB4DOR&HORSE", subject, []string{"missing_lowercase"}},
+		{"missing digit", "Troubador&Horse", subject, []string{"missing_digit"}},
+		{"missing symbol", "Tr0ub4dorHorse", subject, []string{"missing_symbol"}},
+		{"only spaces", "          ", subject, []string{"missing_uppercase", "missing_lowercase", "missing_digit"}},
+		{"several rules", "abc", subject, []string{"too_short", "missing_uppercase", "missing_digit", "missing_symbol"}},
+		{"common password", "Password123!", subject, []string{"too_common"}},
+		{"common password, other case", "pAsSwOrD123!", subject, []string{"too_common"}},
+		{"first name", "Xx!9JaneHorse", subject, []string{"contains_personal_info"}},
+		{"first name, other case", "Xx!9jANEhorse", subject, []string{"contains_personal_info"}},
+		{"email local part", "Xx!9jane.doeZ", PasswordSubject{Email: "jane.doe@example.com"}, []string{"contains_personal_info"}},
+		{"email domain is fine", "Xx!9Example.com", subject, []string{}},
+		{"short name parts are ignored", "Xx!9DoeHorsey", subject, []string{}},
+		{"no subject details", "Xx!9JaneHorse", PasswordSubject{}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := violationCodes(checkPasswordRules(tt.password, tt.subject))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("checkPasswordRules(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakePasswordHistoryRepository returns entries newest first
+type fakePasswordHistoryRepository struct {
+	entries []models.PasswordHistory
+	err     error
+	lists   int
+}
+
+func (r *fakePasswordHistoryRepository) Create(tx *gorm.DB, entry *models.PasswordHistory) error {
+	r.entries = append([]models.PasswordHistory{*entry}, r.entries...)
+	return nil
+}
+
+func (r *fakePasswordHistoryRepository) ListRecent(ownerType models.OwnerType, ownerID uint, limit int) ([]models.PasswordHistory, error) {
+	r.lists++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.entries[:min(limit, len(r.entries))], nil
+}
+
+func (r *fakePasswordHistoryRepository) Prune(tx *gorm.DB, ownerType models.OwnerType, ownerID uint, keep int) error {
+	r.entries = r.entries[:min(keep, len(r.entries))]
+	return nil
+}
+
+func TestPasswordPolicyReuse(t *testing.T) {
+	withArgon2Params(t, 64, 1, 1)
+
+	hash := func(password string) string {
+		t.Helper()
+		h, err := HashPassword(password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	current := hash("Current!Pass1")
+	// Newest first: the last one falls outside a history of two
+	history := []models.PasswordHistory{
+		{PasswordHash: current},
+		{PasswordHash: hash("Previous!Pass2")},
+		{PasswordHash: hash("Oldest!Pass3")},
+	}
+	subject := PasswordSubject{OwnerType: models.OwnerTypePartner, OwnerID: 1, CurrentHash: current}
+
+	tests := []struct {
+		name      string
+		password  string
+		subject   PasswordSubject
+		history   int
+		repoErr   error
+		wantErr   string
+		wantCodes []string
+		wantLists int
+	}{
+		{"new password", "Brand!New4Pass", subject, 2, nil, "", nil, 1},
+		{"current password", "Current!Pass1", subject, 2, nil, "weak_password", []string{"password_reused"}, 1},
+		{"recent password", "Previous!Pass2", subject, 2, nil, "weak_password", []string{"password_reused"}, 1},
+		{"older than the history", "Oldest!Pass3", subject, 2, nil, "", nil, 1},
+		{"history disabled still checks the current hash", "Current!Pass1", subject, 0, nil, "weak_password", []string{"password_reused"}, 0},
+		{"history disabled", "Previous!Pass2", subject, 0, nil, "", nil, 0},
+		{"new account", "Previous!Pass2", PasswordSubject{OwnerType: models.OwnerTypePartner}, 2, nil, "", nil, 0},
+		{"rule violations skip the history", "Sh0rt", subject, 2, nil, "weak_password", []string{"too_short"}, 0},
+		{"history unreadable", "Brand!New4Pass", subject, 2, errors.New("down"), "database_error", nil, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withPasswordPolicy(t, tt.history)
+			config.App.PasswordRequireSymbol = false
+			repo := &fakePasswordHistoryRepository{entries: history, err: tt.repoErr}
+			policy := NewPasswordPolicy(repo)
+
+			err := policy.Validate(tt.password, tt.subject)
+			gotErr := ""
+			if err != nil {
+				gotErr = err.Error()
+			}
+			if gotErr != tt.wantErr {
+				t.Fatalf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+			if tt.wantCodes != nil {
+				if got := violationCodes(PasswordViolations(err)); !slices.Equal(got, tt.wantCodes) {
+					t.Errorf("violations = %v, want %v", got, tt.wantCodes)
+				}
+			}
+			if repo.lists != tt.wantLists {
+				t.Errorf("history read %d times, want %d", repo.lists, tt.wantLists)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyRecord(t *testing.T) {
+	tests := []struct {
+		name    string
+		history int
+		want    []string
+	}{
+		{"keeps the configured number", 2, []string{"new", "first"}},
+		{"disabled", 0, []string{"first", "second"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withPasswordPolicy(t, tt.history)
+			repo := &fakePasswordHistoryRepository{entries: []models.PasswordHistory{{PasswordHash: "first"}, {PasswordHash: "second"}}}
+
+			if err := NewPasswordPolicy(repo).Record(nil, PasswordSubject{OwnerID: 1}, "new"); err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, entry := range repo.entries {
+				got = append(got, entry.PasswordHash)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("history = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
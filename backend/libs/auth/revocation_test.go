@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jafoor/carhub/libs/config"
+	"github.com/jafoor/carhub/libs/models"
+	"gorm.io/gorm"
+)
+
+// fakeRevocationRepository keeps revocations in memory
+type fakeRevocationRepository struct {
+	subject  *models.SubjectRevocation
+	jtis     map[string]bool
+	sessions map[string]bool
+}
+
+func (r *fakeRevocationRepository) CreateRevokedToken(tx *gorm.DB, token *models.RevokedToken) error {
+	r.jtis[token.JTI] = true
+	return nil
+}
+
+func (r *fakeRevocationRepository) IsTokenRevoked(jti string) (bool, error) {
+	return r.jtis[jti], nil
+}
+
+func (r *fakeRevocationRepository) DeleteExpiredTokens(tx *gorm.DB) error {
+	return nil
+}
+
+func (r *fakeRevocationRepository) CreateRevokedSession(tx *gorm.DB, session *models.RevokedSession) error {
+	r.sessions[session.SessionID] = true
+	return nil
+}
+
+func (r *fakeRevocationRepository) IsSessionRevoked(sessionID string) (bool, error) {
+	return r.sessions[sessionID], nil
+}
+
+func (r *fakeRevocationRepository) DeleteExpiredSessions(tx *gorm.DB) error {
+	return nil
+}
+
+func (r *fakeRevocationRepository) SaveSubjectRevocation(tx *gorm.DB, revocation *models.SubjectRevocation) error {
+	r.subject = revocation
+	return nil
+}
+
+func (r *fakeRevocationRepository) FindSubjectRevocation(ownerType models.OwnerType, ownerID uint) (*models.SubjectRevocation, error) {
+	if r.subject == nil || r.subject.OwnerType != ownerType || r.subject.OwnerID != ownerID {
+		return nil, nil
+	}
+	return r.subject, nil
+}
+
+func TestRevokeAllTokens(t *testing.T) {
+	repo := &fakeRevocationRepository{jtis: map[string]bool{}, sessions: map[string]bool{}}
+	store := &dbRevocationStore{repo: repo}
+
+	// A token issued in the same second as the revocation, as iat records it
+	issued := time.Now().Truncate(time.Second)
+	if err := store.RevokeAllTokens(models.OwnerTypeAdmin, 1); err != nil {
+		t.Fatal(err)
+	}
+	cutoff := repo.subject.RevokedBefore
+
+	if !cutoff.Equal(cutoff.Truncate(time.Second)) {
+		t.Errorf("cut-off %v is not truncated to the second", cutoff)
+	}
+
+	tests := []struct {
+		name     string
+		ownerID  uint
+		issuedAt time.Time
+		want     bool
+	}{
+		{"issued earlier", 1, cutoff.Add(-time.Hour), true},
+		{"issued in the same second", 1, issued, true},
+		{"issued at the cut-off", 1, cutoff, true},
+		{"issued after", 1, cutoff.Add(time.Second), false},
+		{"other owner", 2, cutoff.Add(-time.Hour), false},
+		{"no iat", 1, time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := store.IsRevoked(models.OwnerTypeAdmin, tt.ownerID, "", "", tt.issuedAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if revoked != tt.want {
+				t.Errorf("IsRevoked = %v, want %v", revoked, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRevokedByJTI(t *testing.T) {
+	repo := &fakeRevocationRepository{jtis: map[string]bool{"revoked": true}, sessions: map[string]bool{}}
+	store := &dbRevocationStore{repo: repo}
+
+	tests := []struct {
+		jti  string
+		want bool
+	}{
+		{"revoked", true},
+		{"live", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.jti, func(t *testing.T) {
+			revoked, err := store.IsRevoked(models.OwnerTypePartner, 1, tt.jti, "", time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if revoked != tt.want {
+				t.Errorf("IsRevoked(%q) = %v, want %v", tt.jti, revoked, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevokeSessionRejectsItsAccessTokens(t *testing.T) {
+	ks, err := loadKeySet("", "", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	useKeySet(t, ks)
+	saved := config.App
+	t.Cleanup(func() { config.App = saved })
+	config.App.AccessTokenTTL = 15
+
+	repo := &fakeRevocationRepository{jtis: map[string]bool{}, sessions: map[string]bool{}}
+	store := &dbRevocationStore{repo: repo}
+	partner := &models.Partner{ID: 1}
+
+	issue := func(sessionID string) *PartnerClaims {
+		t.Helper()
+		token, err := GeneratePartnerAccessToken(partner, sessionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		claims, err := VerifyPartnerToken(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return claims
+	}
+	// Issued before the revocation, e.g. on sign-in and on a later refresh
+	revokedFirst, revokedLater, other := issue("session-a"), issue("session-a"), issue("session-b")
+
+	if err := store.RevokeSession(models.OwnerTypePartner, 1, "session-a", time.Now().Add(15*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RevokeSession(models.OwnerTypePartner, 1, "session-c", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		claims *PartnerClaims
+		want   bool
+	}{
+		{"revoked session", revokedFirst, true},
+		{"revoked session, later token", revokedLater, true},
+		{"other session", other, false},
+		{"already expired revocation", issue("session-c"), false},
+		{"no session", issue(""), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := store.IsRevoked(models.OwnerTypePartner, tt.claims.PartnerID, tt.claims.ID, tt.claims.SessionID, tt.claims.IssuedAt.Time)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if revoked != tt.want {
+				t.Errorf("IsRevoked = %v, want %v", revoked, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// libs/auth/totp.go
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jafoor/carhub/libs/config"
+)
+
+// RFC 6238 parameters understood by every authenticator app
+const (
+	totpDigits = 6
+	totpPeriod = 30 // seconds
+	totpSkew   = 1  // accepted steps either side of now, for clock drift
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// sealedTOTPPrefix marks secrets encrypted by SealTOTPSecret. Secrets stored
+// before encryption have no prefix and are sealed the next time they're used.
+const sealedTOTPPrefix = "v1:"
+
+// totpCipher is AES-256-GCM keyed with a hash of TOTP_ENCRYPTION_KEY
+func totpCipher() (cipher.AEAD, error) {
+	if config.App.TOTPEncryptionKey == "" {
+		return nil, errors.New("TOTP_ENCRYPTION_KEY is not set")
+	}
+	key := sha256.Sum256([]byte(config.App.TOTPEncryptionKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SealTOTPSecret encrypts a secret for storage. The admin ID is authenticated
+// with it, so a sealed secret copied to another admin's row doesn't open.
+func SealTOTPSecret(adminID uint, secret string) (string, error) {
+	aead, err := totpCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(secret), []byte(strconv.FormatUint(uint64(adminID), 10)))
+	return sealedTOTPPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenTOTPSecret returns the secret stored by SealTOTPSecret. Unsealed legacy
+// secrets are returned as is; TOTPSecretSealed tells them apart.
+func OpenTOTPSecret(adminID uint, stored string) (string, error) {
+	if !TOTPSecretSealed(stored) {
+		return stored, nil
+	}
+
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(stored, sealedTOTPPrefix))
+	if err != nil {
+		return "", err
+	}
+	aead, err := totpCipher()
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("sealed TOTP secret is too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	secret, err := aead.Open(nil, nonce, ciphertext, []byte(strconv.FormatUint(uint64(adminID), 10)))
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// TOTPSecretSealed reports whether a stored secret is encrypted
+func TOTPSecretSealed(stored string) bool {
+	return strings.HasPrefix(stored, sealedTOTPPrefix)
+}
+
+// GenerateTOTPSecret returns a new 160-bit shared secret, base32 encoded
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPProvisioningURI builds the otpauth:// URI that enrollment QR codes encode
+func TOTPProvisioningURI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// ValidateTOTP checks a code against the secret around the current time. It
+// returns the matched time step, which must be greater than lastUsedStep so
+// the same code can't be replayed.
+func ValidateTOTP(secret, code string, lastUsedStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := time.Now().Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastUsedStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode computes the HOTP value (RFC 4226) for one time step
+func totpCode(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jafoor/carhub/libs/config"
+)
+
+func withTOTPEncryptionKey(t *testing.T, key string) {
+	t.Helper()
+	saved := config.App
+	t.Cleanup(func() { config.App = saved })
+	config.App.TOTPEncryptionKey = key
+}
+
+// TestTOTPCodeRFC6238 checks the SHA1 vectors from RFC 6238 appendix B,
+// truncated to the six digits authenticator apps show
+func TestTOTPCodeRFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode(T=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTPStepReplay(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret: %v", err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+
+	// The previous step stays inside the skew window even if the clock ticks
+	// over during the test
+	step := time.Now().Unix()/totpPeriod - 1
+	code := totpCode(key, step)
+
+	tests := []struct {
+		name         string
+		code         string
+		lastUsedStep int64
+		wantOK       bool
+	}{
+		{"fresh code", code, 0, true},
+		{"earlier step used", code, step - 1, true},
+		{"same step used", code, step, false},
+		{"later step used", code, step + 1, false},
+		{"not a code", "abcdef", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ValidateTOTP(secret, tt.code, tt.lastUsedStep)
+			if ok != tt.wantOK {
+				t.Fatalf("ValidateTOTP() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && got != step {
+				t.Errorf("ValidateTOTP() step = %d, want %d", got, step)
+			}
+		})
+	}
+}
+
+func TestSealTOTPSecret(t *testing.T) {
+	withTOTPEncryptionKey(t, "totp-key")
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret: %v", err)
+	}
+
+	sealed, err := SealTOTPSecret(7, secret)
+	if err != nil {
+		t.Fatalf("SealTOTPSecret: %v", err)
+	}
+	if !TOTPSecretSealed(sealed) {
+		t.Fatalf("sealed secret %q has no prefix", sealed)
+	}
+	if len(sealed) > 255 {
+		t.Errorf("sealed secret is %d chars, column holds 255", len(sealed))
+	}
+
+	if got, err := OpenTOTPSecret(7, sealed); err != nil || got != secret {
+		t.Errorf("OpenTOTPSecret() = %q, %v, want %q", got, err, secret)
+	}
+	if _, err := OpenTOTPSecret(8, sealed); err == nil {
+		t.Error("OpenTOTPSecret() opened another admin's secret")
+	}
+	if got, err := OpenTOTPSecret(7, secret); err != nil || got != secret {
+		t.Errorf("OpenTOTPSecret(legacy) = %q, %v, want %q", got, err, secret)
+	}
+
+	config.App.TOTPEncryptionKey = "rotated-key"
+	if _, err := OpenTOTPSecret(7, sealed); err == nil {
+		t.Error("OpenTOTPSecret() opened a secret sealed with another key")
+	}
+	config.App.TOTPEncryptionKey = ""
+	if _, err := SealTOTPSecret(7, secret); err == nil {
+		t.Error("SealTOTPSecret() sealed without a key")
+	}
+}
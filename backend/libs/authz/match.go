@@ -0,0 +1,46 @@
+// libs/authz/match.go
+package authz
+
+import "strings"
+
+// MatchPermission reports whether pattern covers name. Both are dotted names
+// like the ones RequireSectionPermission builds. A "*" segment matches any
+// one segment, and a trailing "*" matches one or more, so "settings.*"
+// covers "settings.regions.write" but not "settings" itself.
+func MatchPermission(pattern, name string) bool {
+	if !strings.Contains(pattern, "*") {
+		return pattern == name
+	}
+
+	patternParts := strings.Split(pattern, ".")
+	nameParts := strings.Split(name, ".")
+	for i, part := range patternParts {
+		if i >= len(nameParts) {
+			return false
+		}
+		if part == "*" {
+			if i == len(patternParts)-1 {
+				return true
+			}
+			continue
+		}
+		if part != nameParts[i] {
+			return false
+		}
+	}
+	return len(patternParts) == len(nameParts)
+}
+
+// ValidPermissionName accepts dotted names whose segments are non-empty and
+// either "*" or free of "*"
+func ValidPermissionName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, part := range strings.Split(name, ".") {
+		if part == "" || (part != "*" && strings.Contains(part, "*")) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package authz
+
+import "testing"
+
+func TestMatchPermission(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		perm    string
+		want    bool
+	}{
+		{"exact", "settings.regions.write", "settings.regions.write", true},
+		{"exact mismatch", "settings.regions.write", "settings.regions.delete", false},
+		{"exact prefix only", "settings.regions", "settings.regions.write", false},
+		{"trailing wildcard one segment", "settings.*", "settings.regions", true},
+		{"trailing wildcard many segments", "settings.*", "settings.vehicle_brands.delete", true},
+		{"trailing wildcard needs a segment", "settings.*", "settings", false},
+		{"trailing wildcard other section", "settings.*", "users.admins.write", false},
+		{"inner wildcard", "settings.*.delete", "settings.cities.delete", true},
+		{"inner wildcard other action", "settings.*.delete", "settings.cities.write", false},
+		{"inner wildcard too long", "settings.*.delete", "settings.cities.areas.delete", false},
+		{"inner wildcard too short", "settings.*.delete", "settings.delete", false},
+		{"lone wildcard", "*", "settings.regions.write", true},
+		{"no wildcard in name matching", "settings.regions.write", "settings.*", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchPermission(tt.pattern, tt.perm); got != tt.want {
+				t.Errorf("MatchPermission(%q, %q) = %v, want %v", tt.pattern, tt.perm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidPermissionName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"settings.regions.write", true},
+		{"settings.*", true},
+		{"settings.*.delete", true},
+		{"*", true},
+		{"", false},
+		{"settings.", false},
+		{".settings", false},
+		{"settings..write", false},
+		{"settings.region*", false},
+		{"settings.**", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidPermissionName(tt.name); got != tt.want {
+				t.Errorf("ValidPermissionName(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+// libs/authz/permission_cache.go
+package authz
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jafoor/carhub/libs/config"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/services/admin/repository"
+)
+
+// PermissionSet is an admin's effective permissions, resolved once so checks
+// don't touch the database. Allowed names and wildcard patterns carry the
+// region and city scope of the role assignments granting them. Denies apply
+// everywhere, whichever assignment they come through, and beat every allow.
+type PermissionSet struct {
+	SuperAdmin bool
+	allows     map[string]*Scope
+	denies     []string
+	scope      *Scope
+}
+
+// NewPermissionSet builds the set from the admin's role assignments and the
+// permissions granted through them. Super-admin assignments are global.
+func NewPermissionSet(assignments []models.AdminUserRole, grants []repository.PermissionGrant) *PermissionSet {
+	set := &PermissionSet{allows: make(map[string]*Scope)}
+
+	// Admins without any assignment have nothing to scope, so lists stay global
+	overall := &scopeBuilder{global: len(assignments) == 0}
+	for _, assignment := range assignments {
+		if assignment.Role != nil && assignment.Role.IsSuperAdmin {
+			set.SuperAdmin = true
+		}
+		overall.add(assignment.RegionID, assignment.CityID)
+	}
+	set.scope = overall.scope()
+
+	builders := make(map[string]*scopeBuilder)
+	for _, grant := range grants {
+		if grant.Effect == models.PermissionEffectDeny {
+			if !slices.Contains(set.denies, grant.Name) {
+				set.denies = append(set.denies, grant.Name)
+			}
+			continue
+		}
+		b, ok := builders[grant.Name]
+		if !ok {
+			b = &scopeBuilder{}
+			builders[grant.Name] = b
+		}
+		b.add(grant.RegionID, grant.CityID)
+	}
+	for name, b := range builders {
+		set.allows[name] = b.scope()
+	}
+
+	return set
+}
+
+// Has reports whether name is allowed anywhere. A matching deny always wins,
+// so the result doesn't depend on role order. Super admins bypass both and
+// have every permission.
+func (p *PermissionSet) Has(name string) bool {
+	if p.SuperAdmin {
+		return true
+	}
+	if p.denied(name) {
+		return false
+	}
+	for pattern := range p.allows {
+		if MatchPermission(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopeFor returns where name is allowed: the union of the scopes of every
+// allow matching it. nil means everywhere; a name that isn't allowed gets an
+// empty scope, which allows nothing.
+func (p *PermissionSet) ScopeFor(name string) *Scope {
+	if p.SuperAdmin {
+		return nil
+	}
+	if p.denied(name) {
+		return &Scope{}
+	}
+
+	union := &scopeBuilder{}
+	for pattern, scope := range p.allows {
+		if !MatchPermission(pattern, name) {
+			continue
+		}
+		if scope.Global() {
+			return nil
+		}
+		for _, regionID := range scope.RegionIDs {
+			union.add(&regionID, nil)
+		}
+		for _, cityID := range scope.CityIDs {
+			union.add(nil, &cityID)
+		}
+	}
+	return union.scope()
+}
+
+func (p *PermissionSet) denied(name string) bool {
+	for _, pattern := range p.denies {
+		if MatchPermission(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scope is the union of the admin's role assignments, used to filter lists
+func (p *PermissionSet) Scope() *Scope {
+	if p.SuperAdmin {
+		return nil
+	}
+	return p.scope
+}
+
+// AdminState is the part of the admin row every admin request is checked
+// against. Deleted admins load as inactive.
+type AdminState struct {
+	Active       bool
+	AuthzVersion int
+}
+
+type cacheEntry struct {
+	set          *PermissionSet
+	authzVersion int
+	expiresAt    time.Time
+}
+
+type stateEntry struct {
+	state     AdminState
+	expiresAt time.Time
+}
+
+// PermissionCache keeps each admin's PermissionSet and AdminState in memory.
+// A set is reloaded when the token's authorization version differs from the
+// one it was loaded for; both are reloaded when invalidated, or after
+// PERMISSION_CACHE_TTL. Invalidation is per process, so with several
+// instances a change made on another one shows up within the TTL.
+type PermissionCache struct {
+	mu        sync.RWMutex
+	entries   map[uint]cacheEntry
+	states    map[uint]stateEntry
+	load      func(adminID uint) (*PermissionSet, error)
+	loadState func(adminID uint) (AdminState, error)
+}
+
+func NewPermissionCache(load func(adminID uint) (*PermissionSet, error), loadState func(adminID uint) (AdminState, error)) *PermissionCache {
+	return &PermissionCache{
+		entries:   make(map[uint]cacheEntry),
+		states:    make(map[uint]stateEntry),
+		load:      load,
+		loadState: loadState,
+	}
+}
+
+// Permissions is the process-wide cache used by the auth and permission
+// middleware and invalidated by RBAC and admin mutations
+var Permissions = NewPermissionCache(func(adminID uint) (*PermissionSet, error) {
+	assignments, err := repository.NewAdminRepository().GetRoleAssignments(adminID)
+	if err != nil {
+		return nil, err
+	}
+	grants, err := repository.NewAdminPermissionRepository().GetEffectivePermissions(adminID)
+	if err != nil {
+		return nil, err
+	}
+	return NewPermissionSet(assignments, grants), nil
+}, func(adminID uint) (AdminState, error) {
+	admin, err := repository.NewAdminRepository().FindByID(adminID)
+	if err != nil || admin == nil {
+		return AdminState{}, err
+	}
+	return AdminState{
+		Active:       admin.IsActive && admin.Status == models.AdminStatusActive,
+		AuthzVersion: admin.AuthzVersion,
+	}, nil
+})
+
+// Get returns the admin's permissions for a token stamped with authzVersion
+func (c *PermissionCache) Get(adminID uint, authzVersion int) (*PermissionSet, error) {
+	c.mu.RLock()
+	entry, ok := c.entries[adminID]
+	c.mu.RUnlock()
+	if ok && entry.authzVersion == authzVersion && time.Now().Before(entry.expiresAt) {
+		return entry.set, nil
+	}
+
+	set, err := c.load(adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := time.Duration(config.App.PermissionCacheTTL) * time.Second
+	c.mu.Lock()
+	c.entries[adminID] = cacheEntry{set: set, authzVersion: authzVersion, expiresAt: time.Now().Add(ttl)}
+	c.mu.Unlock()
+
+	return set, nil
+}
+
+// State returns the admin's current AdminState
+func (c *PermissionCache) State(adminID uint) (AdminState, error) {
+	c.mu.RLock()
+	entry, ok := c.states[adminID]
+	c.mu.RUnlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.state, nil
+	}
+
+	state, err := c.loadState(adminID)
+	if err != nil {
+		return AdminState{}, err
+	}
+
+	ttl := time.Duration(config.App.PermissionCacheTTL) * time.Second
+	c.mu.Lock()
+	c.states[adminID] = stateEntry{state: state, expiresAt: time.Now().Add(ttl)}
+	c.mu.Unlock()
+
+	return state, nil
+}
+
+// Invalidate drops the given admins' entries
+func (c *PermissionCache) Invalidate(adminIDs ...uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range adminIDs {
+		delete(c.entries, id)
+		delete(c.states, id)
+	}
+}
+
+// InvalidateAll drops every entry; used when a role or permission changes
+// and the affected admins aren't worth working out
+func (c *PermissionCache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[uint]cacheEntry)
+	c.states = make(map[uint]stateEntry)
+}
@@ -0,0 +1,55 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/jafoor/carhub/libs/config"
+)
+
+func TestPermissionCacheState(t *testing.T) {
+	saved := config.App
+	t.Cleanup(func() { config.App = saved })
+	config.App.PermissionCacheTTL = 60
+
+	loads := 0
+	current := AdminState{Active: true, AuthzVersion: 1}
+	cache := NewPermissionCache(nil, func(adminID uint) (AdminState, error) {
+		loads++
+		return current, nil
+	})
+
+	steps := []struct {
+		name      string
+		change    func()
+		want      AdminState
+		wantLoads int
+	}{
+		{"first request loads", func() {}, AdminState{Active: true, AuthzVersion: 1}, 1},
+		{"later requests are cached", func() {}, AdminState{Active: true, AuthzVersion: 1}, 1},
+		{"change without invalidation waits for the TTL", func() {
+			current = AdminState{Active: false, AuthzVersion: 2}
+		}, AdminState{Active: true, AuthzVersion: 1}, 1},
+		{"invalidation reloads", func() { cache.Invalidate(1) }, AdminState{Active: false, AuthzVersion: 2}, 2},
+		{"other admins are untouched", func() { cache.Invalidate(2) }, AdminState{Active: false, AuthzVersion: 2}, 2},
+		{"invalidate all reloads", func() {
+			current = AdminState{Active: true, AuthzVersion: 3}
+			cache.InvalidateAll()
+		}, AdminState{Active: true, AuthzVersion: 3}, 3},
+		{"expired entries reload", func() {
+			config.App.PermissionCacheTTL = 0
+			cache.InvalidateAll()
+			cache.State(1)
+		}, AdminState{Active: true, AuthzVersion: 3}, 5},
+	}
+
+	for _, step := range steps {
+		step.change()
+		got, err := cache.State(1)
+		if err != nil {
+			t.Fatalf("%s: State() = %v", step.name, err)
+		}
+		if got != step.want || loads != step.wantLoads {
+			t.Errorf("%s: State() = %+v after %d loads, want %+v after %d", step.name, got, loads, step.want, step.wantLoads)
+		}
+	}
+}
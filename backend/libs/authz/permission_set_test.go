@@ -0,0 +1,184 @@
+package authz
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/services/admin/repository"
+)
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+func allow(name string) repository.PermissionGrant {
+	return repository.PermissionGrant{Name: name, Effect: models.PermissionEffectAllow}
+}
+
+func deny(name string) repository.PermissionGrant {
+	return repository.PermissionGrant{Name: name, Effect: models.PermissionEffectDeny}
+}
+
+func inRegion(grant repository.PermissionGrant, regionID uint) repository.PermissionGrant {
+	grant.RegionID = uintPtr(regionID)
+	return grant
+}
+
+func inCity(grant repository.PermissionGrant, cityID uint) repository.PermissionGrant {
+	grant.CityID = uintPtr(cityID)
+	return grant
+}
+
+func TestPermissionSetHas(t *testing.T) {
+	superAdmin := []models.AdminUserRole{{Role: &models.AdminRole{IsSuperAdmin: true}}}
+
+	tests := []struct {
+		name        string
+		assignments []models.AdminUserRole
+		grants      []repository.PermissionGrant
+		perm        string
+		want        bool
+	}{
+		{
+			name: "no grants",
+			perm: PermSettingsRegionsWrite,
+			want: false,
+		},
+		{
+			name:   "exact allow",
+			grants: []repository.PermissionGrant{allow(PermSettingsRegionsWrite)},
+			perm:   PermSettingsRegionsWrite,
+			want:   true,
+		},
+		{
+			name:   "wildcard allow",
+			grants: []repository.PermissionGrant{allow("settings.*")},
+			perm:   PermSettingsVehicleBrandsDelete,
+			want:   true,
+		},
+		{
+			name:   "deny carves out of wildcard allow",
+			grants: []repository.PermissionGrant{allow("settings.*"), deny(PermSettingsVehicleBrandsDelete)},
+			perm:   PermSettingsVehicleBrandsDelete,
+			want:   false,
+		},
+		{
+			name:   "deny wins regardless of order",
+			grants: []repository.PermissionGrant{deny(PermSettingsVehicleBrandsDelete), allow("settings.*")},
+			perm:   PermSettingsVehicleBrandsDelete,
+			want:   false,
+		},
+		{
+			name:   "deny beats exact allow",
+			grants: []repository.PermissionGrant{allow(PermSettingsVehicleBrandsDelete), deny(PermSettingsVehicleBrandsDelete)},
+			perm:   PermSettingsVehicleBrandsDelete,
+			want:   false,
+		},
+		{
+			name:   "deny leaves siblings allowed",
+			grants: []repository.PermissionGrant{allow("settings.*"), deny(PermSettingsVehicleBrandsDelete)},
+			perm:   PermSettingsVehicleBrandsWrite,
+			want:   true,
+		},
+		{
+			name:   "wildcard deny",
+			grants: []repository.PermissionGrant{allow("settings.*"), deny("settings.*.delete")},
+			perm:   PermSettingsCitiesDelete,
+			want:   false,
+		},
+		{
+			name:   "wildcard deny leaves writes allowed",
+			grants: []repository.PermissionGrant{allow("settings.*"), deny("settings.*.delete")},
+			perm:   PermSettingsCitiesWrite,
+			want:   true,
+		},
+		{
+			name:   "scoped deny applies everywhere",
+			grants: []repository.PermissionGrant{allow(PermSettingsCitiesWrite), inRegion(deny(PermSettingsCitiesWrite), 1)},
+			perm:   PermSettingsCitiesWrite,
+			want:   false,
+		},
+		{
+			name:        "super admin ignores denies",
+			assignments: superAdmin,
+			grants:      []repository.PermissionGrant{deny("*")},
+			perm:        PermSettingsRegionsDelete,
+			want:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := NewPermissionSet(tt.assignments, tt.grants)
+			if got := set.Has(tt.perm); got != tt.want {
+				t.Errorf("Has(%q) = %v, want %v", tt.perm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermissionSetScopeFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		grants []repository.PermissionGrant
+		perm   string
+		want   *Scope
+	}{
+		{
+			name:   "global allow",
+			grants: []repository.PermissionGrant{allow(PermSettingsCitiesWrite)},
+			perm:   PermSettingsCitiesWrite,
+			want:   nil,
+		},
+		{
+			name:   "not allowed",
+			grants: []repository.PermissionGrant{allow(PermSettingsCitiesWrite)},
+			perm:   PermSettingsCitiesDelete,
+			want:   &Scope{},
+		},
+		{
+			name:   "denied",
+			grants: []repository.PermissionGrant{allow("settings.*"), deny(PermSettingsCitiesDelete)},
+			perm:   PermSettingsCitiesDelete,
+			want:   &Scope{},
+		},
+		{
+			name: "scoped allows are unioned",
+			grants: []repository.PermissionGrant{
+				inRegion(allow(PermSettingsCitiesWrite), 3),
+				inCity(allow("settings.*"), 7),
+				inRegion(allow(PermSettingsCitiesWrite), 1),
+			},
+			perm: PermSettingsCitiesWrite,
+			want: &Scope{RegionIDs: []uint{1, 3}, CityIDs: []uint{7}},
+		},
+		{
+			name: "global allow beats scoped allow",
+			grants: []repository.PermissionGrant{
+				inRegion(allow(PermSettingsCitiesWrite), 3),
+				allow("settings.*"),
+			},
+			perm: PermSettingsCitiesWrite,
+			want: nil,
+		},
+		{
+			name: "deny beats global allow",
+			grants: []repository.PermissionGrant{
+				allow("settings.*"),
+				inCity(deny("settings.cities.*"), 7),
+			},
+			perm: PermSettingsCitiesWrite,
+			want: &Scope{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := NewPermissionSet(nil, tt.grants)
+			if got := set.ScopeFor(tt.perm); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ScopeFor(%q) = %+v, want %+v", tt.perm, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+// libs/authz/permissions.go
+package authz
+
+// Permissions checked by routes. Routes reference these constants rather than
+// string literals, so a misspelt name fails to compile instead of locking
+// everyone but super admins out.
+const (
+	PermSettingsRegionsWrite        = "settings.regions.write"
+	PermSettingsRegionsDelete       = "settings.regions.delete"
+	PermSettingsCitiesWrite         = "settings.cities.write"
+	PermSettingsCitiesDelete        = "settings.cities.delete"
+	PermSettingsAreasWrite          = "settings.areas.write"
+	PermSettingsAreasDelete         = "settings.areas.delete"
+	PermSettingsVehicleTypesWrite   = "settings.vehicle_types.write"
+	PermSettingsVehicleTypesDelete  = "settings.vehicle_types.delete"
+	PermSettingsVehicleBrandsWrite  = "settings.vehicle_brands.write"
+	PermSettingsVehicleBrandsDelete = "settings.vehicle_brands.delete"
+)
+
+// settingsGrants keeps the settings routes open to the "admin" role, which
+// they were limited to before they checked permissions
+var settingsGrants = []string{"admin"}
+
+// catalog is every permission the code knows about. Missing rows are seeded
+// at boot; rows not listed here are reported as orphans.
+var catalog = []PermissionDefinition{
+	{Name: PermSettingsRegionsWrite, Description: "Create and update regions", Grants: settingsGrants},
+	{Name: PermSettingsRegionsDelete, Description: "Delete regions", Grants: settingsGrants},
+	{Name: PermSettingsCitiesWrite, Description: "Create and update cities", Grants: settingsGrants},
+	{Name: PermSettingsCitiesDelete, Description: "Delete cities", Grants: settingsGrants},
+	{Name: PermSettingsAreasWrite, Description: "Create and update areas", Grants: settingsGrants},
+	{Name: PermSettingsAreasDelete, Description: "Delete areas", Grants: settingsGrants},
+	{Name: PermSettingsVehicleTypesWrite, Description: "Create and update vehicle types", Grants: settingsGrants},
+	{Name: PermSettingsVehicleTypesDelete, Description: "Delete vehicle types", Grants: settingsGrants},
+	{Name: PermSettingsVehicleBrandsWrite, Description: "Create and update vehicle brands", Grants: settingsGrants},
+	{Name: PermSettingsVehicleBrandsDelete, Description: "Delete vehicle brands", Grants: settingsGrants},
+}
@@ -0,0 +1,74 @@
+// libs/authz/registry.go
+package authz
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// PermissionDefinition is a permission declared in code. Grants names the
+// roles given the permission when its row is first seeded.
+type PermissionDefinition struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Grants      []string `json:"-"`
+}
+
+// RoutePermission records which permission a route requires
+type RoutePermission struct {
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Permission string `json:"permission"`
+}
+
+var (
+	registryMu sync.RWMutex
+	routes     []RoutePermission
+)
+
+// Definition looks up a declared permission
+func Definition(name string) (PermissionDefinition, bool) {
+	for _, def := range catalog {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	return PermissionDefinition{}, false
+}
+
+// Definitions returns every declared permission, sorted by name
+func Definitions() []PermissionDefinition {
+	defs := make([]PermissionDefinition, len(catalog))
+	copy(defs, catalog)
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
+
+// DeclareRoute records the permission a route requires. It panics on a
+// permission missing from the catalog, so the mistake surfaces at boot.
+func DeclareRoute(method, path, permission string) {
+	if _, ok := Definition(permission); !ok {
+		panic(fmt.Sprintf("authz: %s %s requires undeclared permission %q", method, path, permission))
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	routes = append(routes, RoutePermission{Method: method, Path: path, Permission: permission})
+}
+
+// Routes returns the declared routes, sorted by path then method
+func Routes() []RoutePermission {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	list := make([]RoutePermission, len(routes))
+	copy(list, routes)
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Path != list[j].Path {
+			return list[i].Path < list[j].Path
+		}
+		return list[i].Method < list[j].Method
+	})
+	return list
+}
@@ -0,0 +1,64 @@
+// libs/authz/scope.go
+package authz
+
+import (
+	"slices"
+)
+
+// Scope limits an admin to some regions and cities. A nil *Scope is global.
+// A region covers its cities and their areas; a city covers its areas but
+// not the region it belongs to.
+type Scope struct {
+	RegionIDs []uint `json:"region_ids"`
+	CityIDs   []uint `json:"city_ids"`
+}
+
+// Global reports whether the scope is unrestricted
+func (s *Scope) Global() bool {
+	return s == nil
+}
+
+// AllowsRegion reports whether the region itself may be managed
+func (s *Scope) AllowsRegion(regionID uint) bool {
+	return s == nil || slices.Contains(s.RegionIDs, regionID)
+}
+
+// AllowsCity reports whether the city, which belongs to regionID, and its
+// areas may be managed
+func (s *Scope) AllowsCity(cityID, regionID uint) bool {
+	return s == nil || slices.Contains(s.CityIDs, cityID) || slices.Contains(s.RegionIDs, regionID)
+}
+
+// scopeBuilder collects the scopes of several grants; a single unscoped
+// grant makes the union global
+type scopeBuilder struct {
+	global  bool
+	regions []uint
+	cities  []uint
+}
+
+func (b *scopeBuilder) add(regionID, cityID *uint) {
+	switch {
+	case regionID != nil:
+		if !slices.Contains(b.regions, *regionID) {
+			b.regions = append(b.regions, *regionID)
+		}
+	case cityID != nil:
+		if !slices.Contains(b.cities, *cityID) {
+			b.cities = append(b.cities, *cityID)
+		}
+	default:
+		b.global = true
+	}
+}
+
+func (b *scopeBuilder) scope() *Scope {
+	if b.global {
+		return nil
+	}
+	regions := slices.Clone(b.regions)
+	cities := slices.Clone(b.cities)
+	slices.Sort(regions)
+	slices.Sort(cities)
+	return &Scope{RegionIDs: regions, CityIDs: cities}
+}
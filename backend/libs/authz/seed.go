@@ -0,0 +1,91 @@
+// libs/authz/seed.go
+package authz
+
+import (
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/logger"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/services/admin/repository"
+	"gorm.io/gorm"
+)
+
+// SeedPermissions creates the admin_permissions rows for declared permissions
+// that don't exist yet and grants each new one to its Grants roles. Rows
+// that no longer match a declaration are logged as orphans and left alone,
+// since roles may still reference them.
+func SeedPermissions() error {
+	permissionRepo := repository.NewAdminPermissionRepository()
+	roleRepo := repository.NewAdminRoleRepository()
+	adminRepo := repository.NewAdminRepository()
+
+	existing, err := permissionRepo.FindAll()
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(existing))
+	for _, p := range existing {
+		known[p.Name] = true
+	}
+
+	for _, def := range Definitions() {
+		if known[def.Name] {
+			continue
+		}
+
+		description := def.Description
+		permission := &models.AdminPermission{Name: def.Name, Description: &description}
+		err := database.ExecuteTransaction(func(tx *gorm.DB) error {
+			if err := permissionRepo.Create(tx, permission); err != nil {
+				return err
+			}
+			for _, roleName := range def.Grants {
+				role, err := roleRepo.FindByName(roleName)
+				if err != nil {
+					return err
+				}
+				if role == nil {
+					continue
+				}
+				if err := permissionRepo.AssignPermissionToRole(tx, role.ID, permission.ID, models.PermissionEffectAllow); err != nil {
+					return err
+				}
+				if err := adminRepo.BumpAuthzVersionForRole(tx, role.ID); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		logger.Info().Str("permission", def.Name).Strs("granted_to", def.Grants).Msg("Seeded admin permission")
+	}
+
+	for _, name := range Orphans(existing) {
+		logger.Warn().Str("permission", name).Msg("Admin permission matches nothing declared in code and no route checks it")
+	}
+
+	Permissions.InvalidateAll()
+	return nil
+}
+
+// Orphans returns the stored permissions that cover nothing in the catalog:
+// names missing from it and wildcard patterns matching none of its entries
+func Orphans(stored []models.AdminPermission) []string {
+	orphans := []string{}
+	for _, p := range stored {
+		if !coversDefinition(p.Name) {
+			orphans = append(orphans, p.Name)
+		}
+	}
+	return orphans
+}
+
+func coversDefinition(pattern string) bool {
+	for _, def := range catalog {
+		if MatchPermission(pattern, def.Name) {
+			return true
+		}
+	}
+	return false
+}
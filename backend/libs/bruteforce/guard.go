@@ -0,0 +1,154 @@
+// libs/bruteforce/guard.go
+package bruteforce
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jafoor/carhub/libs/config"
+	"github.com/jafoor/carhub/libs/logger"
+)
+
+// Policy decides how long a key is locked after a failure
+type Policy struct {
+	FreeAttempts int           // failures allowed before any lockout
+	BaseDelay    time.Duration // lockout after the first failure past FreeAttempts, doubled for each further one
+	MaxLockout   time.Duration // upper bound for a single lockout
+	Window       time.Duration // failures older than this are forgotten
+}
+
+// lockoutFor returns the lockout earned by the given failure count
+func (p Policy) lockoutFor(failures int) time.Duration {
+	excess := failures - p.FreeAttempts
+	if excess <= 0 {
+		return 0
+	}
+
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(excess-1))
+	if delay > float64(p.MaxLockout) {
+		return p.MaxLockout
+	}
+	return time.Duration(delay)
+}
+
+// LockedError is returned while an account or IP is locked out
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return "too_many_attempts"
+}
+
+// RetryAfterSeconds returns how long a LockedError asks the caller to wait,
+// rounded up to whole seconds for the Retry-After header
+func RetryAfterSeconds(err error) int {
+	locked, ok := err.(*LockedError)
+	if !ok {
+		return 0
+	}
+	return int(math.Ceil(locked.RetryAfter.Seconds()))
+}
+
+// Guard throttles one kind of attempt (a sign-in form, an OTP check) with
+// separate counters per account and per client IP
+type Guard struct {
+	scope         string
+	store         Store
+	accountPolicy Policy
+	ipPolicy      Policy
+}
+
+// NewGuard builds a guard from the BRUTE_FORCE_* settings. The scope keeps
+// counters for different endpoints apart.
+func NewGuard(scope string, store Store) *Guard {
+	policy := Policy{
+		FreeAttempts: config.App.BruteForceFreeAttempts,
+		BaseDelay:    time.Duration(config.App.BruteForceBaseDelay) * time.Second,
+		MaxLockout:   time.Duration(config.App.BruteForceMaxLockout) * time.Second,
+		Window:       time.Duration(config.App.BruteForceWindow) * time.Second,
+	}
+	ipPolicy := policy
+	ipPolicy.FreeAttempts = config.App.BruteForceIPFreeAttempts
+
+	return &Guard{scope: scope, store: store, accountPolicy: policy, ipPolicy: ipPolicy}
+}
+
+func (g *Guard) accountKey(account string) string {
+	return g.scope + ":account:" + strings.ToLower(strings.TrimSpace(account))
+}
+
+func (g *Guard) ipKey(ip string) string {
+	return g.scope + ":ip:" + ip
+}
+
+// Check returns a LockedError if either the account or the IP is locked out.
+// Store errors are logged and let the attempt through rather than locking
+// everyone out.
+func (g *Guard) Check(account, ip string) error {
+	var retryAfter time.Duration
+	for _, key := range g.keys(account, ip) {
+		entry, err := g.store.Get(key)
+		if err != nil {
+			logger.Error().Err(err).Str("key", key).Msg("Brute-force check failed")
+			continue
+		}
+		if wait := time.Until(entry.LockedUntil); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// Fail records a failed attempt for the account and the IP
+func (g *Guard) Fail(account, ip string) {
+	if account != "" {
+		g.fail(g.accountKey(account), g.accountPolicy)
+	}
+	if ip != "" {
+		g.fail(g.ipKey(ip), g.ipPolicy)
+	}
+}
+
+func (g *Guard) fail(key string, policy Policy) {
+	failures, err := g.store.Increment(key, policy.Window)
+	if err != nil {
+		logger.Error().Err(err).Str("key", key).Msg("Failed to record failed attempt")
+		return
+	}
+
+	if lockout := policy.lockoutFor(failures); lockout > 0 {
+		if err := g.store.Lock(key, time.Now().Add(lockout)); err != nil {
+			logger.Error().Err(err).Str("key", key).Msg("Failed to lock out key")
+			return
+		}
+		logger.Warn().Str("key", key).Int("failures", failures).Dur("lockout", lockout).Msg("Locked out after repeated failures")
+	}
+}
+
+// Succeed clears the account's counter. The IP counter is left alone so one
+// valid account can't be used to reset a spraying client.
+func (g *Guard) Succeed(account string) {
+	if account == "" {
+		return
+	}
+	if err := g.store.Reset(g.accountKey(account)); err != nil {
+		logger.Error().Err(err).Str("key", g.accountKey(account)).Msg("Failed to reset failed attempts")
+	}
+}
+
+func (g *Guard) keys(account, ip string) []string {
+	var keys []string
+	if account != "" {
+		keys = append(keys, g.accountKey(account))
+	}
+	if ip != "" {
+		keys = append(keys, g.ipKey(ip))
+	}
+	return keys
+}
@@ -0,0 +1,175 @@
+package bruteforce
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPolicyLockoutFor(t *testing.T) {
+	policy := Policy{FreeAttempts: 3, BaseDelay: 30 * time.Second, MaxLockout: 5 * time.Minute}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, 30 * time.Second},
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{7, 4 * time.Minute},
+		{8, 5 * time.Minute},
+		{100, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := policy.lockoutFor(tt.failures); got != tt.want {
+			t.Errorf("lockoutFor(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"whole seconds", &LockedError{RetryAfter: 30 * time.Second}, 30},
+		{"rounds up", &LockedError{RetryAfter: 1500 * time.Millisecond}, 2},
+		{"under a second", &LockedError{RetryAfter: time.Millisecond}, 1},
+		{"other error", errors.New("invalid_credentials"), 0},
+		{"no error", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RetryAfterSeconds(tt.err); got != tt.want {
+				t.Errorf("RetryAfterSeconds() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func testGuard(store Store) *Guard {
+	policy := Policy{FreeAttempts: 2, BaseDelay: time.Minute, MaxLockout: 10 * time.Minute, Window: time.Hour}
+	ipPolicy := policy
+	ipPolicy.FreeAttempts = 4
+	return &Guard{scope: "test", store: store, accountPolicy: policy, ipPolicy: ipPolicy}
+}
+
+func TestGuardFlow(t *testing.T) {
+	type step struct {
+		action  string // "fail", "succeed" or "check"
+		account string
+		ip      string
+		// wantRetryAfter is what Check reports after the step, in whole
+		// seconds; succeed steps aren't checked
+		wantRetryAfter int
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"free attempts", []step{
+			{"fail", "a@example.com", "10.0.0.1", 0},
+			{"fail", "a@example.com", "10.0.0.1", 0},
+			{"check", "a@example.com", "10.0.0.1", 0},
+		}},
+		{"account lockout doubles", []step{
+			{"fail", "a@example.com", "10.0.0.1", 0},
+			{"fail", "a@example.com", "10.0.0.2", 0},
+			{"fail", "a@example.com", "10.0.0.3", 60},
+			{"fail", "a@example.com", "10.0.0.4", 120},
+			{"check", "a@example.com", "10.0.0.5", 120},
+			{"check", "b@example.com", "10.0.0.5", 0},
+		}},
+		{"account key ignores case and spaces", []step{
+			{"fail", "a@example.com", "", 0},
+			{"fail", " A@Example.com ", "", 0},
+			{"fail", "a@EXAMPLE.com", "", 60},
+		}},
+		{"ip lockout across accounts", []step{
+			{"fail", "a@example.com", "10.0.0.1", 0},
+			{"fail", "b@example.com", "10.0.0.1", 0},
+			{"fail", "c@example.com", "10.0.0.1", 0},
+			{"fail", "d@example.com", "10.0.0.1", 0},
+			{"fail", "e@example.com", "10.0.0.1", 60},
+			{"check", "f@example.com", "10.0.0.1", 60},
+			{"check", "f@example.com", "10.0.0.2", 0},
+			{"check", "", "10.0.0.1", 60},
+		}},
+		{"longest lockout wins", []step{
+			{"fail", "a@example.com", "10.0.0.1", 0},
+			{"fail", "a@example.com", "10.0.0.1", 0},
+			{"fail", "a@example.com", "10.0.0.1", 60},
+			{"fail", "a@example.com", "10.0.0.1", 120},
+			{"fail", "b@example.com", "10.0.0.1", 60},
+			{"check", "a@example.com", "10.0.0.1", 120},
+		}},
+		{"success resets the account only", []step{
+			{"fail", "a@example.com", "10.0.0.1", 0},
+			{"fail", "a@example.com", "10.0.0.1", 0},
+			{"fail", "a@example.com", "10.0.0.1", 60},
+			{"succeed", "a@example.com", "", 0},
+			{"check", "a@example.com", "10.0.0.2", 0},
+			{"fail", "a@example.com", "10.0.0.1", 0},
+			{"fail", "a@example.com", "10.0.0.1", 60},
+		}},
+		{"ip only", []step{
+			{"fail", "", "10.0.0.1", 0},
+			{"fail", "", "10.0.0.1", 0},
+			{"fail", "", "10.0.0.1", 0},
+			{"fail", "", "10.0.0.1", 0},
+			{"fail", "", "10.0.0.1", 60},
+			{"succeed", "", "", 0},
+			{"check", "", "10.0.0.1", 60},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard := testGuard(NewMemoryStore())
+			for i, s := range tt.steps {
+				switch s.action {
+				case "fail":
+					guard.Fail(s.account, s.ip)
+				case "succeed":
+					guard.Succeed(s.account)
+					continue
+				}
+
+				err := guard.Check(s.account, s.ip)
+				if got := RetryAfterSeconds(err); got != s.wantRetryAfter {
+					t.Fatalf("step %d (%s %q %q): retry after %ds, want %ds", i, s.action, s.account, s.ip, got, s.wantRetryAfter)
+				}
+				if (err != nil) != (s.wantRetryAfter > 0) {
+					t.Fatalf("step %d: Check() = %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+// failingStore stands in for an unreachable database
+type failingStore struct{}
+
+func (failingStore) Get(key string) (Entry, error) { return Entry{}, errors.New("down") }
+func (failingStore) Increment(key string, window time.Duration) (int, error) {
+	return 0, errors.New("down")
+}
+func (failingStore) Lock(key string, until time.Time) error { return errors.New("down") }
+func (failingStore) Reset(key string) error                 { return errors.New("down") }
+
+func TestGuardStoreErrorsFailOpen(t *testing.T) {
+	guard := testGuard(failingStore{})
+	for i := 0; i < 10; i++ {
+		guard.Fail("a@example.com", "10.0.0.1")
+	}
+	guard.Succeed("a@example.com")
+	if err := guard.Check("a@example.com", "10.0.0.1"); err != nil {
+		t.Errorf("Check() = %v, want attempts let through", err)
+	}
+}
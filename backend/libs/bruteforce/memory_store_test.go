@@ -0,0 +1,88 @@
+package bruteforce
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreWindow(t *testing.T) {
+	store := NewMemoryStore().(*memoryStore)
+
+	for want := 1; want <= 3; want++ {
+		if got, _ := store.Increment("k", time.Hour); got != want {
+			t.Fatalf("Increment() = %d, want %d", got, want)
+		}
+	}
+
+	// Push the counter past its window
+	store.entries["k"].expiresAt = time.Now().Add(-time.Second)
+	if entry, _ := store.Get("k"); entry.Failures != 0 {
+		t.Errorf("Get() after window = %d failures, want 0", entry.Failures)
+	}
+	if got, _ := store.Increment("k", time.Hour); got != 1 {
+		t.Errorf("Increment() after window = %d, want 1", got)
+	}
+}
+
+func TestMemoryStoreLockOutlivesWindow(t *testing.T) {
+	store := NewMemoryStore().(*memoryStore)
+	until := time.Now().Add(time.Hour)
+
+	store.Increment("k", time.Minute)
+	store.Lock("k", until)
+	if got := store.entries["k"].expiresAt; !got.Equal(until) {
+		t.Errorf("expiresAt after Lock = %v, want the lockout end %v", got, until)
+	}
+
+	// A later failure must not shorten the entry below the lockout
+	store.Increment("k", time.Minute)
+	entry, _ := store.Get("k")
+	if entry.Failures != 2 || !entry.LockedUntil.Equal(until) {
+		t.Errorf("Get() = %+v, want 2 failures locked until %v", entry, until)
+	}
+	if got := store.entries["k"].expiresAt; !got.Equal(until) {
+		t.Errorf("expiresAt after Increment = %v, want %v", got, until)
+	}
+
+	store.Reset("k")
+	if entry, _ := store.Get("k"); entry.Failures != 0 || !entry.LockedUntil.IsZero() {
+		t.Errorf("Get() after Reset = %+v, want empty", entry)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		lastSweep time.Time
+		wantKept  []string
+	}{
+		{"recently swept", now.Add(-sweepInterval / 2), []string{"expired", "live", "locked"}},
+		{"due", now.Add(-sweepInterval), []string{"live", "locked"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryStore{
+				entries: map[string]*memoryEntry{
+					"expired": {expiresAt: now.Add(-time.Second)},
+					"live":    {expiresAt: now.Add(time.Minute)},
+					"locked":  {Entry: Entry{LockedUntil: now.Add(time.Hour)}, expiresAt: now.Add(time.Hour)},
+				},
+				lastSweep: tt.lastSweep,
+			}
+
+			store.sweep(now)
+
+			if len(store.entries) != len(tt.wantKept) {
+				t.Errorf("sweep() kept %d entries, want %v", len(store.entries), tt.wantKept)
+			}
+			for _, key := range tt.wantKept {
+				if _, ok := store.entries[key]; !ok {
+					t.Errorf("sweep() dropped %q", key)
+				}
+			}
+		})
+	}
+}
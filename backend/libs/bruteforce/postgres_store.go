@@ -0,0 +1,43 @@
+// libs/bruteforce/postgres_store.go
+package bruteforce
+
+import (
+	"time"
+
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/repository"
+)
+
+// postgresStore shares counters between every node of a cluster
+type postgresStore struct {
+	repo repository.AuthThrottleRepository
+}
+
+func NewPostgresStore() Store {
+	return &postgresStore{repo: repository.NewAuthThrottleRepository()}
+}
+
+func (s *postgresStore) Get(key string) (Entry, error) {
+	throttle, err := s.repo.FindByKey(key)
+	if err != nil || throttle == nil {
+		return Entry{}, err
+	}
+
+	entry := Entry{Failures: throttle.Failures}
+	if throttle.LockedUntil != nil {
+		entry.LockedUntil = *throttle.LockedUntil
+	}
+	return entry, nil
+}
+
+func (s *postgresStore) Increment(key string, window time.Duration) (int, error) {
+	return s.repo.Increment(database.WriteDB, key, time.Now().Add(window))
+}
+
+func (s *postgresStore) Lock(key string, until time.Time) error {
+	return s.repo.Lock(database.WriteDB, key, until)
+}
+
+func (s *postgresStore) Reset(key string) error {
+	return s.repo.Delete(database.WriteDB, key)
+}
@@ -0,0 +1,50 @@
+// libs/bruteforce/store.go
+package bruteforce
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jafoor/carhub/libs/config"
+	"github.com/jafoor/carhub/libs/logger"
+)
+
+// Entry is the failure state tracked for one key
+type Entry struct {
+	Failures    int
+	LockedUntil time.Time
+}
+
+// Store keeps failure counters. Implementations must make Increment atomic
+// so concurrent attempts can't slip past the limits.
+type Store interface {
+	Get(key string) (Entry, error)
+	// Increment adds a failure and returns the new count. Counters untouched
+	// for longer than window start again from zero.
+	Increment(key string, window time.Duration) (int, error)
+	// Lock refuses the key until the given time
+	Lock(key string, until time.Time) error
+	Reset(key string) error
+}
+
+var (
+	defaultStore     Store
+	defaultStoreOnce sync.Once
+)
+
+// NewStoreFromConfig returns the store selected by BRUTE_FORCE_STORE. The
+// in-memory store is shared process-wide so every guard sees the same counters.
+func NewStoreFromConfig() Store {
+	defaultStoreOnce.Do(func() {
+		switch config.App.BruteForceStore {
+		case "postgres":
+			defaultStore = NewPostgresStore()
+		case "", "memory":
+			defaultStore = NewMemoryStore()
+		default:
+			logger.Warn().Str("store", config.App.BruteForceStore).Msg("Unknown brute-force store, falling back to memory")
+			defaultStore = NewMemoryStore()
+		}
+	})
+	return defaultStore
+}
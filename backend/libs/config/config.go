@@ -1,26 +1,131 @@
 package config
 
 import (
+	"errors"
 	"log"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	ServerPort            string `mapstructure:"SERVER_PORT"`
-	WriteDBUrl            string `mapstructure:"WRITE_DB_URL"`
-	ReadDBUrl             string `mapstructure:"READ_DB_URL"`
-	JWTSecret             string `mapstructure:"JWT_SECRET"`
-	AccessTokenTTL        int64  `mapstructure:"ACCESS_TOKEN_TTL"`        // in minutes (partner)
-	RefreshTokenTTL       int64  `mapstructure:"REFRESH_TOKEN_TTL"`       // in days (partner)
-	AdminAccessTokenTTL   int64  `mapstructure:"ADMIN_ACCESS_TOKEN_TTL"`  // in minutes (admin)
-	AdminRefreshTokenTTL  int64  `mapstructure:"ADMIN_REFRESH_TOKEN_TTL"` // in days (admin)
-	UnverifiedTokenTTL    int64  `env:"UNVERIFIED_TOKEN_TTL" envDefault:"900"`
+	ServerPort           string `mapstructure:"SERVER_PORT"`
+	WriteDBUrl           string `mapstructure:"WRITE_DB_URL"`
+	ReadDBUrl            string `mapstructure:"READ_DB_URL"`
+	JWTSecret            string `mapstructure:"JWT_SECRET"`              // HS256 key; with JWT_KEYS_DIR it only verifies tokens issued before the switch
+	JWTIssuer            string `mapstructure:"JWT_ISSUER"`              // "iss" claim stamped into and required on every token
+	JWTKeysDir           string `mapstructure:"JWT_KEYS_DIR"`            // directory of <kid>.pem RSA/Ed25519 keys; empty means HS256 with JWT_SECRET
+	JWTSigningKeyID      string `mapstructure:"JWT_SIGNING_KEY_ID"`      // kid of the key that signs new tokens
+	AccessTokenTTL       int64  `mapstructure:"ACCESS_TOKEN_TTL"`        // in minutes (partner)
+	RefreshTokenTTL      int64  `mapstructure:"REFRESH_TOKEN_TTL"`       // in days (partner)
+	AdminAccessTokenTTL  int64  `mapstructure:"ADMIN_ACCESS_TOKEN_TTL"`  // in minutes (admin)
+	AdminRefreshTokenTTL int64  `mapstructure:"ADMIN_REFRESH_TOKEN_TTL"` // in days (admin)
+	UnverifiedTokenTTL   int64  `env:"UNVERIFIED_TOKEN_TTL" envDefault:"900"`
+
+	// Brute-force protection
+	BruteForceStore          string `mapstructure:"BRUTE_FORCE_STORE"`            // memory | postgres
+	BruteForceFreeAttempts   int    `mapstructure:"BRUTE_FORCE_FREE_ATTEMPTS"`    // failures per account before backoff starts
+	BruteForceIPFreeAttempts int    `mapstructure:"BRUTE_FORCE_IP_FREE_ATTEMPTS"` // failures per IP before backoff starts
+	BruteForceBaseDelay      int64  `mapstructure:"BRUTE_FORCE_BASE_DELAY"`       // in seconds, doubled per further failure
+	BruteForceMaxLockout     int64  `mapstructure:"BRUTE_FORCE_MAX_LOCKOUT"`      // in seconds
+	BruteForceWindow         int64  `mapstructure:"BRUTE_FORCE_WINDOW"`           // in seconds; failures older than this are forgotten
+
+	// Password policy, applied to every signup, reset and change
+	PasswordMinLength        int  `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordMaxLength        int  `mapstructure:"PASSWORD_MAX_LENGTH"` // in bytes
+	PasswordRequireUppercase bool `mapstructure:"PASSWORD_REQUIRE_UPPERCASE"`
+	PasswordRequireLowercase bool `mapstructure:"PASSWORD_REQUIRE_LOWERCASE"`
+	PasswordRequireDigit     bool `mapstructure:"PASSWORD_REQUIRE_DIGIT"`
+	PasswordRequireSymbol    bool `mapstructure:"PASSWORD_REQUIRE_SYMBOL"`
+	PasswordHistory          int  `mapstructure:"PASSWORD_HISTORY"` // previous passwords that can't be reused; 0 disables
+
+	// Password hashing (argon2id). Raising a cost rehashes on next sign-in.
+	Argon2Memory      uint32 `mapstructure:"ARGON2_MEMORY"` // in KiB
+	Argon2Iterations  uint32 `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism uint8  `mapstructure:"ARGON2_PARALLELISM"`
+
+	// One-time codes. Per-purpose limits come from OTPPolicyFor.
+	OTPHashKey string `mapstructure:"OTP_HASH_KEY"` // HMAC key for stored codes; falls back to JWT_SECRET, one of them is required
+
+	// Admin two-factor authentication
+	TOTPEncryptionKey          string `mapstructure:"TOTP_ENCRYPTION_KEY"`     // encrypts stored TOTP secrets; required
+	TOTPIssuer                 string `mapstructure:"TOTP_ISSUER"`             // label shown in authenticator apps
+	AdminTwoFactorChallengeTTL int64  `mapstructure:"ADMIN_2FA_CHALLENGE_TTL"` // in minutes
+
+	// Admin invitations
+	AdminInviteURL string `mapstructure:"ADMIN_INVITE_URL"` // accept page; the token is appended as ?token=
+	AdminInviteTTL int64  `mapstructure:"ADMIN_INVITE_TTL"` // in hours
+
+	// Admin authorization
+	PermissionCacheTTL int64 `mapstructure:"PERMISSION_CACHE_TTL"` // in seconds; safety net on top of invalidation
+
+	// Super-admin impersonation
+	ImpersonationTTL int64 `mapstructure:"IMPERSONATION_TTL"` // in minutes; impersonation tokens can't be refreshed
+
+	// Partner magic-link sign-in. Lifetime and resend limits are the magic_link OTP policy.
+	PartnerMagicLinkURL string `mapstructure:"PARTNER_MAGIC_LINK_URL"` // landing page; the token is appended as ?token=
+
+	// Partner API keys
+	PartnerAPIKeyLimit int `mapstructure:"PARTNER_API_KEY_LIMIT"` // active keys per partner
+
+	// Notifications
+	EmailDriver          string `mapstructure:"EMAIL_DRIVER"`            // smtp | log; unset disables email
+	SMSDriver            string `mapstructure:"SMS_DRIVER"`              // log or a registered gateway; unset disables SMS
+	NotifierLogFile      string `mapstructure:"NOTIFIER_LOG_FILE"`       // optional sink file for the log driver, the only place it writes message bodies
+	NotifierMaxAttempts  int    `mapstructure:"NOTIFIER_MAX_ATTEMPTS"`   // delivery attempts per message
+	NotifierRetryBackoff int64  `mapstructure:"NOTIFIER_RETRY_BACKOFF"`  // in milliseconds, doubled per attempt
+	NotifierMaxRetryWait int64  `mapstructure:"NOTIFIER_MAX_RETRY_WAIT"` // in milliseconds; total backoff a request may spend retrying
+	SMTPHost             string `mapstructure:"SMTP_HOST"`
+	SMTPPort             int    `mapstructure:"SMTP_PORT"`
+	SMTPUsername         string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword         string `mapstructure:"SMTP_PASSWORD"`
+	SMTPFrom             string `mapstructure:"SMTP_FROM"`
 }
 
 var App Config
 
+func setDefaults() {
+	viper.SetDefault("JWT_ISSUER", "carhub")
+	viper.SetDefault("BRUTE_FORCE_STORE", "memory")
+	viper.SetDefault("BRUTE_FORCE_FREE_ATTEMPTS", 5)
+	viper.SetDefault("BRUTE_FORCE_IP_FREE_ATTEMPTS", 20)
+	viper.SetDefault("BRUTE_FORCE_BASE_DELAY", 1)
+	viper.SetDefault("BRUTE_FORCE_MAX_LOCKOUT", 900)
+	viper.SetDefault("BRUTE_FORCE_WINDOW", 3600)
+	viper.SetDefault("PASSWORD_MIN_LENGTH", 8)
+	viper.SetDefault("PASSWORD_MAX_LENGTH", 72)
+	viper.SetDefault("PASSWORD_REQUIRE_UPPERCASE", true)
+	viper.SetDefault("PASSWORD_REQUIRE_LOWERCASE", true)
+	viper.SetDefault("PASSWORD_REQUIRE_DIGIT", true)
+	viper.SetDefault("PASSWORD_REQUIRE_SYMBOL", false)
+	viper.SetDefault("PASSWORD_HISTORY", 5)
+	viper.SetDefault("ARGON2_MEMORY", 64*1024)
+	viper.SetDefault("ARGON2_ITERATIONS", 3)
+	viper.SetDefault("ARGON2_PARALLELISM", 2)
+	viper.SetDefault("OTP_LENGTH", 6)
+	viper.SetDefault("OTP_TTL", 10)
+	viper.SetDefault("OTP_MAX_ATTEMPTS", 5)
+	viper.SetDefault("OTP_RESEND_COOLDOWN", 60)
+	viper.SetDefault("OTP_DAILY_CAP", 10)
+	viper.SetDefault("OTP_PASSWORD_RESET_TTL", 15)
+	viper.SetDefault("OTP_PASSWORD_RESET_DAILY_CAP", 5)
+	viper.SetDefault("OTP_MAGIC_LINK_TTL", 15)
+	viper.SetDefault("TOTP_ISSUER", "CarHub")
+	viper.SetDefault("ADMIN_2FA_CHALLENGE_TTL", 5)
+	viper.SetDefault("ADMIN_INVITE_URL", "http://localhost:3000/admin/accept-invite")
+	viper.SetDefault("ADMIN_INVITE_TTL", 72)
+	viper.SetDefault("IMPERSONATION_TTL", 15)
+	viper.SetDefault("PERMISSION_CACHE_TTL", 300)
+	viper.SetDefault("PARTNER_MAGIC_LINK_URL", "http://localhost:3000/partner/magic-link")
+	viper.SetDefault("PARTNER_API_KEY_LIMIT", 10)
+	viper.SetDefault("NOTIFIER_MAX_ATTEMPTS", 3)
+	viper.SetDefault("NOTIFIER_RETRY_BACKOFF", 500)
+	viper.SetDefault("NOTIFIER_MAX_RETRY_WAIT", 1500)
+	viper.SetDefault("SMTP_PORT", 587)
+}
+
 func LoadConfig() {
+	setDefaults()
 	viper.SetConfigFile(".env")
 	if err := viper.ReadInConfig(); err != nil {
 		log.Fatalf("Error reading config: %v", err)
@@ -28,4 +133,50 @@ func LoadConfig() {
 	if err := viper.Unmarshal(&App); err != nil {
 		log.Fatalf("Error unmarshalling config: %v", err)
 	}
+	if err := App.Validate(); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+}
+
+// Validate reports settings the server must not start without
+func (c *Config) Validate() error {
+	// Stored OTP hashes keyed with an empty secret are as guessable as the codes
+	if c.OTPHashKey == "" && c.JWTSecret == "" {
+		return errors.New("OTP_HASH_KEY is required when JWT_SECRET is not set")
+	}
+	// TOTP secrets sit next to the password hash and must not be readable from a dump
+	if c.TOTPEncryptionKey == "" {
+		return errors.New("TOTP_ENCRYPTION_KEY is required")
+	}
+	return nil
+}
+
+// OTPPolicy holds the limits for one OTP purpose
+type OTPPolicy struct {
+	Length         int   // digits per code
+	TTL            int64 // in minutes
+	MaxAttempts    int   // wrong codes before an OTP is burnt
+	ResendCooldown int64 // in seconds between two codes
+	DailyCap       int   // codes per owner in any 24 hours
+}
+
+// OTPPolicyFor returns the policy for a purpose. Each value is read from
+// OTP_<PURPOSE>_<SETTING> (e.g. OTP_PASSWORD_RESET_TTL) and falls back to
+// OTP_<SETTING>, so new purposes work without any extra configuration.
+func OTPPolicyFor(purpose string) OTPPolicy {
+	prefix := "OTP_" + strings.ToUpper(purpose) + "_"
+	get := func(setting string) int64 {
+		if viper.IsSet(prefix + setting) {
+			return viper.GetInt64(prefix + setting)
+		}
+		return viper.GetInt64("OTP_" + setting)
+	}
+
+	return OTPPolicy{
+		Length:         int(get("LENGTH")),
+		TTL:            get("TTL"),
+		MaxAttempts:    int(get("MAX_ATTEMPTS")),
+		ResendCooldown: get("RESEND_COOLDOWN"),
+		DailyCap:       int(get("DAILY_CAP")),
+	}
 }
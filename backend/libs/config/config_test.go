@@ -0,0 +1,25 @@
+package config
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"otp hash key", Config{OTPHashKey: "otp-key", JWTKeysDir: "/etc/carhub/keys", TOTPEncryptionKey: "totp-key"}, false},
+		{"jwt secret", Config{JWTSecret: "jwt-secret", TOTPEncryptionKey: "totp-key"}, false},
+		{"key files without otp hash key", Config{JWTKeysDir: "/etc/carhub/keys", TOTPEncryptionKey: "totp-key"}, true},
+		{"no totp encryption key", Config{JWTSecret: "jwt-secret"}, true},
+		{"nothing set", Config{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
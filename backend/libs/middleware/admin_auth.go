@@ -1,12 +1,15 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"slices"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/authz"
+	"github.com/jafoor/carhub/libs/models"
 	"github.com/jafoor/carhub/libs/utils"
 	"github.com/jafoor/carhub/services/admin/repository"
 )
@@ -14,10 +17,15 @@ import (
 const (
 	AdminIDKey   = "admin_id"
 	AdminClaimsKey = "admin_claims"
+	// PermissionScopeKey holds the *authz.Scope of the permission checked by RequirePermission
+	PermissionScopeKey = "permission_scope"
 )
 
-// RequireAdminAuth validates admin JWT token
+// RequireAdminAuth validates an admin access or impersonation token
 func RequireAdminAuth() fiber.Handler {
+	revocations := auth.NewRevocationStore()
+	admins := repository.NewAdminRepository()
+
 	return func(c *fiber.Ctx) error {
 		// Extract token from Authorization header
 		authHeader := c.Get("Authorization")
@@ -35,18 +43,131 @@ func RequireAdminAuth() fiber.Handler {
 
 		// Verify token
 		claims, err := auth.VerifyAdminToken(token)
-		if err != nil {
+		if err != nil || (claims.TokenType != "access" && !claims.Impersonating()) {
 			return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired token", nil)
 		}
 
+		// Refuse tokens revoked by logout or account deactivation
+		revoked, err := revocations.IsRevoked(models.OwnerTypeAdmin, claims.AdminID, claims.ID, claims.SessionID, issuedAt(claims.IssuedAt))
+		if err != nil {
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify token", nil)
+		}
+		if revoked {
+			return utils.ErrorResponse(c, http.StatusUnauthorized, "Token has been revoked", nil)
+		}
+
+		// Roles in the token are only trusted while its authorization version is current
+		claims, err = reauthorize(c, admins, claims)
+		if err != nil {
+			switch err.Error() {
+			case "account_inactive":
+				return utils.ErrorResponse(c, http.StatusUnauthorized, "Account is no longer active", nil)
+			case "impersonation_not_allowed":
+				return utils.ErrorResponse(c, http.StatusUnauthorized, "Impersonation of this admin is no longer allowed", nil)
+			default:
+				return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify token", nil)
+			}
+		}
+
+		// Admins on an initial password may only change it (or sign out)
+		if claims.PasswordChangeRequired && !allowedWhileRestricted(c, passwordChangeRoutes) {
+			return utils.ErrorResponse(c, http.StatusForbidden, "Password change required", fiber.Map{
+				"password_change_required": true,
+			})
+		}
+
+		// Admins whose role demands 2FA may only enroll (or sign out)
+		if claims.TwoFactorSetupRequired && !allowedWhileRestricted(c, twoFactorSetupRoutes) {
+			return utils.ErrorResponse(c, http.StatusForbidden, "Two-factor authentication setup required", fiber.Map{
+				"two_factor_setup_required": true,
+			})
+		}
+
 		// Store admin info in context for downstream handlers
 		c.Locals(AdminIDKey, claims.AdminID)
 		c.Locals(AdminClaimsKey, claims)
 
+		if claims.Impersonating() {
+			return serveImpersonated(c, models.OwnerTypeAdmin, claims.AdminID, claims.Act)
+		}
+
 		return c.Next()
 	}
 }
 
+// reauthorize compares the token's authorization version with the admin's,
+// read through the permission cache so current tokens cost no query. A stale
+// token isn't rejected: its roles and restriction flags are reloaded for this
+// request and X-Authz-Stale tells the client to refresh. Inactive or deleted
+// admins are refused outright.
+func reauthorize(c *fiber.Ctx, admins repository.AdminRepository, claims *auth.AdminClaims) (*auth.AdminClaims, error) {
+	state, err := authz.Permissions.State(claims.AdminID)
+	if err != nil {
+		return nil, errors.New("database_error")
+	}
+	if !state.Active {
+		return nil, errors.New("account_inactive")
+	}
+	if state.AuthzVersion == claims.AuthzVersion {
+		return claims, nil
+	}
+
+	admin, err := admins.FindByID(claims.AdminID)
+	if err != nil {
+		return nil, errors.New("database_error")
+	}
+	if admin == nil || !admin.IsActive || admin.Status != models.AdminStatusActive {
+		return nil, errors.New("account_inactive")
+	}
+
+	roles, err := admins.GetAdminRoles(admin.ID)
+	if err != nil {
+		return nil, errors.New("database_error")
+	}
+
+	fresh := *claims
+	fresh.Roles = auth.AdminRoleNames(roles)
+	fresh.AuthzVersion = admin.AuthzVersion
+
+	if claims.Impersonating() {
+		// Targets must stay lower-privileged for the whole impersonation
+		for _, role := range roles {
+			if role.IsSuperAdmin || role.Name == "super_admin" {
+				return nil, errors.New("impersonation_not_allowed")
+			}
+		}
+	} else {
+		fresh.PasswordChangeRequired = !admin.PasswordChanged
+		fresh.TwoFactorSetupRequired = !admin.TwoFactorEnabled && auth.RolesRequireTwoFactor(roles)
+	}
+
+	c.Set("X-Authz-Stale", "true")
+	return &fresh, nil
+}
+
+// passwordChangeRoutes are the only requests an admin on an initial password
+// may make: the password change itself and logout
+var passwordChangeRoutes = map[string]bool{
+	fiber.MethodPut + " /api/v1/admin/profile/password": true,
+	fiber.MethodPost + " /api/v1/admin/logout":          true,
+}
+
+// twoFactorSetupRoutes are the only requests an admin whose role demands 2FA
+// may make before enrolling: 2FA status, enrollment, activation and logout
+var twoFactorSetupRoutes = map[string]bool{
+	fiber.MethodGet + " /api/v1/admin/2fa":           true,
+	fiber.MethodPost + " /api/v1/admin/2fa/enroll":   true,
+	fiber.MethodPost + " /api/v1/admin/2fa/activate": true,
+	fiber.MethodPost + " /api/v1/admin/logout":       true,
+}
+
+// allowedWhileRestricted reports whether the request exactly matches one of
+// the routes a restricted admin may still use
+func allowedWhileRestricted(c *fiber.Ctx, routes map[string]bool) bool {
+	path := strings.ToLower(strings.TrimRight(c.Path(), "/"))
+	return routes[c.Method()+" "+path]
+}
+
 // RequireSuperAdmin checks if admin has super_admin role using JWT claims
 func RequireSuperAdmin() fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -95,56 +216,58 @@ func RequireRoles(allowedRoles ...string) fiber.Handler {
 }
 
 // RequirePermission creates a middleware that checks if admin has the required permission
-// Super admins automatically pass, non-super admins must have the specific permission
+// Super admins automatically pass, non-super admins must have the specific permission.
+// Permissions come from the in-memory cache, keyed by the token's authorization version.
 func RequirePermission(permissionName string) fiber.Handler {
-	permissionRepo := repository.NewAdminPermissionRepository()
-
 	return func(c *fiber.Ctx) error {
-		// First ensure admin is authenticated
-		adminID, ok := c.Locals(AdminIDKey).(uint)
-		if !ok {
-			return utils.ErrorResponse(c, http.StatusUnauthorized, "Admin authentication required", nil)
-		}
-
-		// Check permission (HasPermission already handles super admin bypass)
-		hasPermission, err := permissionRepo.HasPermission(adminID, permissionName)
-		if err != nil {
-			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to check permission", nil)
-		}
-
-		if !hasPermission {
-			return utils.ErrorResponse(c, http.StatusForbidden, "Insufficient permissions", nil)
-		}
+		return checkPermission(c, permissionName)
+	}
+}
 
-		return c.Next()
+// PermissionRoute registers a route behind RequirePermission and records it
+// in the authz registry, which seeds the permission and lists the route for
+// the role editor. The permission must be declared in authz's catalog.
+func PermissionRoute(router fiber.Router, method, path, permission string, handlers ...fiber.Handler) fiber.Router {
+	prefix := ""
+	if group, ok := router.(*fiber.Group); ok {
+		prefix = group.Prefix
 	}
+	authz.DeclareRoute(method, prefix+path, permission)
+
+	return router.Add(method, path, append([]fiber.Handler{RequirePermission(permission)}, handlers...)...)
 }
 
 func RequireSectionPermission(section string, action string) fiber.Handler {
-	permissionRepo := repository.NewAdminPermissionRepository()
+	permissionName := section
+	if action != "" {
+		permissionName = section + "." + action
+	}
 
 	return func(c *fiber.Ctx) error {
-		adminID, ok := c.Locals(AdminIDKey).(uint)
-		if !ok {
-			return utils.ErrorResponse(c, http.StatusUnauthorized, "Admin authentication required", nil)
-		}
-
-		permissionName := section
-		if action != "" {
-			permissionName = section + "." + action
-		}
+		return checkPermission(c, permissionName)
+	}
+}
 
-		hasPermission, err := permissionRepo.HasPermission(adminID, permissionName)
-		if err != nil {
-			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to check permission", nil)
-		}
+func checkPermission(c *fiber.Ctx, permissionName string) error {
+	// First ensure admin is authenticated
+	claims, err := GetAdminClaims(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Admin authentication required", nil)
+	}
 
-		if !hasPermission {
-			return utils.ErrorResponse(c, http.StatusForbidden, "Insufficient permissions", nil)
-		}
+	permissions, err := authz.Permissions.Get(claims.AdminID, claims.AuthzVersion)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to check permission", nil)
+	}
 
-		return c.Next()
+	if !permissions.Has(permissionName) {
+		return utils.ErrorResponse(c, http.StatusForbidden, "Insufficient permissions", nil)
 	}
+
+	// Handlers enforce region and city limits themselves, see GetPermissionScope
+	c.Locals(PermissionScopeKey, permissions.ScopeFor(permissionName))
+
+	return c.Next()
 }
 
 // GetAdminID extracts admin ID from context
@@ -164,3 +287,29 @@ func GetAdminClaims(c *fiber.Ctx) (*auth.AdminClaims, error) {
 	}
 	return claims, nil
 }
+
+// GetPermissionScope returns where the permission checked by RequirePermission
+// applies; nil means everywhere. Outside RequirePermission it returns an empty
+// scope, which allows nothing.
+func GetPermissionScope(c *fiber.Ctx) *authz.Scope {
+	scope, ok := c.Locals(PermissionScopeKey).(*authz.Scope)
+	if !ok {
+		return &authz.Scope{}
+	}
+	return scope
+}
+
+// GetAdminScope returns the regions and cities the admin's role assignments
+// cover, for filtering lists; nil means everywhere
+func GetAdminScope(c *fiber.Ctx) (*authz.Scope, error) {
+	claims, err := GetAdminClaims(c)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := authz.Permissions.Get(claims.AdminID, claims.AuthzVersion)
+	if err != nil {
+		return nil, err
+	}
+	return permissions.Scope(), nil
+}
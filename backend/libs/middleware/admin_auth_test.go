@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestAllowedWhileRestricted(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		passwordChange bool
+		twoFactorSetup bool
+	}{
+		{"password change", fiber.MethodPut, "/api/v1/admin/profile/password", true, false},
+		{"password change, trailing slash", fiber.MethodPut, "/api/v1/admin/profile/password/", true, false},
+		{"logout", fiber.MethodPost, "/api/v1/admin/logout", true, true},
+		{"2fa status", fiber.MethodGet, "/api/v1/admin/2fa", false, true},
+		{"2fa enroll", fiber.MethodPost, "/api/v1/admin/2fa/enroll", false, true},
+		{"2fa activate", fiber.MethodPost, "/api/v1/admin/2fa/activate", false, true},
+		{"2fa disable", fiber.MethodDelete, "/api/v1/admin/2fa", false, false},
+		{"2fa recovery codes", fiber.MethodPost, "/api/v1/admin/2fa/recovery-codes", false, false},
+		{"path containing a 2fa route", fiber.MethodPost, "/api/v1/admin/users/api/v1/admin/2fa/enroll", false, false},
+		{"password read with the wrong method", fiber.MethodGet, "/api/v1/admin/profile/password", false, false},
+		{"profile", fiber.MethodGet, "/api/v1/admin/profile", false, false},
+		{"sessions", fiber.MethodDelete, "/api/v1/admin/sessions", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var passwordChange, twoFactorSetup bool
+			app := fiber.New()
+			app.Use(func(c *fiber.Ctx) error {
+				passwordChange = allowedWhileRestricted(c, passwordChangeRoutes)
+				twoFactorSetup = allowedWhileRestricted(c, twoFactorSetupRoutes)
+				return nil
+			})
+
+			if _, err := app.Test(httptest.NewRequest(tt.method, tt.path, nil)); err != nil {
+				t.Fatal(err)
+			}
+			if passwordChange != tt.passwordChange || twoFactorSetup != tt.twoFactorSetup {
+				t.Errorf("allowed with password change %v, with 2FA setup %v; want %v, %v",
+					passwordChange, twoFactorSetup, tt.passwordChange, tt.twoFactorSetup)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/libs/repository"
+	"github.com/jafoor/carhub/libs/utils"
+)
+
+const (
+	PartnerIDKey     = "partner_id"
+	PartnerClaimsKey = "partner_claims"
+	PartnerAPIKeyKey = "partner_api_key"
+)
+
+// authorizationCredentials splits an "Authorization: <scheme> <credentials>" header
+func authorizationCredentials(c *fiber.Ctx) (string, string, bool) {
+	parts := strings.Split(c.Get("Authorization"), " ")
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+func bearerToken(c *fiber.Ctx) (string, bool) {
+	scheme, token, ok := authorizationCredentials(c)
+	if !ok || scheme != "Bearer" {
+		return "", false
+	}
+	return token, true
+}
+
+// issuedAt tolerates tokens without an iat: they count as issued at the zero
+// time, so any owner-wide revocation covers them
+func issuedAt(iat *jwt.NumericDate) time.Time {
+	if iat == nil {
+		return time.Time{}
+	}
+	return iat.Time
+}
+
+// RequirePartnerAuth validates a partner access or impersonation token, or a partner API key
+// sent as "Authorization: ApiKey <key>". Key callers get no claims; routes
+// they may use are marked with RequirePartnerScope, the rest with
+// RequirePartnerSession.
+func RequirePartnerAuth() fiber.Handler {
+	revocations := auth.NewRevocationStore()
+	apiKeys := repository.NewPartnerAPIKeyRepository()
+
+	return func(c *fiber.Ctx) error {
+		if c.Get("Authorization") == "" {
+			return utils.ErrorResponse(c, http.StatusUnauthorized, "Authorization header required", nil)
+		}
+
+		scheme, token, ok := authorizationCredentials(c)
+		if !ok || (scheme != "Bearer" && scheme != "ApiKey") {
+			return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid authorization header format", nil)
+		}
+
+		if scheme == "ApiKey" {
+			key, err := auth.AuthenticatePartnerAPIKey(apiKeys, token, c.IP())
+			if err != nil {
+				if err.Error() == "database_error" {
+					return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify API key", nil)
+				}
+				return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired API key", nil)
+			}
+
+			c.Locals(PartnerIDKey, key.PartnerID)
+			c.Locals(PartnerAPIKeyKey, key)
+
+			return c.Next()
+		}
+
+		claims, err := auth.VerifyPartnerToken(token)
+		if err != nil || (claims.TokenType != "access" && !claims.Impersonating()) {
+			return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired token", nil)
+		}
+
+		revoked, err := revocations.IsRevoked(models.OwnerTypePartner, claims.PartnerID, claims.ID, claims.SessionID, issuedAt(claims.IssuedAt))
+		if err != nil {
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify token", nil)
+		}
+		if revoked {
+			return utils.ErrorResponse(c, http.StatusUnauthorized, "Token has been revoked", nil)
+		}
+
+		c.Locals(PartnerIDKey, claims.PartnerID)
+		c.Locals(PartnerClaimsKey, claims)
+
+		if claims.Impersonating() {
+			return serveImpersonated(c, models.OwnerTypePartner, claims.PartnerID, claims.Act)
+		}
+
+		return c.Next()
+	}
+}
+
+// RequirePartnerScope lets API key callers through only when their key has
+// the scope. Signed-in partners always pass.
+func RequirePartnerScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key, ok := GetPartnerAPIKey(c)
+		if ok && !key.HasScope(scope) {
+			return utils.ErrorResponse(c, http.StatusForbidden, "API key is missing the "+scope+" scope", nil)
+		}
+		return c.Next()
+	}
+}
+
+// RequirePartnerSession keeps API keys away from account management, which
+// needs a signed-in partner
+func RequirePartnerSession() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := GetPartnerAPIKey(c); ok {
+			return utils.ErrorResponse(c, http.StatusForbidden, "This endpoint requires a signed-in partner", nil)
+		}
+		return c.Next()
+	}
+}
+
+// GetPartnerAPIKey returns the API key the request was authenticated with, if any
+func GetPartnerAPIKey(c *fiber.Ctx) (*models.PartnerAPIKey, bool) {
+	key, ok := c.Locals(PartnerAPIKeyKey).(*models.PartnerAPIKey)
+	return key, ok
+}
+
+// GetPartnerID extracts partner ID from context
+func GetPartnerID(c *fiber.Ctx) (uint, error) {
+	partnerID, ok := c.Locals(PartnerIDKey).(uint)
+	if !ok {
+		return 0, fiber.NewError(http.StatusUnauthorized, "Partner ID not found in context")
+	}
+	return partnerID, nil
+}
+
+// GetPartnerClaims extracts partner claims from context
+func GetPartnerClaims(c *fiber.Ctx) (*auth.PartnerClaims, error) {
+	claims, ok := c.Locals(PartnerClaimsKey).(*auth.PartnerClaims)
+	if !ok {
+		return nil, fiber.NewError(http.StatusUnauthorized, "Partner claims not found in context")
+	}
+	return claims, nil
+}
@@ -6,6 +6,13 @@ import (
 	"gorm.io/gorm"
 )
 
+type AdminStatus string
+
+const (
+	AdminStatusInvited AdminStatus = "invited" // waiting for the invitee to set a password
+	AdminStatusActive  AdminStatus = "active"
+)
+
 type Admin struct {
 	ID                   uint      `gorm:"primaryKey;autoIncrement" json:"id"`
 	FirstName            string    `gorm:"size:100;not null" json:"first_name"`
@@ -14,14 +21,28 @@ type Admin struct {
 	Phone                *string   `gorm:"size:30;uniqueIndex" json:"phone,omitempty"`
 	PasswordHash         string    `gorm:"size:255;not null" json:"-"`
 	
+	Status               AdminStatus `gorm:"size:20;default:'active';not null" json:"status"`
 	EmailVerified        bool      `gorm:"default:true;not null" json:"email_verified"`
 	IsActive             bool      `gorm:"default:true;not null" json:"is_active"`
 	PasswordChanged      bool      `gorm:"default:false;not null" json:"password_changed"`
 	LastPasswordChange   *time.Time `json:"-"`
 	LastLoginAt          *time.Time `json:"last_login_at,omitempty"`
+
+	// Two-factor authentication (TOTP). The secret is stored on enrollment,
+	// encrypted by auth.SealTOTPSecret, and only takes effect once
+	// TwoFactorEnabled is set by a verified code.
+	TwoFactorEnabled     bool       `gorm:"default:false;not null" json:"two_factor_enabled"`
+	TwoFactorEnabledAt   *time.Time `json:"-"`
+	TOTPSecret           string     `gorm:"column:totp_secret;size:255" json:"-"`
+	TOTPLastUsedStep     int64      `gorm:"column:totp_last_used_step;default:0;not null" json:"-"`
+
+	// AuthzVersion is stamped into access tokens and bumped whenever the
+	// admin's roles, their permissions or the active flag change
+	AuthzVersion         int        `gorm:"default:1;not null" json:"-"`
 	
 	// Associations
 	Roles                []AdminRole `gorm:"many2many:admin_user_roles;joinForeignKey:admin_id;joinReferences:role_id" json:"roles,omitempty"`
+	Invitation           *AdminInvitation `gorm:"foreignKey:AdminID" json:"invitation,omitempty"`
 
 	CreatedAt            time.Time `json:"created_at"`
 	UpdatedAt            time.Time `json:"updated_at"`
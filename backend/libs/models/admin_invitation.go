@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	InvitationPending  = "pending"
+	InvitationExpired  = "expired"
+	InvitationRevoked  = "revoked"
+	InvitationAccepted = "accepted"
+)
+
+// AdminInvitation is the single invitation of an invited admin. Resending
+// replaces TokenID, which makes links sent earlier unusable.
+type AdminInvitation struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	AdminID    uint       `gorm:"not null;uniqueIndex" json:"-"`
+	TokenID    string     `gorm:"size:64;not null" json:"-"` // jti of the only valid invite token
+	InvitedBy  uint       `gorm:"not null" json:"invited_by"`
+	SentCount  int        `gorm:"default:1;not null" json:"sent_count"`
+	LastSentAt time.Time  `gorm:"not null" json:"last_sent_at"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	State      string     `gorm:"-" json:"state"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// CurrentState derives pending/expired/revoked/accepted from the timestamps
+func (i *AdminInvitation) CurrentState() string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationAccepted
+	case i.RevokedAt != nil:
+		return InvitationRevoked
+	case time.Now().After(i.ExpiresAt):
+		return InvitationExpired
+	default:
+		return InvitationPending
+	}
+}
+
+// AfterFind fills State so listings show it without extra work
+func (i *AdminInvitation) AfterFind(tx *gorm.DB) error {
+	i.State = i.CurrentState()
+	return nil
+}
@@ -2,9 +2,18 @@ package models
 
 import "time"
 
+// Effects of a permission on a role. Deny overrides allow, whichever role
+// either comes from.
+const (
+	PermissionEffectAllow = "allow"
+	PermissionEffectDeny  = "deny"
+)
+
 type AdminPermission struct {
 	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
 	Name        string    `gorm:"size:100;uniqueIndex;not null" json:"name"`
 	Description *string   `gorm:"type:text" json:"description,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
-}
\ No newline at end of file
+	// Effect is read from admin_role_permissions when listing a role's permissions
+	Effect string `gorm:"->;-:migration" json:"effect,omitempty"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// AdminRecoveryCode is a one-time fallback for an admin's TOTP device. Only
+// the SHA-256 hash of the code is kept.
+type AdminRecoveryCode struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	AdminID   uint       `gorm:"not null;index" json:"-"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	UsedAt    *time.Time `json:"-"`
+	CreatedAt time.Time  `json:"-"`
+}
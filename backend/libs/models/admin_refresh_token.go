@@ -4,10 +4,21 @@ import (
 	"time"
 )
 
+// AdminRefreshToken is one signed-in device session. The stored hash is
+// replaced on every rotation while the session itself stays the same, so a
+// session is also the rotation family: Generation counts rotations and is
+// embedded in the refresh token, which lets a replayed, already-rotated token
+// be told apart from a forged one.
 type AdminRefreshToken struct {
-	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
-	AdminID   uint      `gorm:"not null;index" json:"-"`
-	TokenHash string    `gorm:"size:512;not null" json:"-"`
-	ExpiresAt time.Time `gorm:"not null;index" json:"-"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	AdminID    uint      `gorm:"not null;index" json:"-"`
+	SessionID  string    `gorm:"size:36;uniqueIndex;not null" json:"-"`
+	TokenHash  string    `gorm:"size:512;not null" json:"-"`
+	Generation int       `gorm:"default:0;not null" json:"-"`
+	DeviceName string    `gorm:"size:100" json:"-"`
+	IPAddress  string    `gorm:"size:45" json:"-"`
+	UserAgent  string    `gorm:"size:255" json:"-"`
+	LastUsedAt time.Time `gorm:"not null" json:"-"`
+	ExpiresAt  time.Time `gorm:"not null;index" json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
 }
@@ -3,11 +3,19 @@ package models
 import "time"
 
 type AdminRole struct {
-	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	Name         string    `gorm:"size:50;uniqueIndex;not null" json:"name"`
-	DisplayName  string    `gorm:"size:100;not null" json:"display_name"`
-	Description  *string   `gorm:"type:text" json:"description,omitempty"`
-	IsDefault    bool      `gorm:"default:false;not null" json:"is_default"`
-	IsSuperAdmin bool      `gorm:"default:false;not null" json:"is_super_admin"`
-	CreatedAt    time.Time `json:"created_at"`
-}
\ No newline at end of file
+	ID           uint    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name         string  `gorm:"size:50;uniqueIndex;not null" json:"name"`
+	DisplayName  string  `gorm:"size:100;not null" json:"display_name"`
+	Description  *string `gorm:"type:text" json:"description,omitempty"`
+	IsDefault    bool    `gorm:"default:false;not null" json:"is_default"`
+	IsSuperAdmin bool    `gorm:"default:false;not null" json:"is_super_admin"`
+	// ParentID makes the role inherit every permission of the parent and its
+	// ancestors. The super-admin and 2FA flags are not inherited.
+	ParentID *uint `gorm:"index" json:"parent_id,omitempty"`
+	// RequiresTwoFactor forces holders of the role to enroll in TOTP before using the API
+	RequiresTwoFactor bool      `gorm:"default:false;not null" json:"requires_two_factor"`
+	CreatedAt         time.Time `json:"created_at"`
+	// ScopedOnly is set by AdminRepository.GetAdminRoles when the admin holds
+	// the role only within regions or cities
+	ScopedOnly bool `gorm:"->;-:migration" json:"scoped_only,omitempty"`
+}
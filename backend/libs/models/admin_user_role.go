@@ -0,0 +1,22 @@
+package models
+
+// AdminUserRole assigns a role to an admin. RegionID or CityID, at most one
+// of them, limits the role's permissions to that region or city; without
+// either the assignment applies everywhere. Super-admin roles are never scoped.
+type AdminUserRole struct {
+	ID       uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	AdminID  uint       `gorm:"not null;index" json:"admin_id"`
+	RoleID   uint       `gorm:"not null" json:"role_id"`
+	RegionID *uint      `json:"region_id,omitempty"`
+	CityID   *uint      `json:"city_id,omitempty"`
+	Role     *AdminRole `gorm:"foreignKey:RoleID" json:"role,omitempty"`
+}
+
+func (AdminUserRole) TableName() string {
+	return "admin_user_roles"
+}
+
+// Scoped reports whether the assignment is limited to a region or city
+func (a *AdminUserRole) Scoped() bool {
+	return a.RegionID != nil || a.CityID != nil
+}
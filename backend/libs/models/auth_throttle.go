@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// AuthThrottle is the shared failure counter behind the Postgres brute-force store
+type AuthThrottle struct {
+	ThrottleKey string     `gorm:"primaryKey;size:255" json:"throttle_key"`
+	Failures    int        `gorm:"not null;default:0" json:"failures"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	ExpiresAt   time.Time  `gorm:"not null;index" json:"expires_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
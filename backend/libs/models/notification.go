@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type NotificationStatus string
+
+const (
+	NotificationPending NotificationStatus = "pending"
+	NotificationSent    NotificationStatus = "sent"
+	NotificationFailed  NotificationStatus = "failed"
+)
+
+// NotificationDelivery records every outbound message and its delivery outcome.
+// The rendered body is never stored because it may carry one-time codes.
+type NotificationDelivery struct {
+	ID        uint               `gorm:"primaryKey;autoIncrement" json:"id"`
+	Channel   string             `gorm:"size:20;not null;index" json:"channel"`
+	Recipient string             `gorm:"size:255;not null;index" json:"recipient"`
+	Template  string             `gorm:"size:100;not null" json:"template"`
+	Status    NotificationStatus `gorm:"size:20;not null;index" json:"status"`
+	Attempts  int                `gorm:"default:0;not null" json:"attempts"`
+	LastError *string            `gorm:"type:text" json:"last_error,omitempty"`
+	SentAt    *time.Time         `json:"sent_at,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
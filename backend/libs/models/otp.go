@@ -15,14 +15,23 @@ const (
 	OwnerTypeAdmin     OwnerType = "admin"
 )
 
+const (
+	OTPPurposeEmailVerification = "email_verification"
+	OTPPurposePasswordReset     = "password_reset"
+	OTPPurposePhoneVerification = "phone_verification"
+	OTPPurposePhoneSignin       = "phone_signin"
+	OTPPurposeMagicLink         = "magic_link" // stores the link token's jti, not a typed code
+)
+
 type OTP struct {
 	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
 	OwnerID   uint       `gorm:"not null;index" json:"-"`
 	OwnerType OwnerType  `gorm:"size:20;not null;index" json:"-"`
-	Code      string     `gorm:"size:6;not null" json:"-"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"` // HMAC of the code, see auth.HashOTPCode
 	Purpose   string     `gorm:"size:50;not null;index" json:"-"`
 	ExpiresAt time.Time  `gorm:"not null;index" json:"-"`
 	Used      bool       `gorm:"default:false;index" json:"-"`
+	Attempts  int        `gorm:"default:0;not null" json:"-"` // wrong codes entered; the OTP is burnt at the limit
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
@@ -24,6 +24,8 @@ type Partner struct {
 	
 	Status         PartnerStatus `gorm:"type:partner_status;default:'unverified';not null" json:"status"`
 	EmailVerified  bool          `gorm:"default:false;not null" json:"email_verified"`
+	PhoneVerified  bool          `gorm:"default:false;not null" json:"phone_verified"` // reset whenever the phone changes
+	PhoneVerifiedAt *time.Time   `json:"phone_verified_at,omitempty"`
 	
 	LastLoginAt *time.Time    `json:"last_login_at,omitempty"`
 	CreatedAt   time.Time     `json:"created_at"`
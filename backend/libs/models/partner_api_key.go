@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Scopes a partner API key can be granted. Keys never reach session-only
+// endpoints such as password, session or key management.
+const (
+	APIKeyScopeProfileRead  = "profile:read"
+	APIKeyScopeProfileWrite = "profile:write"
+)
+
+// PartnerAPIKeyScopes lists every scope a key may be created with
+var PartnerAPIKeyScopes = []string{APIKeyScopeProfileRead, APIKeyScopeProfileWrite}
+
+const (
+	APIKeyActive  = "active"
+	APIKeyExpired = "expired"
+	APIKeyRevoked = "revoked"
+)
+
+// PartnerAPIKey lets a partner's own software call CarHub without a sign-in.
+// Only the public prefix and a hash of the full key are stored; the key
+// itself is shown once, when it is created.
+type PartnerAPIKey struct {
+	ID               uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	PartnerID        uint       `gorm:"not null;index" json:"partner_id"`
+	Name             string     `gorm:"size:100;not null" json:"name"`
+	Prefix           string     `gorm:"size:20;uniqueIndex;not null" json:"prefix"`
+	KeyHash          string     `gorm:"size:64;not null" json:"-"`
+	Scopes           []string   `gorm:"serializer:json;type:jsonb;not null" json:"scopes"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"` // nil never expires
+	LastUsedAt       *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP       string     `gorm:"size:45" json:"last_used_ip,omitempty"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevokedByAdminID *uint      `json:"revoked_by_admin_id,omitempty"` // nil when the partner revoked it
+	State            string     `gorm:"-" json:"state"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	Partner *Partner `gorm:"foreignKey:PartnerID" json:"-"`
+}
+
+// CurrentState derives active/expired/revoked from the timestamps
+func (k *PartnerAPIKey) CurrentState() string {
+	switch {
+	case k.RevokedAt != nil:
+		return APIKeyRevoked
+	case k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt):
+		return APIKeyExpired
+	default:
+		return APIKeyActive
+	}
+}
+
+// HasScope reports whether the key was granted scope
+func (k *PartnerAPIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AfterFind fills State so listings show it without extra work
+func (k *PartnerAPIKey) AfterFind(tx *gorm.DB) error {
+	k.State = k.CurrentState()
+	return nil
+}
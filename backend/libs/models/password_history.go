@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// PasswordHistory keeps hashes of previous passwords so they can't be reused
+type PasswordHistory struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	OwnerType    OwnerType `gorm:"size:20;not null;index:idx_password_histories_owner" json:"-"`
+	OwnerID      uint      `gorm:"not null;index:idx_password_histories_owner" json:"-"`
+	PasswordHash string    `gorm:"size:255;not null" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// RevokedSession denies every access token carrying the session's id (sid)
+// until the last one issued for it would have expired; after ExpiresAt the
+// row can be purged.
+type RevokedSession struct {
+	SessionID string    `gorm:"primaryKey;size:36" json:"session_id"`
+	OwnerType OwnerType `gorm:"size:20;not null" json:"owner_type"`
+	OwnerID   uint      `gorm:"not null" json:"owner_id"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
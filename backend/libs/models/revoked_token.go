@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// RevokedToken denies a single JWT by its jti until the token would have
+// expired anyway; after ExpiresAt the row can be purged.
+type RevokedToken struct {
+	JTI       string    `gorm:"column:jti;primaryKey;size:36" json:"jti"`
+	OwnerType OwnerType `gorm:"size:20;not null" json:"owner_type"`
+	OwnerID   uint      `gorm:"not null" json:"owner_id"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
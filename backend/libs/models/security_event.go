@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+const (
+	SecurityEventRefreshTokenReuse    = "refresh_token_reuse"
+	SecurityEventAPIKeyRevokedByAdmin = "api_key_revoked_by_admin"
+	SecurityEventImpersonationStarted = "impersonation_started"
+	SecurityEventImpersonatedRequest  = "impersonated_request"
+)
+
+// SecurityEvent is an append-only record of suspicious or security relevant activity
+type SecurityEvent struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	EventType string    `gorm:"size:50;not null;index" json:"event_type"`
+	OwnerType OwnerType `gorm:"size:20;not null;index:idx_security_events_owner" json:"owner_type"`
+	OwnerID   uint      `gorm:"not null;index:idx_security_events_owner" json:"owner_id"`
+	IPAddress string    `gorm:"size:45" json:"ip_address,omitempty"`
+	UserAgent string    `gorm:"size:255" json:"user_agent,omitempty"`
+	Details   string    `gorm:"type:jsonb" json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// SubjectRevocation denies every token issued to an owner before RevokedBefore,
+// e.g. when an admin is deactivated.
+type SubjectRevocation struct {
+	OwnerType     OwnerType `gorm:"primaryKey;size:20" json:"owner_type"`
+	OwnerID       uint      `gorm:"primaryKey" json:"owner_id"`
+	RevokedBefore time.Time `gorm:"not null" json:"revoked_before"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,63 @@
+// libs/notifier/log_sender.go
+package notifier
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jafoor/carhub/libs/logger"
+)
+
+// LogSender is the local dev / test sink. Messages are written to the
+// application log and, when a path is set, appended to a JSON-lines file.
+// Bodies carry codes and sign-in links, so they only go to the file.
+type LogSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewLogSender(path string) *LogSender {
+	return &LogSender{path: path}
+}
+
+func (s *LogSender) Send(msg Message) error {
+	logger.Info().
+		Str("channel", string(msg.Channel)).
+		Str("to", msg.To).
+		Str("subject", msg.Subject).
+		Msg("Notification (log driver)")
+
+	if s.path == "" {
+		return nil
+	}
+
+	line, err := json.Marshal(struct {
+		Channel Channel   `json:"channel"`
+		To      string    `json:"to"`
+		Subject string    `json:"subject,omitempty"`
+		Body    string    `json:"body"`
+		SentAt  time.Time `json:"sent_at"`
+	}{msg.Channel, msg.To, msg.Subject, msg.Body, time.Now()})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// SendSMS lets the log sink stand in for a real SMS gateway
+func (s *LogSender) SendSMS(to, body string) error {
+	return s.Send(Message{Channel: ChannelSMS, To: to, Body: body})
+}
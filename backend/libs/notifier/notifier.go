@@ -0,0 +1,186 @@
+// libs/notifier/notifier.go
+package notifier
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jafoor/carhub/libs/config"
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/logger"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/libs/repository"
+)
+
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelSMS   Channel = "sms"
+)
+
+// Message is a rendered notification ready to be handed to a Sender
+type Message struct {
+	Channel Channel
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers a rendered message over a single channel
+type Sender interface {
+	Send(msg Message) error
+}
+
+// Notifier renders a named template and delivers it, retrying failed attempts
+type Notifier interface {
+	Notify(channel Channel, to, templateName string, data map[string]interface{}) error
+}
+
+type notifier struct {
+	senders     map[Channel]Sender
+	repo        repository.NotificationRepository
+	maxAttempts int
+	backoff     time.Duration
+	maxWait     time.Duration
+}
+
+// New builds a notifier from explicit senders. repo may be nil to skip delivery records.
+// Notify runs on the request path, so retries stop once their backoff would
+// exceed maxWait in total.
+func New(
+	senders map[Channel]Sender,
+	repo repository.NotificationRepository,
+	maxAttempts int,
+	backoff time.Duration,
+	maxWait time.Duration,
+) Notifier {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &notifier{
+		senders:     senders,
+		repo:        repo,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		maxWait:     maxWait,
+	}
+}
+
+// NewFromConfig wires senders according to EMAIL_DRIVER / SMS_DRIVER. There
+// is no default driver: a channel without one isn't configured, so a deploy
+// missing its SMTP settings fails to send rather than logging codes.
+func NewFromConfig() Notifier {
+	senders := map[Channel]Sender{}
+
+	switch config.App.EmailDriver {
+	case "smtp":
+		senders[ChannelEmail] = NewSMTPSender(
+			config.App.SMTPHost,
+			config.App.SMTPPort,
+			config.App.SMTPUsername,
+			config.App.SMTPPassword,
+			config.App.SMTPFrom,
+		)
+	case "log":
+		senders[ChannelEmail] = NewLogSender(config.App.NotifierLogFile)
+	default:
+		logger.Warn().Str("driver", config.App.EmailDriver).Msg("No email driver configured, emails will not be sent")
+	}
+
+	switch gateway, ok := smsGateways[config.App.SMSDriver]; {
+	case ok:
+		senders[ChannelSMS] = NewSMSSender(gateway)
+	case config.App.SMSDriver == "log":
+		senders[ChannelSMS] = NewSMSSender(NewLogSender(config.App.NotifierLogFile))
+	default:
+		logger.Warn().Str("driver", config.App.SMSDriver).Msg("No SMS driver configured, text messages will not be sent")
+	}
+
+	return New(
+		senders,
+		repository.NewNotificationRepository(),
+		config.App.NotifierMaxAttempts,
+		time.Duration(config.App.NotifierRetryBackoff)*time.Millisecond,
+		time.Duration(config.App.NotifierMaxRetryWait)*time.Millisecond,
+	)
+}
+
+func (n *notifier) Notify(channel Channel, to, templateName string, data map[string]interface{}) error {
+	sender, ok := n.senders[channel]
+	if !ok {
+		return errors.New("channel_not_configured")
+	}
+
+	subject, body, err := Render(templateName, channel, data)
+	if err != nil {
+		return err
+	}
+
+	record := &models.NotificationDelivery{
+		Channel:   string(channel),
+		Recipient: to,
+		Template:  templateName,
+		Status:    models.NotificationPending,
+	}
+	n.record(record, true)
+
+	msg := Message{Channel: channel, To: to, Subject: subject, Body: body}
+
+	var lastErr error
+	var waited time.Duration
+	for attempt := 1; attempt <= n.maxAttempts; attempt++ {
+		record.Attempts = attempt
+		if lastErr = sender.Send(msg); lastErr == nil {
+			break
+		}
+
+		logger.Warn().
+			Err(lastErr).
+			Str("channel", string(channel)).
+			Str("template", templateName).
+			Int("attempt", attempt).
+			Msg("Notification delivery attempt failed")
+
+		if attempt == n.maxAttempts {
+			break
+		}
+		delay := n.backoff * time.Duration(1<<(attempt-1))
+		if waited+delay > n.maxWait {
+			break
+		}
+		waited += delay
+		time.Sleep(delay)
+	}
+
+	if lastErr != nil {
+		errMsg := lastErr.Error()
+		record.Status = models.NotificationFailed
+		record.LastError = &errMsg
+		n.record(record, false)
+		return errors.New("notification_delivery_failed")
+	}
+
+	now := time.Now()
+	record.Status = models.NotificationSent
+	record.SentAt = &now
+	n.record(record, false)
+	return nil
+}
+
+// record persists the delivery outcome; bookkeeping failures never block delivery
+func (n *notifier) record(delivery *models.NotificationDelivery, create bool) {
+	if n.repo == nil || database.WriteDB == nil {
+		return
+	}
+
+	var err error
+	if create {
+		err = n.repo.Create(database.WriteDB, delivery)
+	} else {
+		err = n.repo.Update(database.WriteDB, delivery)
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("template", delivery.Template).Msg("Failed to record notification delivery")
+	}
+}
@@ -0,0 +1,28 @@
+// libs/notifier/sms_sender.go
+package notifier
+
+// SMSGateway is implemented by SMS providers (Twilio, local aggregators, ...)
+type SMSGateway interface {
+	SendSMS(to, body string) error
+}
+
+var smsGateways = map[string]SMSGateway{}
+
+// RegisterSMSGateway makes a gateway selectable through SMS_DRIVER.
+// Call it before the routes are registered.
+func RegisterSMSGateway(name string, gateway SMSGateway) {
+	smsGateways[name] = gateway
+}
+
+// SMSSender adapts an SMSGateway to the Sender interface
+type SMSSender struct {
+	gateway SMSGateway
+}
+
+func NewSMSSender(gateway SMSGateway) *SMSSender {
+	return &SMSSender{gateway: gateway}
+}
+
+func (s *SMSSender) Send(msg Message) error {
+	return s.gateway.SendSMS(msg.To, msg.Body)
+}
@@ -0,0 +1,110 @@
+// libs/notifier/templates.go
+package notifier
+
+import (
+	"bytes"
+	"errors"
+	"text/template"
+)
+
+const (
+	TemplateOTPEmailVerification = "otp_email_verification"
+	TemplateOTPPasswordReset     = "otp_password_reset"
+	TemplateOTPPhoneVerification = "otp_phone_verification"
+	TemplateOTPPhoneSignin       = "otp_phone_signin"
+	TemplateAdminInvite          = "admin_invite"
+	TemplateMagicLink            = "magic_link"
+)
+
+// Template holds the per-channel text for a notification. Channels left empty
+// are not supported by that template.
+type Template struct {
+	Subject string
+	Email   string
+	SMS     string
+}
+
+var templates = map[string]Template{
+	TemplateOTPEmailVerification: {
+		Subject: "Verify your CarHub email",
+		Email: "Hi {{.FirstName}},\n\n" +
+			"Your CarHub verification code is {{.Code}}.\n" +
+			"It expires in {{.ExpiresInMinutes}} minutes.\n\n" +
+			"If you did not sign up for CarHub, you can ignore this email.\n",
+		SMS: "Your CarHub verification code is {{.Code}}. It expires in {{.ExpiresInMinutes}} minutes.",
+	},
+	TemplateOTPPasswordReset: {
+		Subject: "Reset your CarHub password",
+		Email: "Hi {{.FirstName}},\n\n" +
+			"Use the code {{.Code}} to reset your CarHub password.\n" +
+			"It expires in {{.ExpiresInMinutes}} minutes.\n\n" +
+			"If you did not request a password reset, you can ignore this email. Your password has not been changed.\n",
+		SMS: "Your CarHub password reset code is {{.Code}}. It expires in {{.ExpiresInMinutes}} minutes.",
+	},
+	TemplateAdminInvite: {
+		Subject: "You have been invited to CarHub admin",
+		Email: "Hi {{.FirstName}},\n\n" +
+			"{{.InviterName}} has invited you to the CarHub admin panel.\n" +
+			"Set your password here to activate your account:\n\n" +
+			"{{.Link}}\n\n" +
+			"The link can be used once and expires in {{.ExpiresInHours}} hours.\n" +
+			"If you were not expecting this invitation, you can ignore this email.\n",
+	},
+	TemplateMagicLink: {
+		Subject: "Your CarHub sign-in link",
+		Email: "Hi {{.FirstName}},\n\n" +
+			"Use this link to sign in to CarHub:\n\n" +
+			"{{.Link}}\n\n" +
+			"The link can be used once and expires in {{.ExpiresInMinutes}} minutes.\n" +
+			"If you did not ask to sign in, you can ignore this email.\n",
+	},
+	TemplateOTPPhoneVerification: {
+		SMS: "Your CarHub phone verification code is {{.Code}}. It expires in {{.ExpiresInMinutes}} minutes.",
+	},
+	TemplateOTPPhoneSignin: {
+		SMS: "Your CarHub sign-in code is {{.Code}}. It expires in {{.ExpiresInMinutes}} minutes. Never share it with anyone.",
+	},
+}
+
+// Render executes the named template for the given channel
+func Render(name string, channel Channel, data map[string]interface{}) (string, string, error) {
+	tpl, ok := templates[name]
+	if !ok {
+		return "", "", errors.New("unknown_template")
+	}
+
+	var source string
+	switch channel {
+	case ChannelEmail:
+		source = tpl.Email
+	case ChannelSMS:
+		source = tpl.SMS
+	}
+	if source == "" {
+		return "", "", errors.New("template_not_supported_for_channel")
+	}
+
+	subject, err := execute(name+".subject", tpl.Subject, data)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := execute(name+"."+string(channel), source, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	return subject, body, nil
+}
+
+func execute(name, source string, data map[string]interface{}) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -2,14 +2,21 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/jafoor/carhub/libs/database"
 	"github.com/jafoor/carhub/libs/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AdminRefreshTokenRepository interface {
 	Create(tx *gorm.DB, token *models.AdminRefreshToken) error
-	FindByAdminID(adminID uint) (*models.AdminRefreshToken, error)
+	Rotate(tx *gorm.DB, token *models.AdminRefreshToken, previousGeneration int) (bool, error)
+	FindBySessionID(sessionID string) (*models.AdminRefreshToken, error)
+	ListActiveByAdminID(adminID uint) ([]models.AdminRefreshToken, error)
+	DeleteByID(tx *gorm.DB, adminID, id uint) (*models.AdminRefreshToken, error)
+	DeleteBySessionID(tx *gorm.DB, sessionID string) error
 	DeleteByAdminID(tx *gorm.DB, adminID uint) error
 }
 
@@ -23,9 +30,25 @@ func (r *adminRefreshTokenRepository) Create(tx *gorm.DB, token *models.AdminRef
 	return tx.Create(token).Error
 }
 
-func (r *adminRefreshTokenRepository) FindByAdminID(adminID uint) (*models.AdminRefreshToken, error) {
+// Rotate stores the new token state only if the session is still at
+// previousGeneration; false means another rotation won the race.
+func (r *adminRefreshTokenRepository) Rotate(tx *gorm.DB, token *models.AdminRefreshToken, previousGeneration int) (bool, error) {
+	result := tx.Model(&models.AdminRefreshToken{}).
+		Where("id = ? AND generation = ?", token.ID, previousGeneration).
+		Updates(map[string]interface{}{
+			"token_hash":   token.TokenHash,
+			"generation":   token.Generation,
+			"ip_address":   token.IPAddress,
+			"user_agent":   token.UserAgent,
+			"last_used_at": token.LastUsedAt,
+			"expires_at":   token.ExpiresAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *adminRefreshTokenRepository) FindBySessionID(sessionID string) (*models.AdminRefreshToken, error) {
 	var token models.AdminRefreshToken
-	err := database.ReadDB.Where("admin_id = ?", adminID).First(&token).Error
+	err := database.ReadDB.Where("session_id = ?", sessionID).First(&token).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -35,6 +58,31 @@ func (r *adminRefreshTokenRepository) FindByAdminID(adminID uint) (*models.Admin
 	return &token, nil
 }
 
+// ListActiveByAdminID returns unexpired sessions, most recently used first
+func (r *adminRefreshTokenRepository) ListActiveByAdminID(adminID uint) ([]models.AdminRefreshToken, error) {
+	var tokens []models.AdminRefreshToken
+	err := database.ReadDB.
+		Where("admin_id = ? AND expires_at > ?", adminID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+// DeleteByID removes a single session owned by the admin and returns it; nil
+// means nothing matched
+func (r *adminRefreshTokenRepository) DeleteByID(tx *gorm.DB, adminID, id uint) (*models.AdminRefreshToken, error) {
+	var token models.AdminRefreshToken
+	result := tx.Clauses(clause.Returning{}).Where("id = ? AND admin_id = ?", id, adminID).Delete(&token)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+func (r *adminRefreshTokenRepository) DeleteBySessionID(tx *gorm.DB, sessionID string) error {
+	return tx.Where("session_id = ?", sessionID).Delete(&models.AdminRefreshToken{}).Error
+}
+
 func (r *adminRefreshTokenRepository) DeleteByAdminID(tx *gorm.DB, adminID uint) error {
 	return tx.Where("admin_id = ?", adminID).Delete(&models.AdminRefreshToken{}).Error
 }
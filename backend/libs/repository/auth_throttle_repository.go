@@ -0,0 +1,67 @@
+// libs/repository/auth_throttle_repository.go
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/models"
+	"gorm.io/gorm"
+)
+
+type AuthThrottleRepository interface {
+	FindByKey(key string) (*models.AuthThrottle, error)
+	Increment(tx *gorm.DB, key string, expiresAt time.Time) (int, error)
+	Lock(tx *gorm.DB, key string, until time.Time) error
+	Delete(tx *gorm.DB, key string) error
+}
+
+type authThrottleRepository struct{}
+
+func NewAuthThrottleRepository() AuthThrottleRepository {
+	return &authThrottleRepository{}
+}
+
+// FindByKey returns the live counter for the key, ignoring expired rows
+func (r *authThrottleRepository) FindByKey(key string) (*models.AuthThrottle, error) {
+	var throttle models.AuthThrottle
+	err := database.ReadDB.Where("throttle_key = ? AND expires_at > ?", key, time.Now()).First(&throttle).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// Increment atomically adds a failure, restarting expired counters at one
+func (r *authThrottleRepository) Increment(tx *gorm.DB, key string, expiresAt time.Time) (int, error) {
+	var failures int
+	err := tx.Raw(`
+		INSERT INTO auth_throttles (throttle_key, failures, expires_at, updated_at)
+		VALUES (?, 1, ?, NOW())
+		ON CONFLICT (throttle_key) DO UPDATE SET
+			failures = CASE WHEN auth_throttles.expires_at < NOW() THEN 1 ELSE auth_throttles.failures + 1 END,
+			locked_until = CASE WHEN auth_throttles.expires_at < NOW() THEN NULL ELSE auth_throttles.locked_until END,
+			expires_at = GREATEST(EXCLUDED.expires_at, auth_throttles.locked_until),
+			updated_at = NOW()
+		RETURNING failures`,
+		key, expiresAt,
+	).Scan(&failures).Error
+	return failures, err
+}
+
+func (r *authThrottleRepository) Lock(tx *gorm.DB, key string, until time.Time) error {
+	return tx.Model(&models.AuthThrottle{}).
+		Where("throttle_key = ?", key).
+		Updates(map[string]interface{}{
+			"locked_until": until,
+			"expires_at":   gorm.Expr("GREATEST(expires_at, ?)", until),
+		}).Error
+}
+
+func (r *authThrottleRepository) Delete(tx *gorm.DB, key string) error {
+	return tx.Where("throttle_key = ?", key).Delete(&models.AuthThrottle{}).Error
+}
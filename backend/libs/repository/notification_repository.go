@@ -0,0 +1,26 @@
+// libs/repository/notification_repository.go
+package repository
+
+import (
+	"github.com/jafoor/carhub/libs/models"
+	"gorm.io/gorm"
+)
+
+type NotificationRepository interface {
+	Create(tx *gorm.DB, delivery *models.NotificationDelivery) error
+	Update(tx *gorm.DB, delivery *models.NotificationDelivery) error
+}
+
+type notificationRepository struct{}
+
+func NewNotificationRepository() NotificationRepository {
+	return &notificationRepository{}
+}
+
+func (r *notificationRepository) Create(tx *gorm.DB, delivery *models.NotificationDelivery) error {
+	return tx.Create(delivery).Error
+}
+
+func (r *notificationRepository) Update(tx *gorm.DB, delivery *models.NotificationDelivery) error {
+	return tx.Save(delivery).Error
+}
@@ -12,9 +12,13 @@ import (
 
 type OTPRepository interface {
 	Create(tx *gorm.DB, otp *models.OTP) error
-	FindValidOTP(ownerID uint, ownerType models.OwnerType, code, purpose string) (*models.OTP, error)
+	FindActiveOTP(ownerID uint, ownerType models.OwnerType, purpose string) (*models.OTP, error)
 	MarkAsUsed(tx *gorm.DB, otpID uint) error
+	Consume(tx *gorm.DB, otpID uint) (bool, error)
+	InvalidateActive(tx *gorm.DB, ownerID uint, ownerType models.OwnerType, purpose string) error
+	RecordFailedAttempt(tx *gorm.DB, otpID uint, maxAttempts int) (int, error)
 	CountRecentOTPs(ownerID uint, ownerType models.OwnerType, purpose string, duration time.Duration) (int64, error)
+	CountActiveOTPs(ownerID uint, ownerType models.OwnerType, purpose string) (int64, error)
 	DeleteExpiredOTPs(tx *gorm.DB) error
 }
 
@@ -28,15 +32,17 @@ func (r *otpRepository) Create(tx *gorm.DB, otp *models.OTP) error {
 	return tx.Create(otp).Error
 }
 
-func (r *otpRepository) FindValidOTP(
+// FindActiveOTP returns the newest unused, unexpired code for the owner and purpose
+func (r *otpRepository) FindActiveOTP(
 	ownerID uint,
 	ownerType models.OwnerType,
-	code, purpose string,
+	purpose string,
 ) (*models.OTP, error) {
 	var otp models.OTP
 	err := database.ReadDB.
-		Where("owner_id = ? AND owner_type = ? AND purpose = ? AND code = ? AND used = ? AND expires_at > ?",
-			ownerID, ownerType, purpose, code, false, time.Now()).
+		Where("owner_id = ? AND owner_type = ? AND purpose = ? AND used = ? AND expires_at > ?",
+			ownerID, ownerType, purpose, false, time.Now()).
+		Order("created_at DESC").
 		First(&otp).Error
 
 	if err != nil {
@@ -54,6 +60,39 @@ func (r *otpRepository) MarkAsUsed(tx *gorm.DB, otpID uint) error {
 		Update("used", true).Error
 }
 
+// Consume marks an OTP used only if it still isn't, so of two concurrent
+// redemptions exactly one gets true
+func (r *otpRepository) Consume(tx *gorm.DB, otpID uint) (bool, error) {
+	result := tx.Model(&models.OTP{}).
+		Where("id = ? AND used = ?", otpID, false).
+		Update("used", true)
+	return result.RowsAffected > 0, result.Error
+}
+
+// InvalidateActive burns every outstanding code for the owner and purpose
+func (r *otpRepository) InvalidateActive(
+	tx *gorm.DB,
+	ownerID uint,
+	ownerType models.OwnerType,
+	purpose string,
+) error {
+	return tx.Model(&models.OTP{}).
+		Where("owner_id = ? AND owner_type = ? AND purpose = ? AND used = ?",
+			ownerID, ownerType, purpose, false).
+		Update("used", true).Error
+}
+
+// RecordFailedAttempt counts a wrong code and burns the OTP once maxAttempts
+// is reached. It returns the updated attempt count.
+func (r *otpRepository) RecordFailedAttempt(tx *gorm.DB, otpID uint, maxAttempts int) (int, error) {
+	var attempts int
+	err := tx.Raw(
+		"UPDATE otps SET attempts = attempts + 1, used = (attempts + 1 >= ?), updated_at = ? WHERE id = ? RETURNING attempts",
+		maxAttempts, time.Now(), otpID,
+	).Scan(&attempts).Error
+	return attempts, err
+}
+
 func (r *otpRepository) CountRecentOTPs(
 	ownerID uint,
 	ownerType models.OwnerType,
@@ -63,7 +102,8 @@ func (r *otpRepository) CountRecentOTPs(
 	var count int64
 	cutoffTime := time.Now().Add(-duration)
 
-	err := database.ReadDB.Model(&models.OTP{}).
+	// Unscoped so codes cleaned up by DeleteExpiredOTPs still count against limits
+	err := database.ReadDB.Unscoped().Model(&models.OTP{}).
 		Where("owner_id = ? AND owner_type = ? AND purpose = ? AND created_at > ?",
 			ownerID, ownerType, purpose, cutoffTime).
 		Count(&count).Error
@@ -71,6 +111,21 @@ func (r *otpRepository) CountRecentOTPs(
 	return count, err
 }
 
+// CountActiveOTPs counts unused, unexpired codes still outstanding for the owner
+func (r *otpRepository) CountActiveOTPs(
+	ownerID uint,
+	ownerType models.OwnerType,
+	purpose string,
+) (int64, error) {
+	var count int64
+	err := database.ReadDB.Model(&models.OTP{}).
+		Where("owner_id = ? AND owner_type = ? AND purpose = ? AND used = ? AND expires_at > ?",
+			ownerID, ownerType, purpose, false, time.Now()).
+		Count(&count).Error
+
+	return count, err
+}
+
 func (r *otpRepository) DeleteExpiredOTPs(tx *gorm.DB) error {
 	return tx.Where("expires_at < ? OR used = ?", time.Now(), true).
 		Delete(&models.OTP{}).Error
@@ -0,0 +1,82 @@
+// libs/repository/partner_api_key_repository.go
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/models"
+	"gorm.io/gorm"
+)
+
+type PartnerAPIKeyRepository interface {
+	Create(tx *gorm.DB, key *models.PartnerAPIKey) error
+	FindByPrefix(prefix string) (*models.PartnerAPIKey, error)
+	ListByPartnerID(partnerID uint) ([]models.PartnerAPIKey, error)
+	CountActiveByPartnerID(partnerID uint) (int64, error)
+	Revoke(tx *gorm.DB, partnerID, keyID uint, revokedByAdminID *uint) (bool, error)
+	TouchLastUsed(tx *gorm.DB, keyID uint, at time.Time, ip string) error
+}
+
+type partnerAPIKeyRepository struct{}
+
+func NewPartnerAPIKeyRepository() PartnerAPIKeyRepository {
+	return &partnerAPIKeyRepository{}
+}
+
+func (r *partnerAPIKeyRepository) Create(tx *gorm.DB, key *models.PartnerAPIKey) error {
+	return tx.Create(key).Error
+}
+
+// FindByPrefix loads a key with its partner for authentication
+func (r *partnerAPIKeyRepository) FindByPrefix(prefix string) (*models.PartnerAPIKey, error) {
+	var key models.PartnerAPIKey
+	err := database.ReadDB.Preload("Partner").Where("prefix = ?", prefix).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListByPartnerID returns every key of the partner, revoked ones included, newest first
+func (r *partnerAPIKeyRepository) ListByPartnerID(partnerID uint) ([]models.PartnerAPIKey, error) {
+	var keys []models.PartnerAPIKey
+	err := database.ReadDB.
+		Where("partner_id = ?", partnerID).
+		Order("created_at DESC").
+		Find(&keys).Error
+	return keys, err
+}
+
+func (r *partnerAPIKeyRepository) CountActiveByPartnerID(partnerID uint) (int64, error) {
+	var count int64
+	err := database.ReadDB.Model(&models.PartnerAPIKey{}).
+		Where("partner_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", partnerID, time.Now()).
+		Count(&count).Error
+	return count, err
+}
+
+// Revoke marks the partner's key revoked. It returns false when no such
+// unrevoked key exists.
+func (r *partnerAPIKeyRepository) Revoke(tx *gorm.DB, partnerID, keyID uint, revokedByAdminID *uint) (bool, error) {
+	result := tx.Model(&models.PartnerAPIKey{}).
+		Where("id = ? AND partner_id = ? AND revoked_at IS NULL", keyID, partnerID).
+		Updates(map[string]interface{}{
+			"revoked_at":          time.Now(),
+			"revoked_by_admin_id": revokedByAdminID,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *partnerAPIKeyRepository) TouchLastUsed(tx *gorm.DB, keyID uint, at time.Time, ip string) error {
+	return tx.Model(&models.PartnerAPIKey{}).
+		Where("id = ?", keyID).
+		UpdateColumns(map[string]interface{}{
+			"last_used_at": at,
+			"last_used_ip": ip,
+		}).Error
+}
@@ -2,14 +2,21 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/jafoor/carhub/libs/database"
 	"github.com/jafoor/carhub/libs/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PartnerRefreshTokenRepository interface {
 	Create(tx *gorm.DB, token *models.PartnerRefreshToken) error
-	FindByPartnerID(partnerID uint) (*models.PartnerRefreshToken, error)
+	Rotate(tx *gorm.DB, token *models.PartnerRefreshToken, previousGeneration int) (bool, error)
+	FindBySessionID(sessionID string) (*models.PartnerRefreshToken, error)
+	ListActiveByPartnerID(partnerID uint) ([]models.PartnerRefreshToken, error)
+	DeleteByID(tx *gorm.DB, partnerID, id uint) (*models.PartnerRefreshToken, error)
+	DeleteBySessionID(tx *gorm.DB, sessionID string) error
 	DeleteByPartnerID(tx *gorm.DB, partnerID uint) error
 }
 
@@ -23,9 +30,25 @@ func (r *partnerRefreshTokenRepository) Create(tx *gorm.DB, token *models.Partne
 	return tx.Create(token).Error
 }
 
-func (r *partnerRefreshTokenRepository) FindByPartnerID(partnerID uint) (*models.PartnerRefreshToken, error) {
+// Rotate stores the new token state only if the session is still at
+// previousGeneration; false means another rotation won the race.
+func (r *partnerRefreshTokenRepository) Rotate(tx *gorm.DB, token *models.PartnerRefreshToken, previousGeneration int) (bool, error) {
+	result := tx.Model(&models.PartnerRefreshToken{}).
+		Where("id = ? AND generation = ?", token.ID, previousGeneration).
+		Updates(map[string]interface{}{
+			"token_hash":   token.TokenHash,
+			"generation":   token.Generation,
+			"ip_address":   token.IPAddress,
+			"user_agent":   token.UserAgent,
+			"last_used_at": token.LastUsedAt,
+			"expires_at":   token.ExpiresAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *partnerRefreshTokenRepository) FindBySessionID(sessionID string) (*models.PartnerRefreshToken, error) {
 	var token models.PartnerRefreshToken
-	err := database.ReadDB.Where("partner_id = ?", partnerID).First(&token).Error
+	err := database.ReadDB.Where("session_id = ?", sessionID).First(&token).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -35,6 +58,31 @@ func (r *partnerRefreshTokenRepository) FindByPartnerID(partnerID uint) (*models
 	return &token, nil
 }
 
+// ListActiveByPartnerID returns unexpired sessions, most recently used first
+func (r *partnerRefreshTokenRepository) ListActiveByPartnerID(partnerID uint) ([]models.PartnerRefreshToken, error) {
+	var tokens []models.PartnerRefreshToken
+	err := database.ReadDB.
+		Where("partner_id = ? AND expires_at > ?", partnerID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+// DeleteByID removes a single session owned by the partner and returns it; nil
+// means nothing matched
+func (r *partnerRefreshTokenRepository) DeleteByID(tx *gorm.DB, partnerID, id uint) (*models.PartnerRefreshToken, error) {
+	var token models.PartnerRefreshToken
+	result := tx.Clauses(clause.Returning{}).Where("id = ? AND partner_id = ?", id, partnerID).Delete(&token)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+func (r *partnerRefreshTokenRepository) DeleteBySessionID(tx *gorm.DB, sessionID string) error {
+	return tx.Where("session_id = ?", sessionID).Delete(&models.PartnerRefreshToken{}).Error
+}
+
 func (r *partnerRefreshTokenRepository) DeleteByPartnerID(tx *gorm.DB, partnerID uint) error {
 	return tx.Where("partner_id = ?", partnerID).Delete(&models.PartnerRefreshToken{}).Error
-}
\ No newline at end of file
+}
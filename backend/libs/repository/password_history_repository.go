@@ -0,0 +1,49 @@
+// libs/repository/password_history_repository.go
+package repository
+
+import (
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/models"
+	"gorm.io/gorm"
+)
+
+type PasswordHistoryRepository interface {
+	Create(tx *gorm.DB, entry *models.PasswordHistory) error
+	ListRecent(ownerType models.OwnerType, ownerID uint, limit int) ([]models.PasswordHistory, error)
+	Prune(tx *gorm.DB, ownerType models.OwnerType, ownerID uint, keep int) error
+}
+
+type passwordHistoryRepository struct{}
+
+func NewPasswordHistoryRepository() PasswordHistoryRepository {
+	return &passwordHistoryRepository{}
+}
+
+func (r *passwordHistoryRepository) Create(tx *gorm.DB, entry *models.PasswordHistory) error {
+	return tx.Create(entry).Error
+}
+
+// ListRecent returns the newest entries first
+func (r *passwordHistoryRepository) ListRecent(ownerType models.OwnerType, ownerID uint, limit int) ([]models.PasswordHistory, error) {
+	var entries []models.PasswordHistory
+	err := database.ReadDB.
+		Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
+
+// Prune deletes everything but the newest keep entries
+func (r *passwordHistoryRepository) Prune(tx *gorm.DB, ownerType models.OwnerType, ownerID uint, keep int) error {
+	return tx.Exec(
+		`DELETE FROM password_histories
+		 WHERE owner_type = ? AND owner_id = ? AND id NOT IN (
+		     SELECT id FROM password_histories
+		     WHERE owner_type = ? AND owner_id = ?
+		     ORDER BY created_at DESC, id DESC
+		     LIMIT ?
+		 )`,
+		ownerType, ownerID, ownerType, ownerID, keep,
+	).Error
+}
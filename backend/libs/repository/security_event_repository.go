@@ -0,0 +1,21 @@
+// libs/repository/security_event_repository.go
+package repository
+
+import (
+	"github.com/jafoor/carhub/libs/models"
+	"gorm.io/gorm"
+)
+
+type SecurityEventRepository interface {
+	Create(tx *gorm.DB, event *models.SecurityEvent) error
+}
+
+type securityEventRepository struct{}
+
+func NewSecurityEventRepository() SecurityEventRepository {
+	return &securityEventRepository{}
+}
+
+func (r *securityEventRepository) Create(tx *gorm.DB, event *models.SecurityEvent) error {
+	return tx.Create(event).Error
+}
@@ -0,0 +1,79 @@
+// libs/repository/token_revocation_repository.go
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TokenRevocationRepository interface {
+	CreateRevokedToken(tx *gorm.DB, token *models.RevokedToken) error
+	IsTokenRevoked(jti string) (bool, error)
+	DeleteExpiredTokens(tx *gorm.DB) error
+	CreateRevokedSession(tx *gorm.DB, session *models.RevokedSession) error
+	IsSessionRevoked(sessionID string) (bool, error)
+	DeleteExpiredSessions(tx *gorm.DB) error
+	SaveSubjectRevocation(tx *gorm.DB, revocation *models.SubjectRevocation) error
+	FindSubjectRevocation(ownerType models.OwnerType, ownerID uint) (*models.SubjectRevocation, error)
+}
+
+type tokenRevocationRepository struct{}
+
+func NewTokenRevocationRepository() TokenRevocationRepository {
+	return &tokenRevocationRepository{}
+}
+
+// CreateRevokedToken is idempotent: revoking the same jti twice is not an error
+func (r *tokenRevocationRepository) CreateRevokedToken(tx *gorm.DB, token *models.RevokedToken) error {
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+func (r *tokenRevocationRepository) IsTokenRevoked(jti string) (bool, error) {
+	var count int64
+	err := database.ReadDB.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *tokenRevocationRepository) DeleteExpiredTokens(tx *gorm.DB) error {
+	return tx.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error
+}
+
+// CreateRevokedSession is idempotent: revoking the same session twice is not an error
+func (r *tokenRevocationRepository) CreateRevokedSession(tx *gorm.DB, session *models.RevokedSession) error {
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(session).Error
+}
+
+func (r *tokenRevocationRepository) IsSessionRevoked(sessionID string) (bool, error) {
+	var count int64
+	err := database.ReadDB.Model(&models.RevokedSession{}).Where("session_id = ?", sessionID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *tokenRevocationRepository) DeleteExpiredSessions(tx *gorm.DB) error {
+	return tx.Where("expires_at < ?", time.Now()).Delete(&models.RevokedSession{}).Error
+}
+
+// SaveSubjectRevocation inserts the cut-off or moves an existing one forward
+func (r *tokenRevocationRepository) SaveSubjectRevocation(tx *gorm.DB, revocation *models.SubjectRevocation) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "owner_type"}, {Name: "owner_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_before", "updated_at"}),
+	}).Create(revocation).Error
+}
+
+func (r *tokenRevocationRepository) FindSubjectRevocation(ownerType models.OwnerType, ownerID uint) (*models.SubjectRevocation, error) {
+	var revocation models.SubjectRevocation
+	err := database.ReadDB.Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).First(&revocation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &revocation, nil
+}
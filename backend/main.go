@@ -5,6 +5,8 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/authz"
 	"github.com/jafoor/carhub/libs/config"
 	"github.com/jafoor/carhub/libs/database"
 	"github.com/jafoor/carhub/libs/logger"
@@ -16,6 +18,7 @@ import (
 
 func main() {
 	config.LoadConfig()
+	auth.LoadKeys()
 
 	database.Connect(database.DBConfig{
 		WriteDSN: config.App.WriteDBUrl,
@@ -39,10 +42,21 @@ func main() {
 		return c.JSON(fiber.Map{"message": "CarHub API Running 🚗"})
 	})
 
+	// Public keys for services that verify CarHub tokens
+	app.Get("/.well-known/jwks.json", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+		return c.JSON(auth.JWKS())
+	})
+
 	partnerRoutes.RegisterPartnerRoutes(app)
 	adminRoutes.RegisterAdminRoutes(app)
 	settingsRoutes.RegisterSettingsRoutes(app)
 
+	// Routes have declared their permissions by now
+	if err := authz.SeedPermissions(); err != nil {
+		logger.Error().Err(err).Msg("Failed to seed admin permissions")
+	}
+
 	port := config.App.ServerPort
 	logger.Log.Info().Msgf("Server running on port %s", port)
 	app.Listen(fmt.Sprintf(":%s", port))
@@ -0,0 +1,16 @@
+CREATE TABLE notification_deliveries (
+    id SERIAL PRIMARY KEY,
+    channel VARCHAR(20) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    template VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_notification_deliveries_channel ON notification_deliveries(channel);
+CREATE INDEX idx_notification_deliveries_recipient ON notification_deliveries(recipient);
+CREATE INDEX idx_notification_deliveries_status ON notification_deliveries(status);
@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS idx_admin_refresh_tokens_session_id;
+ALTER TABLE admin_refresh_tokens
+    DROP COLUMN IF EXISTS session_id,
+    DROP COLUMN IF EXISTS device_name,
+    DROP COLUMN IF EXISTS ip_address,
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS last_used_at;
+
+DROP INDEX IF EXISTS idx_partner_refresh_tokens_session_id;
+ALTER TABLE partner_refresh_tokens
+    DROP COLUMN IF EXISTS session_id,
+    DROP COLUMN IF EXISTS device_name,
+    DROP COLUMN IF EXISTS ip_address,
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS last_used_at;
@@ -0,0 +1,29 @@
+ALTER TABLE partner_refresh_tokens
+    ADD COLUMN session_id VARCHAR(36),
+    ADD COLUMN device_name VARCHAR(100),
+    ADD COLUMN ip_address VARCHAR(45),
+    ADD COLUMN user_agent VARCHAR(255),
+    ADD COLUMN last_used_at TIMESTAMP;
+
+UPDATE partner_refresh_tokens SET session_id = gen_random_uuid()::text, last_used_at = created_at;
+
+ALTER TABLE partner_refresh_tokens
+    ALTER COLUMN session_id SET NOT NULL,
+    ALTER COLUMN last_used_at SET NOT NULL;
+
+CREATE UNIQUE INDEX idx_partner_refresh_tokens_session_id ON partner_refresh_tokens(session_id);
+
+ALTER TABLE admin_refresh_tokens
+    ADD COLUMN session_id VARCHAR(36),
+    ADD COLUMN device_name VARCHAR(100),
+    ADD COLUMN ip_address VARCHAR(45),
+    ADD COLUMN user_agent VARCHAR(255),
+    ADD COLUMN last_used_at TIMESTAMP;
+
+UPDATE admin_refresh_tokens SET session_id = gen_random_uuid()::text, last_used_at = created_at;
+
+ALTER TABLE admin_refresh_tokens
+    ALTER COLUMN session_id SET NOT NULL,
+    ALTER COLUMN last_used_at SET NOT NULL;
+
+CREATE UNIQUE INDEX idx_admin_refresh_tokens_session_id ON admin_refresh_tokens(session_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS security_events;
+ALTER TABLE admin_refresh_tokens DROP COLUMN IF EXISTS generation;
+ALTER TABLE partner_refresh_tokens DROP COLUMN IF EXISTS generation;
@@ -0,0 +1,16 @@
+ALTER TABLE partner_refresh_tokens ADD COLUMN generation INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE admin_refresh_tokens ADD COLUMN generation INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE security_events (
+    id SERIAL PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    owner_type VARCHAR(20) NOT NULL,
+    owner_id INTEGER NOT NULL,
+    ip_address VARCHAR(45),
+    user_agent VARCHAR(255),
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_security_events_event_type ON security_events(event_type);
+CREATE INDEX idx_security_events_owner ON security_events(owner_type, owner_id);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS admin_recovery_codes;
+
+ALTER TABLE admin_roles DROP COLUMN IF EXISTS requires_two_factor;
+
+ALTER TABLE admins
+    DROP COLUMN IF EXISTS totp_last_used_step,
+    DROP COLUMN IF EXISTS totp_secret,
+    DROP COLUMN IF EXISTS two_factor_enabled_at,
+    DROP COLUMN IF EXISTS two_factor_enabled;
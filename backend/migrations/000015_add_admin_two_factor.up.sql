@@ -0,0 +1,17 @@
+ALTER TABLE admins
+    ADD COLUMN two_factor_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN two_factor_enabled_at TIMESTAMP,
+    ADD COLUMN totp_secret VARCHAR(64),
+    ADD COLUMN totp_last_used_step BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE admin_roles ADD COLUMN requires_two_factor BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE admin_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_admin_recovery_codes_admin_id ON admin_recovery_codes(admin_id);
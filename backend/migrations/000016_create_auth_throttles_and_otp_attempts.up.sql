@@ -0,0 +1,11 @@
+CREATE TABLE auth_throttles (
+    throttle_key VARCHAR(255) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_auth_throttles_expires_at ON auth_throttles(expires_at);
+
+ALTER TABLE otps ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
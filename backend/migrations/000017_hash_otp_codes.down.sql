@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_otps_created_at;
+
+DELETE FROM otps;
+
+ALTER TABLE otps DROP COLUMN IF EXISTS code_hash;
+ALTER TABLE otps ADD COLUMN code VARCHAR(6) NOT NULL;
@@ -0,0 +1,8 @@
+-- Outstanding plaintext codes can't be converted, so they are dropped;
+-- affected users simply request a new code.
+DELETE FROM otps;
+
+ALTER TABLE otps DROP COLUMN code;
+ALTER TABLE otps ADD COLUMN code_hash VARCHAR(64) NOT NULL;
+
+CREATE INDEX idx_otps_created_at ON otps(owner_type, owner_id, purpose, created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE partners DROP COLUMN IF EXISTS phone_verified_at;
+ALTER TABLE partners DROP COLUMN IF EXISTS phone_verified;
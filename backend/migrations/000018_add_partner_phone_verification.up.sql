@@ -0,0 +1,2 @@
+ALTER TABLE partners ADD COLUMN phone_verified BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE partners ADD COLUMN phone_verified_at TIMESTAMP;
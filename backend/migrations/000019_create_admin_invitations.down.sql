@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS admin_invitations;
+
+DROP INDEX IF EXISTS idx_admins_status;
+ALTER TABLE admins DROP COLUMN IF EXISTS status;
@@ -0,0 +1,17 @@
+ALTER TABLE admins ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('invited', 'active'));
+
+CREATE INDEX idx_admins_status ON admins(status);
+
+CREATE TABLE admin_invitations (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL UNIQUE REFERENCES admins(id) ON DELETE CASCADE,
+    token_id VARCHAR(64) NOT NULL,
+    invited_by INTEGER NOT NULL REFERENCES admins(id),
+    sent_count INTEGER NOT NULL DEFAULT 1,
+    last_sent_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,9 @@
+CREATE TABLE password_histories (
+    id SERIAL PRIMARY KEY,
+    owner_type VARCHAR(20) NOT NULL,
+    owner_id INTEGER NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_password_histories_owner ON password_histories(owner_type, owner_id, created_at);
@@ -0,0 +1 @@
+ALTER TABLE admins DROP COLUMN IF EXISTS authz_version;
@@ -0,0 +1 @@
+ALTER TABLE admins ADD COLUMN authz_version INTEGER NOT NULL DEFAULT 1;
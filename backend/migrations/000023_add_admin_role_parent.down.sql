@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_admin_roles_parent_id;
+ALTER TABLE admin_roles DROP CONSTRAINT IF EXISTS admin_roles_parent_not_self;
+ALTER TABLE admin_roles DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,3 @@
+ALTER TABLE admin_roles ADD COLUMN parent_id INTEGER REFERENCES admin_roles(id) ON DELETE SET NULL;
+ALTER TABLE admin_roles ADD CONSTRAINT admin_roles_parent_not_self CHECK (parent_id <> id);
+CREATE INDEX idx_admin_roles_parent_id ON admin_roles(parent_id);
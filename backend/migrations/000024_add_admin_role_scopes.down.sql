@@ -0,0 +1,10 @@
+-- Scoped assignments can't be represented without the scope columns
+DELETE FROM admin_user_roles WHERE region_id IS NOT NULL OR city_id IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_admin_user_roles_admin_id;
+DROP INDEX IF EXISTS idx_admin_user_roles_assignment;
+ALTER TABLE admin_user_roles DROP CONSTRAINT IF EXISTS admin_user_roles_single_scope;
+ALTER TABLE admin_user_roles DROP COLUMN IF EXISTS city_id;
+ALTER TABLE admin_user_roles DROP COLUMN IF EXISTS region_id;
+ALTER TABLE admin_user_roles DROP COLUMN IF EXISTS id;
+ALTER TABLE admin_user_roles ADD PRIMARY KEY (admin_id, role_id);
@@ -0,0 +1,10 @@
+-- A role can now be held several times, once per region or city it is limited to
+ALTER TABLE admin_user_roles DROP CONSTRAINT admin_user_roles_pkey;
+ALTER TABLE admin_user_roles ADD COLUMN id SERIAL PRIMARY KEY;
+ALTER TABLE admin_user_roles ADD COLUMN region_id INTEGER REFERENCES regions(id) ON DELETE CASCADE;
+ALTER TABLE admin_user_roles ADD COLUMN city_id INTEGER REFERENCES cities(id) ON DELETE CASCADE;
+ALTER TABLE admin_user_roles ADD CONSTRAINT admin_user_roles_single_scope CHECK (region_id IS NULL OR city_id IS NULL);
+
+CREATE UNIQUE INDEX idx_admin_user_roles_assignment
+    ON admin_user_roles (admin_id, role_id, COALESCE(region_id, 0), COALESCE(city_id, 0));
+CREATE INDEX idx_admin_user_roles_admin_id ON admin_user_roles(admin_id);
@@ -0,0 +1,5 @@
+-- Without the column every entry would read as an allow
+DELETE FROM admin_role_permissions WHERE effect = 'deny';
+
+ALTER TABLE admin_role_permissions DROP CONSTRAINT IF EXISTS admin_role_permissions_effect_check;
+ALTER TABLE admin_role_permissions DROP COLUMN IF EXISTS effect;
@@ -0,0 +1,3 @@
+-- Deny entries override allows, including allows from wildcard permissions
+ALTER TABLE admin_role_permissions ADD COLUMN effect VARCHAR(10) NOT NULL DEFAULT 'allow';
+ALTER TABLE admin_role_permissions ADD CONSTRAINT admin_role_permissions_effect_check CHECK (effect IN ('allow', 'deny'));
@@ -0,0 +1,6 @@
+-- Sealed secrets don't fit the old width; their admins must enroll again
+UPDATE admins
+SET totp_secret = NULL, two_factor_enabled = FALSE, two_factor_enabled_at = NULL, totp_last_used_step = 0
+WHERE LENGTH(totp_secret) > 64;
+
+ALTER TABLE admins ALTER COLUMN totp_secret TYPE VARCHAR(64);
@@ -0,0 +1,2 @@
+-- Sealed secrets are longer than the base32 plaintext they replace
+ALTER TABLE admins ALTER COLUMN totp_secret TYPE VARCHAR(255);
@@ -0,0 +1,9 @@
+CREATE TABLE revoked_sessions (
+    session_id VARCHAR(36) PRIMARY KEY,
+    owner_type VARCHAR(20) NOT NULL,
+    owner_id INTEGER NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_revoked_sessions_expires_at ON revoked_sessions(expires_at);
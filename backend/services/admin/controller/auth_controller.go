@@ -3,8 +3,11 @@ package controller
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/bruteforce"
 	"github.com/jafoor/carhub/libs/middleware"
 	"github.com/jafoor/carhub/libs/utils"
 	"github.com/jafoor/carhub/services/admin/service"
@@ -18,17 +21,65 @@ func NewAuthController(s service.AuthService) *AuthController {
 	return &AuthController{service: s}
 }
 
+// clientInfo captures the caller's device details for session tracking
+func clientInfo(c *fiber.Ctx) service.ClientInfo {
+	return service.ClientInfo{
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}
+}
+
 func (ac *AuthController) Signin(c *fiber.Ctx) error {
 	var input service.SigninInput
 	if err := c.BodyParser(&input); err != nil {
 		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
 	}
 
-	token, err := ac.service.Signin(input)
+	token, err := ac.service.Signin(input, clientInfo(c))
 	if err != nil {
 		switch err.Error() {
 		case "invalid_credentials":
 			return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid credentials", nil)
+		case "too_many_attempts":
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(bruteforce.RetryAfterSeconds(err)))
+			return utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many failed attempts, try again later", nil)
+		case "account_inactive":
+			return utils.ErrorResponse(c, http.StatusForbidden, "Account is inactive", nil)
+		case "failed_to_get_roles":
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve roles", nil)
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Login failed", nil)
+		}
+	}
+
+	if token.TwoFactorRequired {
+		return utils.SuccessResponse(c, "Two-factor authentication required", token)
+	}
+
+	return utils.SuccessResponse(c, "Login successful", token)
+}
+
+// VerifyTwoFactor completes sign-in with the challenge token and a TOTP or recovery code
+func (ac *AuthController) VerifyTwoFactor(c *fiber.Ctx) error {
+	var input service.TwoFactorSigninInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+
+	if input.ChallengeToken == "" || (input.Code == "" && input.RecoveryCode == "") {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "challenge_token and code or recovery_code are required", nil)
+	}
+
+	token, err := ac.service.VerifyTwoFactor(input, clientInfo(c))
+	if err != nil {
+		switch err.Error() {
+		case "invalid_challenge":
+			return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired challenge", nil)
+		case "invalid_two_factor_code":
+			return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid two-factor code", nil)
+		case "too_many_attempts":
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(bruteforce.RetryAfterSeconds(err)))
+			return utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many failed attempts, try again later", nil)
 		case "account_inactive":
 			return utils.ErrorResponse(c, http.StatusForbidden, "Account is inactive", nil)
 		case "failed_to_get_roles":
@@ -55,10 +106,10 @@ func (ac *AuthController) RefreshToken(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, http.StatusBadRequest, "refresh_token is required", nil)
 	}
 
-	token, err := ac.service.RefreshToken(req.RefreshToken)
+	token, err := ac.service.RefreshToken(req.RefreshToken, clientInfo(c))
 	if err != nil {
 		switch err.Error() {
-		case "invalid_refresh_token", "refresh_token_expired":
+		case "invalid_refresh_token", "refresh_token_expired", "refresh_token_reused":
 			return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired refresh token", nil)
 		case "admin_not_found":
 			return utils.ErrorResponse(c, http.StatusNotFound, "Admin not found", nil)
@@ -74,6 +125,20 @@ func (ac *AuthController) RefreshToken(c *fiber.Ctx) error {
 	return utils.SuccessResponse(c, "Token refreshed", token)
 }
 
+// Logout revokes the presented access token and ends its session
+func (ac *AuthController) Logout(c *fiber.Ctx) error {
+	claims, err := middleware.GetAdminClaims(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Admin authentication required", nil)
+	}
+
+	if err := ac.service.Logout(claims); err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "Logout failed", nil)
+	}
+
+	return utils.SuccessResponse(c, "Logged out successfully", nil)
+}
+
 type UpdateProfileRequest struct {
 	FirstName string  `json:"first_name"`
 	LastName  string  `json:"last_name"`
@@ -144,7 +209,7 @@ func (ac *AuthController) UpdatePassword(c *fiber.Ctx) error {
 		case "invalid_current_password":
 			return utils.ErrorResponse(c, http.StatusBadRequest, "Current password is incorrect", nil)
 		case "weak_password":
-			return utils.ErrorResponse(c, http.StatusBadRequest, "Password does not meet requirements", nil)
+			return utils.ErrorResponse(c, http.StatusBadRequest, "Password does not meet requirements", auth.PasswordViolations(err))
 		default:
 			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update password", nil)
 		}
@@ -0,0 +1,69 @@
+// services/admin/controller/impersonation_controller.go
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/middleware"
+	"github.com/jafoor/carhub/libs/utils"
+	"github.com/jafoor/carhub/services/admin/service"
+)
+
+type ImpersonationController struct {
+	service service.ImpersonationService
+}
+
+func NewImpersonationController(s service.ImpersonationService) *ImpersonationController {
+	return &ImpersonationController{service: s}
+}
+
+func (ic *ImpersonationController) ImpersonatePartner(c *fiber.Ctx) error {
+	return ic.impersonate(c, "partnerId", ic.service.ImpersonatePartner)
+}
+
+func (ic *ImpersonationController) ImpersonateAdmin(c *fiber.Ctx) error {
+	return ic.impersonate(c, "adminId", ic.service.ImpersonateAdmin)
+}
+
+type impersonateFunc func(actorID, subjectID uint, input service.ImpersonationInput, client service.ClientInfo) (*service.ImpersonationResponse, error)
+
+func (ic *ImpersonationController) impersonate(c *fiber.Ctx, param string, mint impersonateFunc) error {
+	actorID, err := middleware.GetAdminID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Admin authentication required", nil)
+	}
+
+	subjectID, err := strconv.ParseUint(c.Params(param), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid id", nil)
+	}
+
+	var input service.ImpersonationInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+
+	token, err := mint(actorID, uint(subjectID), input, clientInfo(c))
+	if err != nil {
+		switch err.Error() {
+		case "invalid_reason":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "reason is required and must be at most 255 characters", nil)
+		case "partner_not_found":
+			return utils.ErrorResponse(c, http.StatusNotFound, "Partner not found", nil)
+		case "admin_not_found":
+			return utils.ErrorResponse(c, http.StatusNotFound, "Admin not found", nil)
+		case "admin_not_active":
+			return utils.ErrorResponse(c, http.StatusConflict, "Only active admins can be impersonated", nil)
+		case "cannot_impersonate_self":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "You cannot impersonate yourself", nil)
+		case "cannot_impersonate_super_admin":
+			return utils.ErrorResponse(c, http.StatusForbidden, "Super admins cannot be impersonated", nil)
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start impersonation", nil)
+		}
+	}
+
+	return utils.SuccessResponse(c, "Impersonation token issued", token)
+}
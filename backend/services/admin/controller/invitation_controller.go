@@ -0,0 +1,89 @@
+// services/admin/controller/invitation_controller.go
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/middleware"
+	"github.com/jafoor/carhub/libs/utils"
+	"github.com/jafoor/carhub/services/admin/service"
+)
+
+type InvitationController struct {
+	service service.InvitationService
+}
+
+func NewInvitationController(s service.InvitationService) *InvitationController {
+	return &InvitationController{service: s}
+}
+
+// invitationError maps service errors shared by the invitation endpoints
+func invitationError(c *fiber.Ctx, err error, fallback string) error {
+	switch err.Error() {
+	case "admin_not_found":
+		return utils.ErrorResponse(c, http.StatusNotFound, "Admin not found", nil)
+	case "invitation_not_pending":
+		return utils.ErrorResponse(c, http.StatusConflict, "Admin has no pending invitation", nil)
+	case "invalid_or_expired_invitation":
+		return utils.ErrorResponse(c, http.StatusBadRequest, "Invitation link is invalid or has expired", nil)
+	case "weak_password":
+		return utils.ErrorResponse(c, http.StatusBadRequest, "Password does not meet requirements", auth.PasswordViolations(err))
+	default:
+		return utils.ErrorResponse(c, http.StatusInternalServerError, fallback, nil)
+	}
+}
+
+// Accept sets the invitee's password from the link token (public)
+func (ic *InvitationController) Accept(c *fiber.Ctx) error {
+	var input service.AcceptInvitationInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+	if input.Token == "" || input.Password == "" {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "token and password are required", nil)
+	}
+
+	if err := ic.service.Accept(input); err != nil {
+		return invitationError(c, err, "Failed to accept invitation")
+	}
+
+	return utils.SuccessResponse(c, "Invitation accepted. You can now sign in", nil)
+}
+
+func (ic *InvitationController) Resend(c *fiber.Ctx) error {
+	inviterID, err := middleware.GetAdminID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Admin authentication required", nil)
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "Invalid ID", nil)
+	}
+
+	invitation, err := ic.service.Resend(inviterID, uint(id))
+	if err != nil {
+		if err.Error() == "invitation_delivery_failed" {
+			return utils.ErrorResponse(c, http.StatusBadGateway, "Invitation renewed but the email could not be sent", nil)
+		}
+		return invitationError(c, err, "Failed to resend invitation")
+	}
+
+	return utils.SuccessResponse(c, "Invitation resent successfully", invitation)
+}
+
+func (ic *InvitationController) Revoke(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "Invalid ID", nil)
+	}
+
+	if err := ic.service.Revoke(uint(id)); err != nil {
+		return invitationError(c, err, "Failed to revoke invitation")
+	}
+
+	return utils.SuccessResponse(c, "Invitation revoked successfully", nil)
+}
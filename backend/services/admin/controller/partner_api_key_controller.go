@@ -0,0 +1,62 @@
+// services/admin/controller/partner_api_key_controller.go
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/middleware"
+	"github.com/jafoor/carhub/libs/utils"
+	"github.com/jafoor/carhub/services/admin/service"
+)
+
+type PartnerAPIKeyController struct {
+	service service.PartnerAPIKeyService
+}
+
+func NewPartnerAPIKeyController(s service.PartnerAPIKeyService) *PartnerAPIKeyController {
+	return &PartnerAPIKeyController{service: s}
+}
+
+// ListPartnerAPIKeys returns every key of a partner, revoked and expired included
+func (kc *PartnerAPIKeyController) ListPartnerAPIKeys(c *fiber.Ctx) error {
+	partnerID, err := strconv.ParseUint(c.Params("partnerId"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid partner_id", nil)
+	}
+
+	keys, err := kc.service.ListPartnerAPIKeys(uint(partnerID))
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve API keys", nil)
+	}
+
+	return utils.SuccessResponse(c, "API keys retrieved successfully", keys)
+}
+
+func (kc *PartnerAPIKeyController) RevokePartnerAPIKey(c *fiber.Ctx) error {
+	adminID, err := middleware.GetAdminID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Admin authentication required", nil)
+	}
+
+	partnerID, err := strconv.ParseUint(c.Params("partnerId"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid partner_id", nil)
+	}
+	keyID, err := strconv.ParseUint(c.Params("keyId"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid key_id", nil)
+	}
+
+	if err := kc.service.RevokePartnerAPIKey(adminID, uint(partnerID), uint(keyID), c.IP()); err != nil {
+		switch err.Error() {
+		case "api_key_not_found":
+			return utils.ErrorResponse(c, http.StatusNotFound, "API key not found", nil)
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke API key", nil)
+		}
+	}
+
+	return utils.SuccessResponse(c, "API key revoked successfully", nil)
+}
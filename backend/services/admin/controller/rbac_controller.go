@@ -19,19 +19,23 @@ func NewRBACController(s service.RBACService) *RBACController {
 }
 
 type CreateRoleRequest struct {
-	Name         string  `json:"name"`
-	DisplayName  string  `json:"display_name"`
-	Description  *string `json:"description"`
-	IsDefault    bool    `json:"is_default"`
-	IsSuperAdmin bool    `json:"is_super_admin"`
+	Name              string  `json:"name"`
+	DisplayName       string  `json:"display_name"`
+	Description       *string `json:"description"`
+	IsDefault         bool    `json:"is_default"`
+	IsSuperAdmin      bool    `json:"is_super_admin"`
+	RequiresTwoFactor bool    `json:"requires_two_factor"`
+	ParentID          *uint   `json:"parent_id"`
 }
 
 type UpdateRoleRequest struct {
-	Name         string  `json:"name"`
-	DisplayName  string  `json:"display_name"`
-	Description  *string `json:"description"`
-	IsDefault    bool    `json:"is_default"`
-	IsSuperAdmin bool    `json:"is_super_admin"`
+	Name              string  `json:"name"`
+	DisplayName       string  `json:"display_name"`
+	Description       *string `json:"description"`
+	IsDefault         bool    `json:"is_default"`
+	IsSuperAdmin      bool    `json:"is_super_admin"`
+	RequiresTwoFactor bool    `json:"requires_two_factor"`
+	ParentID          *uint   `json:"parent_id"`
 }
 
 type CreatePermissionRequest struct {
@@ -44,9 +48,12 @@ type UpdatePermissionRequest struct {
 	Description *string `json:"description"`
 }
 
+// AssignRoleToAdminRequest optionally limits the role to one region or city
 type AssignRoleToAdminRequest struct {
-	AdminID uint `json:"admin_id" validate:"required"`
-	RoleID  uint `json:"role_id" validate:"required"`
+	AdminID  uint  `json:"admin_id" validate:"required"`
+	RoleID   uint  `json:"role_id" validate:"required"`
+	RegionID *uint `json:"region_id,omitempty"`
+	CityID   *uint `json:"city_id,omitempty"`
 }
 
 // AssignRoleToAdmin assigns a role to an admin
@@ -60,7 +67,7 @@ func (rc *RBACController) AssignRoleToAdmin(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, http.StatusBadRequest, "admin_id and role_id are required", nil)
 	}
 
-	err := rc.service.AssignRoleToAdmin(req.AdminID, req.RoleID)
+	err := rc.service.AssignRoleToAdmin(req.AdminID, req.RoleID, service.RoleScope{RegionID: req.RegionID, CityID: req.CityID})
 	if err != nil {
 		switch err.Error() {
 		case "admin_not_found":
@@ -69,7 +76,15 @@ func (rc *RBACController) AssignRoleToAdmin(c *fiber.Ctx) error {
 			return utils.ErrorResponse(c, http.StatusNotFound, "Role not found", nil)
 		case "role_already_assigned":
 			return utils.ErrorResponse(c, http.StatusConflict, "Role already assigned to admin", nil)
-		case "failed_to_find_admin", "failed_to_find_role", "failed_to_check_existing_roles":
+		case "invalid_scope":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "Limit a role to either a region or a city, not both", nil)
+		case "super_admin_role_cannot_be_scoped":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "Super admin roles cannot be limited to a region or city", nil)
+		case "region_not_found":
+			return utils.ErrorResponse(c, http.StatusNotFound, "Region not found", nil)
+		case "city_not_found":
+			return utils.ErrorResponse(c, http.StatusNotFound, "City not found", nil)
+		case "failed_to_find_admin", "failed_to_find_role", "failed_to_find_scope", "failed_to_check_existing_roles":
 			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process request", nil)
 		default:
 			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to assign role", nil)
@@ -79,9 +94,11 @@ func (rc *RBACController) AssignRoleToAdmin(c *fiber.Ctx) error {
 	return utils.SuccessResponse(c, "Role assigned successfully", nil)
 }
 
+// AssignPermissionToRoleRequest's Effect is "allow" (the default) or "deny"
 type AssignPermissionToRoleRequest struct {
-	RoleID       uint `json:"role_id" validate:"required"`
-	PermissionID uint `json:"permission_id" validate:"required"`
+	RoleID       uint   `json:"role_id" validate:"required"`
+	PermissionID uint   `json:"permission_id" validate:"required"`
+	Effect       string `json:"effect,omitempty"`
 }
 
 // AssignPermissionToRole assigns a permission to a role
@@ -95,7 +112,7 @@ func (rc *RBACController) AssignPermissionToRole(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, http.StatusBadRequest, "role_id and permission_id are required", nil)
 	}
 
-	err := rc.service.AssignPermissionToRole(req.RoleID, req.PermissionID)
+	err := rc.service.AssignPermissionToRole(req.RoleID, req.PermissionID, req.Effect)
 	if err != nil {
 		switch err.Error() {
 		case "role_not_found":
@@ -104,6 +121,8 @@ func (rc *RBACController) AssignPermissionToRole(c *fiber.Ctx) error {
 			return utils.ErrorResponse(c, http.StatusNotFound, "Permission not found", nil)
 		case "permission_already_assigned":
 			return utils.ErrorResponse(c, http.StatusConflict, "Permission already assigned to role", nil)
+		case "invalid_effect":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "effect must be allow or deny", nil)
 		case "failed_to_find_role", "failed_to_check_permissions", "failed_to_check_existing_permissions":
 			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process request", nil)
 		default:
@@ -114,6 +133,109 @@ func (rc *RBACController) AssignPermissionToRole(c *fiber.Ctx) error {
 	return utils.SuccessResponse(c, "Permission assigned successfully", nil)
 }
 
+// UnassignRoleFromAdmin removes a role from an admin
+func (rc *RBACController) UnassignRoleFromAdmin(c *fiber.Ctx) error {
+	var req AssignRoleToAdminRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+
+	if req.AdminID == 0 || req.RoleID == 0 {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "admin_id and role_id are required", nil)
+	}
+
+	err := rc.service.UnassignRoleFromAdmin(req.AdminID, req.RoleID, service.RoleScope{RegionID: req.RegionID, CityID: req.CityID})
+	if err != nil {
+		switch err.Error() {
+		case "admin_not_found":
+			return utils.ErrorResponse(c, http.StatusNotFound, "Admin not found", nil)
+		case "role_not_found":
+			return utils.ErrorResponse(c, http.StatusNotFound, "Role not found", nil)
+		case "role_not_assigned":
+			return utils.ErrorResponse(c, http.StatusNotFound, "Role is not assigned to admin", nil)
+		case "failed_to_find_admin", "failed_to_find_role":
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process request", nil)
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to unassign role", nil)
+		}
+	}
+
+	return utils.SuccessResponse(c, "Role unassigned successfully", nil)
+}
+
+// UnassignPermissionFromRole removes a permission from a role
+func (rc *RBACController) UnassignPermissionFromRole(c *fiber.Ctx) error {
+	var req AssignPermissionToRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+
+	if req.RoleID == 0 || req.PermissionID == 0 {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "role_id and permission_id are required", nil)
+	}
+
+	err := rc.service.UnassignPermissionFromRole(req.RoleID, req.PermissionID)
+	if err != nil {
+		switch err.Error() {
+		case "role_not_found":
+			return utils.ErrorResponse(c, http.StatusNotFound, "Role not found", nil)
+		case "permission_not_found":
+			return utils.ErrorResponse(c, http.StatusNotFound, "Permission not found", nil)
+		case "permission_not_assigned":
+			return utils.ErrorResponse(c, http.StatusNotFound, "Permission is not assigned to role", nil)
+		case "failed_to_find_role", "failed_to_find_permission":
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process request", nil)
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to unassign permission", nil)
+		}
+	}
+
+	return utils.SuccessResponse(c, "Permission unassigned successfully", nil)
+}
+
+type SetRolePermissionsRequest struct {
+	PermissionIDs     *[]uint `json:"permission_ids"`
+	DenyPermissionIDs []uint  `json:"deny_permission_ids"`
+}
+
+// SetRolePermissions replaces a role's permissions with the given list and
+// returns what was added and removed
+func (rc *RBACController) SetRolePermissions(c *fiber.Ctx) error {
+	roleIDStr := c.Params("roleId")
+	roleID, err := strconv.ParseUint(roleIDStr, 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid role_id", nil)
+	}
+
+	var req SetRolePermissionsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+
+	// An empty list is allowed and clears the role; a missing one is not
+	if req.PermissionIDs == nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "permission_ids is required", nil)
+	}
+
+	diff, err := rc.service.SetRolePermissions(uint(roleID), *req.PermissionIDs, req.DenyPermissionIDs)
+	if err != nil {
+		switch err.Error() {
+		case "role_not_found":
+			return utils.ErrorResponse(c, http.StatusNotFound, "Role not found", nil)
+		case "permission_not_found":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "One or more permissions do not exist", nil)
+		case "conflicting_effects":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "A permission cannot be both allowed and denied", nil)
+		case "failed_to_find_role", "failed_to_check_permissions":
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to process request", nil)
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to set permissions", nil)
+		}
+	}
+
+	return utils.SuccessResponse(c, "Permissions updated successfully", diff)
+}
+
 func (rc *RBACController) ListRoles(c *fiber.Ctx) error {
 	roles, err := rc.service.ListRoles()
 	if err != nil {
@@ -133,11 +255,13 @@ func (rc *RBACController) CreateRole(c *fiber.Ctx) error {
 	}
 
 	input := service.CreateRoleInput{
-		Name:         req.Name,
-		DisplayName:  req.DisplayName,
-		Description:  req.Description,
-		IsDefault:    req.IsDefault,
-		IsSuperAdmin: req.IsSuperAdmin,
+		Name:              req.Name,
+		DisplayName:       req.DisplayName,
+		Description:       req.Description,
+		IsDefault:         req.IsDefault,
+		IsSuperAdmin:      req.IsSuperAdmin,
+		RequiresTwoFactor: req.RequiresTwoFactor,
+		ParentID:          req.ParentID,
 	}
 
 	role, err := rc.service.CreateRole(input)
@@ -147,6 +271,10 @@ func (rc *RBACController) CreateRole(c *fiber.Ctx) error {
 			return utils.ErrorResponse(c, http.StatusConflict, "Role with this name already exists", nil)
 		case "invalid_role_data":
 			return utils.ErrorResponse(c, http.StatusBadRequest, "Invalid role data", nil)
+		case "parent_role_not_found":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "Parent role not found", nil)
+		case "role_cycle":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "Parent role would create an inheritance cycle", nil)
 		default:
 			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create role", nil)
 		}
@@ -192,11 +320,13 @@ func (rc *RBACController) UpdateRole(c *fiber.Ctx) error {
 	}
 
 	input := service.UpdateRoleInput{
-		Name:         req.Name,
-		DisplayName:  req.DisplayName,
-		Description:  req.Description,
-		IsDefault:    req.IsDefault,
-		IsSuperAdmin: req.IsSuperAdmin,
+		Name:              req.Name,
+		DisplayName:       req.DisplayName,
+		Description:       req.Description,
+		IsDefault:         req.IsDefault,
+		IsSuperAdmin:      req.IsSuperAdmin,
+		RequiresTwoFactor: req.RequiresTwoFactor,
+		ParentID:          req.ParentID,
 	}
 
 	role, err := rc.service.UpdateRole(uint(roleID), input)
@@ -208,6 +338,10 @@ func (rc *RBACController) UpdateRole(c *fiber.Ctx) error {
 			return utils.ErrorResponse(c, http.StatusConflict, "Role with this name already exists", nil)
 		case "invalid_role_data":
 			return utils.ErrorResponse(c, http.StatusBadRequest, "Invalid role data", nil)
+		case "parent_role_not_found":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "Parent role not found", nil)
+		case "role_cycle":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "Parent role would create an inheritance cycle", nil)
 		default:
 			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update role", nil)
 		}
@@ -259,7 +393,30 @@ func (rc *RBACController) GetAdminRoles(c *fiber.Ctx) error {
 	return utils.SuccessResponse(c, "Roles retrieved successfully", roles)
 }
 
-// GetRolePermissions retrieves all permissions assigned to a role
+// GetAdminRoleAssignments lists an admin's roles with the region or city each is limited to
+func (rc *RBACController) GetAdminRoleAssignments(c *fiber.Ctx) error {
+	adminIDStr := c.Params("adminId")
+	adminID, err := strconv.ParseUint(adminIDStr, 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid admin_id", nil)
+	}
+
+	assignments, err := rc.service.GetAdminRoleAssignments(uint(adminID))
+	if err != nil {
+		switch err.Error() {
+		case "admin_not_found":
+			return utils.ErrorResponse(c, http.StatusNotFound, "Admin not found", nil)
+		case "failed_to_find_admin":
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve admin", nil)
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve role assignments", nil)
+		}
+	}
+
+	return utils.SuccessResponse(c, "Role assignments retrieved successfully", assignments)
+}
+
+// GetRolePermissions retrieves a role's direct and effective (inherited) permissions
 func (rc *RBACController) GetRolePermissions(c *fiber.Ctx) error {
 	roleIDStr := c.Params("roleId")
 	roleID, err := strconv.ParseUint(roleIDStr, 10, 32)
@@ -397,3 +554,13 @@ func (rc *RBACController) DeletePermission(c *fiber.Ctx) error {
 
 	return utils.SuccessResponse(c, "Permission deleted successfully", nil)
 }
+
+// GetPermissionRegistry returns the declared permissions and the routes that
+// require them, for building the role editor
+func (rc *RBACController) GetPermissionRegistry(c *fiber.Ctx) error {
+	registry, err := rc.service.GetPermissionRegistry()
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve permission registry", nil)
+	}
+	return utils.SuccessResponse(c, "Permission registry retrieved successfully", registry)
+}
@@ -0,0 +1,73 @@
+// services/admin/controller/session_controller.go
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/middleware"
+	"github.com/jafoor/carhub/libs/utils"
+	"github.com/jafoor/carhub/services/admin/service"
+)
+
+type SessionController struct {
+	service service.SessionService
+}
+
+func NewSessionController(s service.SessionService) *SessionController {
+	return &SessionController{service: s}
+}
+
+// ListSessions returns the signed-in admin's devices
+func (sc *SessionController) ListSessions(c *fiber.Ctx) error {
+	claims, err := middleware.GetAdminClaims(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Admin authentication required", nil)
+	}
+
+	sessions, err := sc.service.ListSessions(claims.AdminID, claims.SessionID)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve sessions", nil)
+	}
+
+	return utils.SuccessResponse(c, "Sessions retrieved successfully", sessions)
+}
+
+// RevokeSession signs a single device out
+func (sc *SessionController) RevokeSession(c *fiber.Ctx) error {
+	adminID, err := middleware.GetAdminID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Admin authentication required", nil)
+	}
+
+	sessionID, err := strconv.ParseUint(c.Params("sessionId"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid session_id", nil)
+	}
+
+	if err := sc.service.RevokeSession(adminID, uint(sessionID)); err != nil {
+		switch err.Error() {
+		case "session_not_found":
+			return utils.ErrorResponse(c, http.StatusNotFound, "Session not found", nil)
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke session", nil)
+		}
+	}
+
+	return utils.SuccessResponse(c, "Session revoked successfully", nil)
+}
+
+// RevokeAllSessions signs every device out
+func (sc *SessionController) RevokeAllSessions(c *fiber.Ctx) error {
+	adminID, err := middleware.GetAdminID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Admin authentication required", nil)
+	}
+
+	if err := sc.service.RevokeAllSessions(adminID); err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke sessions", nil)
+	}
+
+	return utils.SuccessResponse(c, "All sessions revoked successfully", nil)
+}
@@ -0,0 +1,138 @@
+// services/admin/controller/two_factor_controller.go
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/middleware"
+	"github.com/jafoor/carhub/libs/utils"
+	"github.com/jafoor/carhub/services/admin/service"
+)
+
+type TwoFactorController struct {
+	service service.TwoFactorService
+}
+
+func NewTwoFactorController(s service.TwoFactorService) *TwoFactorController {
+	return &TwoFactorController{service: s}
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// twoFactorError maps service errors shared by the 2FA endpoints
+func twoFactorError(c *fiber.Ctx, err error, fallback string) error {
+	switch err.Error() {
+	case "admin_not_found":
+		return utils.ErrorResponse(c, http.StatusNotFound, "Admin not found", nil)
+	case "invalid_two_factor_code":
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid two-factor code", nil)
+	case "two_factor_already_enabled":
+		return utils.ErrorResponse(c, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+	case "two_factor_not_enrolled":
+		return utils.ErrorResponse(c, http.StatusBadRequest, "Start enrollment first", nil)
+	case "two_factor_not_enabled":
+		return utils.ErrorResponse(c, http.StatusBadRequest, "Two-factor authentication is not enabled", nil)
+	case "two_factor_required_by_role":
+		return utils.ErrorResponse(c, http.StatusForbidden, "Two-factor authentication is required for your role", nil)
+	default:
+		return utils.ErrorResponse(c, http.StatusInternalServerError, fallback, nil)
+	}
+}
+
+func (tc *TwoFactorController) GetStatus(c *fiber.Ctx) error {
+	adminID, err := middleware.GetAdminID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Admin authentication required", nil)
+	}
+
+	status, err := tc.service.GetStatus(adminID)
+	if err != nil {
+		return twoFactorError(c, err, "Failed to retrieve two-factor status")
+	}
+
+	return utils.SuccessResponse(c, "Two-factor status retrieved successfully", status)
+}
+
+// Enroll starts enrollment and returns the secret and provisioning URI
+func (tc *TwoFactorController) Enroll(c *fiber.Ctx) error {
+	adminID, err := middleware.GetAdminID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Admin authentication required", nil)
+	}
+
+	enrollment, err := tc.service.Enroll(adminID)
+	if err != nil {
+		return twoFactorError(c, err, "Failed to start two-factor enrollment")
+	}
+
+	return utils.SuccessResponse(c, "Scan the QR code and confirm with a code to activate", enrollment)
+}
+
+// Activate confirms enrollment with a code and returns the recovery codes
+func (tc *TwoFactorController) Activate(c *fiber.Ctx) error {
+	adminID, err := middleware.GetAdminID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Admin authentication required", nil)
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+	if req.Code == "" {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "code is required", nil)
+	}
+
+	codes, err := tc.service.Activate(adminID, req.Code)
+	if err != nil {
+		return twoFactorError(c, err, "Failed to activate two-factor authentication")
+	}
+
+	return utils.SuccessResponse(c, "Two-factor authentication enabled. Store the recovery codes safely, they won't be shown again", codes)
+}
+
+func (tc *TwoFactorController) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	adminID, err := middleware.GetAdminID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Admin authentication required", nil)
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+	if req.Code == "" {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "code is required", nil)
+	}
+
+	codes, err := tc.service.RegenerateRecoveryCodes(adminID, req.Code)
+	if err != nil {
+		return twoFactorError(c, err, "Failed to generate recovery codes")
+	}
+
+	return utils.SuccessResponse(c, "Recovery codes regenerated", codes)
+}
+
+func (tc *TwoFactorController) Disable(c *fiber.Ctx) error {
+	adminID, err := middleware.GetAdminID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Admin authentication required", nil)
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+	if req.Code == "" {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "code is required", nil)
+	}
+
+	if err := tc.service.Disable(adminID, req.Code); err != nil {
+		return twoFactorError(c, err, "Failed to disable two-factor authentication")
+	}
+
+	return utils.SuccessResponse(c, "Two-factor authentication disabled", nil)
+}
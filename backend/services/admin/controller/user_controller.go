@@ -1,37 +1,64 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
+	"slices"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/authz"
 	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/middleware"
 	"github.com/jafoor/carhub/libs/models"
+	libRepository "github.com/jafoor/carhub/libs/repository"
 	"github.com/jafoor/carhub/libs/utils"
 	"github.com/jafoor/carhub/services/admin/repository"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/jafoor/carhub/services/admin/service"
 	"gorm.io/gorm"
 )
 
 type AdminUserController struct {
-	repo     repository.AdminRepository
-	roleRepo repository.AdminRoleRepository
+	repo             repository.AdminRepository
+	roleRepo         repository.AdminRoleRepository
+	refreshTokenRepo libRepository.AdminRefreshTokenRepository
+	revocations      auth.RevocationStore
+	invitations      service.InvitationService
 }
 
-func NewAdminUserController() *AdminUserController {
+func NewAdminUserController(revocations auth.RevocationStore, invitations service.InvitationService) *AdminUserController {
 	return &AdminUserController{
-		repo:     repository.NewAdminRepository(),
-		roleRepo: repository.NewAdminRoleRepository(),
+		repo:             repository.NewAdminRepository(),
+		roleRepo:         repository.NewAdminRoleRepository(),
+		refreshTokenRepo: libRepository.NewAdminRefreshTokenRepository(),
+		revocations:      revocations,
+		invitations:      invitations,
 	}
 }
 
-type CreateAdminInput struct {
-	FirstName string  `json:"first_name"`
-	LastName  string  `json:"last_name"`
-	Email     string  `json:"email"`
-	Phone     *string `json:"phone,omitempty"`
-	Password  string  `json:"password"`
-	RoleIDs   []uint  `json:"role_ids"`
+// revokeAdminAccess kills every live token of an admin who can no longer sign in
+func (c *AdminUserController) revokeAdminAccess(adminID uint) error {
+	return c.revocations.RevokeAllTokens(models.OwnerTypeAdmin, adminID)
+}
+
+// granter loads the caller's permissions for mayGrant
+func (c *AdminUserController) granter(ctx *fiber.Ctx) (*authz.PermissionSet, error) {
+	claims, err := middleware.GetAdminClaims(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return authz.Permissions.Get(claims.AdminID, claims.AuthzVersion)
+}
+
+// mayGrant reports whether the caller may give or take away the role. Roles
+// handed out here apply everywhere, so callers limited to regions or cities
+// may change none, and only super admins may change super-admin roles.
+func mayGrant(granter *authz.PermissionSet, role *models.AdminRole) bool {
+	if granter.SuperAdmin {
+		return true
+	}
+	return granter.Scope().Global() && !role.IsSuperAdmin && role.Name != "super_admin"
 }
 
 type UpdateAdminUserInput struct {
@@ -42,74 +69,55 @@ type UpdateAdminUserInput struct {
 	RoleIDs   []uint  `json:"role_ids"`
 }
 
-// CreateAdminUser creates a new admin user
+// CreateAdminUser invites a new admin. The invitee sets their own password
+// through the emailed link, so the creator never knows it.
 func (c *AdminUserController) CreateAdminUser(ctx *fiber.Ctx) error {
-	var input CreateAdminInput
+	inviterID, err := middleware.GetAdminID(ctx)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Admin authentication required", nil)
+	}
+
+	var input service.InviteAdminInput
 	if err := ctx.BodyParser(&input); err != nil {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid input", err)
 	}
 
-	if input.FirstName == "" || input.LastName == "" || input.Email == "" || input.Password == "" || len(input.RoleIDs) == 0 {
+	if input.FirstName == "" || input.LastName == "" || input.Email == "" || len(input.RoleIDs) == 0 {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Missing required fields", nil)
 	}
-	
-	if len(input.Password) < 6 {
-		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Password must be at least 6 characters", nil)
-	}
 
-	// Check if email already exists
-	existing, err := c.repo.FindByEmailUnscoped(input.Email)
+	granter, err := c.granter(ctx)
 	if err != nil {
-		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Database error", err)
-	}
-	if existing != nil {
-		return utils.ErrorResponse(ctx, http.StatusConflict, "Email already exists", nil)
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to check permissions", nil)
 	}
-
-	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to hash password", err)
-	}
-
-	admin := &models.Admin{
-		FirstName:       input.FirstName,
-		LastName:        input.LastName,
-		Email:           input.Email,
-		Phone:           input.Phone,
-		PasswordHash:    string(hashedPassword),
-		PasswordChanged: false, // Initial password
-		IsActive:        true,
-	}
-
-	// Use transaction to create admin and assign role
-	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
-		// Create Admin
-		if err := c.repo.Create(tx, admin); err != nil {
-			return err
+	for _, roleID := range input.RoleIDs {
+		role, err := c.roleRepo.FindByID(roleID)
+		if err != nil {
+			return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to create admin user", nil)
 		}
-
-		// Assign Roles
-		for _, roleID := range input.RoleIDs {
-			// Verify role exists
-			role, err := c.roleRepo.FindByID(roleID)
-			if err != nil || role == nil {
-				return fiber.NewError(http.StatusBadRequest, "Invalid Role ID: "+strconv.Itoa(int(roleID)))
-			}
-
-			if err := c.repo.AssignRoleToAdmin(tx, admin.ID, roleID); err != nil {
-				return err
-			}
+		if role == nil {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid Role ID", nil)
 		}
+		if !mayGrant(granter, role) {
+			return utils.ErrorResponse(ctx, http.StatusForbidden, "You may not grant role "+role.Name, nil)
+		}
+	}
 
-		return nil
-	})
-
+	admin, err := c.invitations.Invite(inviterID, input)
 	if err != nil {
-		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to create admin user", err)
+		switch err.Error() {
+		case "invitation_delivery_failed":
+			return utils.SuccessResponse(ctx, "Admin invited, but the invitation email could not be sent. Resend the invitation", admin)
+		case "email_already_exists":
+			return utils.ErrorResponse(ctx, http.StatusConflict, "Email already exists", nil)
+		case "invalid_role":
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid Role ID", nil)
+		default:
+			return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to create admin user", nil)
+		}
 	}
 
-	return utils.SuccessResponse(ctx, "Admin user created successfully", admin)
+	return utils.SuccessResponse(ctx, "Admin invited successfully", admin)
 }
 
 // ListAdminUsers retrieves admins with pagination, filtering, and search
@@ -120,6 +128,7 @@ func (c *AdminUserController) ListAdminUsers(ctx *fiber.Ctx) error {
 	email := ctx.Query("email", "")
 	phone := ctx.Query("phone", "")
 	isActive := ctx.Query("is_active")
+	status := ctx.Query("status")
 	roleID := ctx.Query("role_id")
 
 	if page < 1 {
@@ -137,6 +146,9 @@ func (c *AdminUserController) ListAdminUsers(ctx *fiber.Ctx) error {
 	if phone != "" {
 		filters["phone"] = phone
 	}
+	if status != "" {
+		filters["status"] = status
+	}
 	if isActive != "" {
 		active, err := strconv.ParseBool(isActive)
 		if err == nil {
@@ -177,7 +189,13 @@ func (c *AdminUserController) UpdateAdminUser(ctx *fiber.Ctx) error {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid input", err)
 	}
 
+	granter, err := c.granter(ctx)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to check permissions", nil)
+	}
+
 	// Transaction not strictly needed for single update, but good for consistency if we add more
+	var deactivated, authzChanged bool
 	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
 		admin, err := c.repo.FindByID(uint(id))
 		if err != nil {
@@ -197,35 +215,80 @@ func (c *AdminUserController) UpdateAdminUser(ctx *fiber.Ctx) error {
 			admin.Phone = input.Phone
 		}
 		if input.IsActive != nil {
+			deactivated = admin.IsActive && !*input.IsActive
+			authzChanged = admin.IsActive != *input.IsActive
 			admin.IsActive = *input.IsActive
 		}
 
+		// A deactivated admin must not be able to refresh back in
+		if deactivated {
+			if err := c.refreshTokenRepo.DeleteByAdminID(tx, admin.ID); err != nil {
+				return err
+			}
+		}
+
 		if len(input.RoleIDs) > 0 {
-			// Clear existing roles
-			if err := c.repo.ClearAdminRoles(tx, admin.ID); err != nil {
+			authzChanged = true
+			// Roles the admin already holds keep their assignments, so
+			// resubmitting a role limited to a region never widens it
+			existing, err := c.repo.GetRoleAssignments(admin.ID)
+			if err != nil {
+				return err
+			}
+			held := make(map[uint]bool, len(existing))
+			for _, assignment := range existing {
+				held[assignment.RoleID] = true
+				if assignment.Role != nil && !slices.Contains(input.RoleIDs, assignment.RoleID) && !mayGrant(granter, assignment.Role) {
+					return fiber.NewError(http.StatusForbidden, "You may not remove role "+assignment.Role.Name)
+				}
+			}
+
+			if err := c.repo.RemoveRolesExcept(tx, admin.ID, input.RoleIDs); err != nil {
 				return err
 			}
-			// Assign new roles
 			for _, roleID := range input.RoleIDs {
 				role, err := c.roleRepo.FindByID(roleID)
 				if err != nil || role == nil {
 					return fiber.NewError(http.StatusBadRequest, "Invalid Role ID: "+strconv.Itoa(int(roleID)))
 				}
+				if held[roleID] {
+					continue
+				}
+				if !mayGrant(granter, role) {
+					return fiber.NewError(http.StatusForbidden, "You may not grant role "+role.Name)
+				}
+				held[roleID] = true
 				if err := c.repo.AssignRoleToAdmin(tx, admin.ID, roleID); err != nil {
 					return err
 				}
 			}
 		}
 
-		return c.repo.Update(tx, admin)
+		if err := c.repo.Update(tx, admin); err != nil {
+			return err
+		}
+
+		// Tokens issued before a role or active-flag change must be re-checked
+		if authzChanged {
+			return c.repo.BumpAuthzVersion(tx, admin.ID)
+		}
+		return nil
 	})
 
 	if err != nil {
-		if err.Error() == "Admin not found" {
-			return utils.ErrorResponse(ctx, http.StatusNotFound, "Admin not found", nil)
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return utils.ErrorResponse(ctx, fiberErr.Code, fiberErr.Message, nil)
 		}
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to update admin", err)
 	}
+	authz.Permissions.Invalidate(uint(id))
+
+	if deactivated {
+		if err := c.revokeAdminAccess(uint(id)); err != nil {
+			return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Admin deactivated but failed to revoke active tokens", nil)
+		}
+	}
 
 	return utils.SuccessResponse(ctx, "Admin updated successfully", nil)
 }
@@ -247,6 +310,10 @@ func (c *AdminUserController) DeleteAdminUser(ctx *fiber.Ctx) error {
 			return fiber.NewError(http.StatusNotFound, "Admin not found")
 		}
 
+		if err := c.refreshTokenRepo.DeleteByAdminID(tx, admin.ID); err != nil {
+			return err
+		}
+
 		return c.repo.Delete(tx, uint(id))
 	})
 
@@ -256,6 +323,11 @@ func (c *AdminUserController) DeleteAdminUser(ctx *fiber.Ctx) error {
 		}
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to delete admin", err)
 	}
+	authz.Permissions.Invalidate(uint(id))
+
+	if err := c.revokeAdminAccess(uint(id)); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Admin deleted but failed to revoke active tokens", nil)
+	}
 
 	return utils.SuccessResponse(ctx, "Admin deleted successfully", nil)
 }
@@ -0,0 +1,55 @@
+// services/admin/repository/admin_invitation_repository.go
+package repository
+
+import (
+	"errors"
+
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/models"
+	"gorm.io/gorm"
+)
+
+type AdminInvitationRepository interface {
+	Create(tx *gorm.DB, invitation *models.AdminInvitation) error
+	Update(tx *gorm.DB, invitation *models.AdminInvitation) error
+	FindByAdminID(adminID uint) (*models.AdminInvitation, error)
+	Accept(tx *gorm.DB, invitation *models.AdminInvitation) (bool, error)
+}
+
+type adminInvitationRepository struct{}
+
+func NewAdminInvitationRepository() AdminInvitationRepository {
+	return &adminInvitationRepository{}
+}
+
+func (r *adminInvitationRepository) Create(tx *gorm.DB, invitation *models.AdminInvitation) error {
+	return tx.Create(invitation).Error
+}
+
+func (r *adminInvitationRepository) Update(tx *gorm.DB, invitation *models.AdminInvitation) error {
+	return tx.Save(invitation).Error
+}
+
+func (r *adminInvitationRepository) FindByAdminID(adminID uint) (*models.AdminInvitation, error) {
+	var invitation models.AdminInvitation
+	err := database.ReadDB.Where("admin_id = ?", adminID).First(&invitation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// Accept marks the invitation accepted only if it is still open for the same
+// token, so two concurrent uses of one link can't both succeed
+func (r *adminInvitationRepository) Accept(tx *gorm.DB, invitation *models.AdminInvitation) (bool, error) {
+	result := tx.Model(&models.AdminInvitation{}).
+		Where("id = ? AND token_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", invitation.ID, invitation.TokenID).
+		Update("accepted_at", invitation.AcceptedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
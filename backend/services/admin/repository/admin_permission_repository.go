@@ -4,6 +4,7 @@ import (
 	"github.com/jafoor/carhub/libs/database"
 	"github.com/jafoor/carhub/libs/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AdminPermissionRepository interface {
@@ -11,9 +12,13 @@ type AdminPermissionRepository interface {
 	FindAll() ([]models.AdminPermission, error)
 	FindByID(id uint) (*models.AdminPermission, error)
 	FindByName(name string) (*models.AdminPermission, error)
-	AssignPermissionToRole(tx *gorm.DB, roleID, permissionID uint) error
+	FindByIDs(ids []uint) ([]models.AdminPermission, error)
+	AssignPermissionToRole(tx *gorm.DB, roleID, permissionID uint, effect string) error
+	RemovePermissionFromRole(tx *gorm.DB, roleID, permissionID uint) (bool, error)
 	GetRolePermissions(roleID uint) ([]models.AdminPermission, error)
-	HasPermission(adminID uint, permissionName string) (bool, error)
+	GetDirectRolePermissions(roleID uint) ([]models.AdminPermission, error)
+	GetRolePermissionsForUpdate(tx *gorm.DB, roleID uint) ([]models.AdminPermission, error)
+	GetEffectivePermissions(adminID uint) ([]PermissionGrant, error)
 	Update(tx *gorm.DB, permission *models.AdminPermission) error
 	Delete(tx *gorm.DB, id uint) error
 }
@@ -58,17 +63,37 @@ func (r *adminPermissionRepository) FindByName(name string) (*models.AdminPermis
 	return &permission, nil
 }
 
-func (r *adminPermissionRepository) AssignPermissionToRole(tx *gorm.DB, roleID, permissionID uint) error {
+func (r *adminPermissionRepository) FindByIDs(ids []uint) ([]models.AdminPermission, error) {
+	var permissions []models.AdminPermission
+	if len(ids) == 0 {
+		return permissions, nil
+	}
+	err := database.ReadDB.Where("id IN ?", ids).Find(&permissions).Error
+	return permissions, err
+}
+
+// AssignPermissionToRole adds the permission to the role as an allow or a deny
+func (r *adminPermissionRepository) AssignPermissionToRole(tx *gorm.DB, roleID, permissionID uint, effect string) error {
 	type RolePermission struct {
 		RoleID       uint `gorm:"primaryKey"`
 		PermissionID uint `gorm:"primaryKey"`
+		Effect       string
 	}
 	return tx.Table("admin_role_permissions").Create(&RolePermission{
 		RoleID:       roleID,
 		PermissionID: permissionID,
+		Effect:       effect,
 	}).Error
 }
 
+// RemovePermissionFromRole reports false when the role didn't have the permission
+func (r *adminPermissionRepository) RemovePermissionFromRole(tx *gorm.DB, roleID, permissionID uint) (bool, error) {
+	result := tx.Table("admin_role_permissions").
+		Where("role_id = ? AND permission_id = ?", roleID, permissionID).
+		Delete(nil)
+	return result.RowsAffected > 0, result.Error
+}
+
 func (r *adminPermissionRepository) Update(tx *gorm.DB, permission *models.AdminPermission) error {
 	return tx.Save(permission).Error
 }
@@ -77,47 +102,85 @@ func (r *adminPermissionRepository) Delete(tx *gorm.DB, id uint) error {
 	return tx.Delete(&models.AdminPermission{}, id).Error
 }
 
+// GetRolePermissions resolves the role's effective permissions: its own plus
+// everything inherited from its ancestors. A permission denied anywhere in
+// the lineage comes back as a deny.
 func (r *adminPermissionRepository) GetRolePermissions(roleID uint) ([]models.AdminPermission, error) {
+	var permissions []models.AdminPermission
+	err := database.ReadDB.Raw(`
+		WITH RECURSIVE role_lineage AS (
+			SELECT id, parent_id FROM admin_roles WHERE id = ?
+			UNION
+			SELECT r.id, r.parent_id FROM admin_roles r JOIN role_lineage l ON r.id = l.parent_id
+		)
+		SELECT admin_permissions.*,
+			CASE WHEN bool_or(admin_role_permissions.effect = 'deny') THEN 'deny' ELSE 'allow' END AS effect
+		FROM admin_permissions
+		JOIN admin_role_permissions ON admin_role_permissions.permission_id = admin_permissions.id
+		WHERE admin_role_permissions.role_id IN (SELECT id FROM role_lineage)
+		GROUP BY admin_permissions.id
+		ORDER BY admin_permissions.name`, roleID).
+		Scan(&permissions).Error
+	return permissions, err
+}
+
+// GetDirectRolePermissions returns only the permissions assigned to the role itself
+func (r *adminPermissionRepository) GetDirectRolePermissions(roleID uint) ([]models.AdminPermission, error) {
 	var permissions []models.AdminPermission
 	err := database.ReadDB.
+		Select("admin_permissions.*, admin_role_permissions.effect").
 		Joins("JOIN admin_role_permissions ON admin_permissions.id = admin_role_permissions.permission_id").
 		Where("admin_role_permissions.role_id = ?", roleID).
+		Order("admin_permissions.name").
 		Find(&permissions).Error
 	return permissions, err
 }
 
-// HasPermission checks if admin has the required permission
-func (r *adminPermissionRepository) HasPermission(adminID uint, permissionName string) (bool, error) {
-	// First, check if admin has super admin role
-	var superAdminCount int64
-	err := database.ReadDB.
-		Table("admins").
-		Joins("JOIN admin_user_roles ON admins.id = admin_user_roles.admin_id").
-		Joins("JOIN admin_roles ON admin_user_roles.role_id = admin_roles.id").
-		Where("admins.id = ? AND admin_roles.is_super_admin = true", adminID).
-		Count(&superAdminCount).Error
-	
-	if err != nil {
-		return false, err
-	}
-	
-	if superAdminCount > 0 {
-		return true, nil // Super admin has all permissions
+// GetRolePermissionsForUpdate locks the role row and reads its direct
+// permissions inside tx, so concurrent syncs of the same role apply one
+// after the other
+func (r *adminPermissionRepository) GetRolePermissionsForUpdate(tx *gorm.DB, roleID uint) ([]models.AdminPermission, error) {
+	var role models.AdminRole
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&role, roleID).Error; err != nil {
+		return nil, err
 	}
 
-	// Normal RBAC check
-	var count int64
-	err = database.ReadDB.
-		Table("admins").
-		Joins("JOIN admin_user_roles ON admins.id = admin_user_roles.admin_id").
-		Joins("JOIN admin_role_permissions ON admin_user_roles.role_id = admin_role_permissions.role_id").
-		Joins("JOIN admin_permissions ON admin_role_permissions.permission_id = admin_permissions.id").
-		Where("admins.id = ? AND admin_permissions.name = ?", adminID, permissionName).
-		Count(&count).Error
+	var permissions []models.AdminPermission
+	err := tx.
+		Select("admin_permissions.*, admin_role_permissions.effect").
+		Joins("JOIN admin_role_permissions ON admin_permissions.id = admin_role_permissions.permission_id").
+		Where("admin_role_permissions.role_id = ?", roleID).
+		Find(&permissions).Error
+	return permissions, err
+}
 
-	if err != nil {
-		return false, err
-	}
+// PermissionGrant is a permission, or wildcard pattern, reaching an admin
+// through one role assignment, carrying that assignment's region or city
+// scope and whether the role allows or denies it
+type PermissionGrant struct {
+	Name     string
+	Effect   string
+	RegionID *uint
+	CityID   *uint
+}
 
-	return count > 0, nil
+// GetEffectivePermissions returns every permission granted through the
+// admin's role assignments and the roles' ancestors. Inherited permissions
+// keep the scope of the assignment they came through.
+func (r *adminPermissionRepository) GetEffectivePermissions(adminID uint) ([]PermissionGrant, error) {
+	var grants []PermissionGrant
+	err := database.ReadDB.Raw(`
+		WITH RECURSIVE assignment_lineage AS (
+			SELECT role_id AS id, region_id, city_id FROM admin_user_roles WHERE admin_id = ?
+			UNION
+			SELECT r.parent_id, l.region_id, l.city_id FROM admin_roles r
+			JOIN assignment_lineage l ON r.id = l.id
+			WHERE r.parent_id IS NOT NULL
+		)
+		SELECT DISTINCT admin_permissions.name, admin_role_permissions.effect, assignment_lineage.region_id, assignment_lineage.city_id
+		FROM assignment_lineage
+		JOIN admin_role_permissions ON admin_role_permissions.role_id = assignment_lineage.id
+		JOIN admin_permissions ON admin_permissions.id = admin_role_permissions.permission_id`, adminID).
+		Scan(&grants).Error
+	return grants, err
 }
@@ -0,0 +1,56 @@
+// services/admin/repository/admin_recovery_code_repository.go
+package repository
+
+import (
+	"time"
+
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/models"
+	"gorm.io/gorm"
+)
+
+type AdminRecoveryCodeRepository interface {
+	ReplaceForAdmin(tx *gorm.DB, adminID uint, codeHashes []string) error
+	Consume(tx *gorm.DB, adminID uint, codeHash string) (bool, error)
+	CountUnused(adminID uint) (int64, error)
+	DeleteByAdminID(tx *gorm.DB, adminID uint) error
+}
+
+type adminRecoveryCodeRepository struct{}
+
+func NewAdminRecoveryCodeRepository() AdminRecoveryCodeRepository {
+	return &adminRecoveryCodeRepository{}
+}
+
+// ReplaceForAdmin drops every existing code and stores the new set
+func (r *adminRecoveryCodeRepository) ReplaceForAdmin(tx *gorm.DB, adminID uint, codeHashes []string) error {
+	if err := r.DeleteByAdminID(tx, adminID); err != nil {
+		return err
+	}
+
+	codes := make([]models.AdminRecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = models.AdminRecoveryCode{AdminID: adminID, CodeHash: hash}
+	}
+	return tx.Create(&codes).Error
+}
+
+// Consume marks an unused code as used; false means no such code was left
+func (r *adminRecoveryCodeRepository) Consume(tx *gorm.DB, adminID uint, codeHash string) (bool, error) {
+	result := tx.Model(&models.AdminRecoveryCode{}).
+		Where("admin_id = ? AND code_hash = ? AND used_at IS NULL", adminID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *adminRecoveryCodeRepository) CountUnused(adminID uint) (int64, error) {
+	var count int64
+	err := database.ReadDB.Model(&models.AdminRecoveryCode{}).
+		Where("admin_id = ? AND used_at IS NULL", adminID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *adminRecoveryCodeRepository) DeleteByAdminID(tx *gorm.DB, adminID uint) error {
+	return tx.Where("admin_id = ?", adminID).Delete(&models.AdminRecoveryCode{}).Error
+}
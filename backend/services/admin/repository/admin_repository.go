@@ -3,6 +3,7 @@ package repository
 
 import (
 	"errors"
+	"time"
 
 	"github.com/jafoor/carhub/libs/database"
 	"github.com/jafoor/carhub/libs/models"
@@ -14,12 +15,23 @@ type AdminRepository interface {
 	FindByEmail(email string) (*models.Admin, error)
 	FindByID(id uint) (*models.Admin, error)
 	Update(tx *gorm.DB, admin *models.Admin) error
+	UpdateColumns(tx *gorm.DB, id uint, columns map[string]interface{}) error
+	UpdateLastLogin(tx *gorm.DB, id uint, at time.Time) error
 	Delete(tx *gorm.DB, id uint) error
 	List(offset, limit int, filter map[string]interface{}, search string) ([]models.Admin, int64, error)
 	GetAdminRoles(adminID uint) ([]models.AdminRole, error)
 	AssignRoleToAdmin(tx *gorm.DB, adminID, roleID uint) error
+	GetRoleAssignments(adminID uint) ([]models.AdminUserRole, error)
+	CreateRoleAssignment(tx *gorm.DB, assignment *models.AdminUserRole) error
+	RemoveRoleFromAdmin(tx *gorm.DB, adminID, roleID uint, regionID, cityID *uint) (bool, error)
+	RemoveRolesExcept(tx *gorm.DB, adminID uint, roleIDs []uint) error
 	ClearAdminRoles(tx *gorm.DB, adminID uint) error
 	FindByEmailUnscoped(email string) (*models.Admin, error)
+	MarkTOTPStepUsed(tx *gorm.DB, adminID uint, step int64) (bool, error)
+	UpgradePasswordHash(tx *gorm.DB, id uint, oldHash, newHash string) error
+	BumpAuthzVersion(tx *gorm.DB, adminID uint) error
+	BumpAuthzVersionForRole(tx *gorm.DB, roleID uint) error
+	BumpAuthzVersionForPermission(tx *gorm.DB, permissionID uint) error
 }
 
 type adminRepository struct{}
@@ -68,8 +80,20 @@ func (r *adminRepository) FindByID(id uint) (*models.Admin, error) {
 	return &admin, nil
 }
 
+// Update saves the admin. AuthzVersion is left alone so a stale copy can't
+// roll it back; it only moves through the BumpAuthzVersion methods.
 func (r *adminRepository) Update(tx *gorm.DB, admin *models.Admin) error {
-	return tx.Save(admin).Error
+	return tx.Omit("AuthzVersion").Save(admin).Error
+}
+
+// UpdateColumns writes only the given columns, so flows that loaded the admin
+// earlier can't overwrite concurrent changes to the rest of the row
+func (r *adminRepository) UpdateColumns(tx *gorm.DB, id uint, columns map[string]interface{}) error {
+	return tx.Model(&models.Admin{}).Where("id = ?", id).Updates(columns).Error
+}
+
+func (r *adminRepository) UpdateLastLogin(tx *gorm.DB, id uint, at time.Time) error {
+	return tx.Model(&models.Admin{}).Where("id = ?", id).Update("last_login_at", at).Error
 }
 
 func (r *adminRepository) Delete(tx *gorm.DB, id uint) error {
@@ -92,6 +116,9 @@ func (r *adminRepository) List(offset, limit int, filter map[string]interface{},
 	if val, ok := filter["is_active"]; ok {
 		query = query.Where("admins.is_active = ?", val)
 	}
+	if val, ok := filter["status"]; ok && val != "" {
+		query = query.Where("admins.status = ?", val)
+	}
 	if val, ok := filter["role_id"]; ok {
 		query = query.Joins("JOIN admin_user_roles ON admins.id = admin_user_roles.admin_id").
 			Where("admin_user_roles.role_id = ?", val)
@@ -119,31 +146,120 @@ func (r *adminRepository) List(offset, limit int, filter map[string]interface{},
 		query = query.Select("admins.*")
 	}
 
-	err := query.Model(&models.Admin{}).Preload("Roles").Order("admins.created_at DESC").Offset(offset).Limit(limit).Find(&admins).Error
+	err := query.Model(&models.Admin{}).Preload("Roles").Preload("Invitation").Order("admins.created_at DESC").Offset(offset).Limit(limit).Find(&admins).Error
 	
 	return admins, total, err
 }
 
+// GetAdminRoles lists each role the admin holds once, however many scopes
+// it is held with. ScopedOnly marks roles with no unscoped assignment.
 func (r *adminRepository) GetAdminRoles(adminID uint) ([]models.AdminRole, error) {
 	var roles []models.AdminRole
 	err := database.ReadDB.
-		Joins("JOIN admin_user_roles ON admin_roles.id = admin_user_roles.role_id").
-		Where("admin_user_roles.admin_id = ?", adminID).
+		Select(`admin_roles.*, NOT EXISTS (
+			SELECT 1 FROM admin_user_roles
+			WHERE admin_user_roles.role_id = admin_roles.id AND admin_user_roles.admin_id = ?
+			AND admin_user_roles.region_id IS NULL AND admin_user_roles.city_id IS NULL
+		) AS scoped_only`, adminID).
+		Where("id IN (?)", database.ReadDB.Table("admin_user_roles").Select("role_id").Where("admin_id = ?", adminID)).
 		Find(&roles).Error
 	return roles, err
 }
 
+// GetRoleAssignments lists the admin's role assignments with their scopes
+func (r *adminRepository) GetRoleAssignments(adminID uint) ([]models.AdminUserRole, error) {
+	var assignments []models.AdminUserRole
+	err := database.ReadDB.Preload("Role").Where("admin_id = ?", adminID).Order("id").Find(&assignments).Error
+	return assignments, err
+}
+
+// AssignRoleToAdmin gives the admin the role everywhere
 func (r *adminRepository) AssignRoleToAdmin(tx *gorm.DB, adminID, roleID uint) error {
-	type AdminUserRole struct {
-		AdminID uint `gorm:"primaryKey"`
-		RoleID  uint `gorm:"primaryKey"`
+	return r.CreateRoleAssignment(tx, &models.AdminUserRole{AdminID: adminID, RoleID: roleID})
+}
+
+func (r *adminRepository) CreateRoleAssignment(tx *gorm.DB, assignment *models.AdminUserRole) error {
+	return tx.Omit("Role").Create(assignment).Error
+}
+
+// RemoveRoleFromAdmin deletes the assignment of the role with exactly the
+// given scope. It reports false when there was no such assignment.
+func (r *adminRepository) RemoveRoleFromAdmin(tx *gorm.DB, adminID, roleID uint, regionID, cityID *uint) (bool, error) {
+	query := tx.Where("admin_id = ? AND role_id = ?", adminID, roleID)
+	if regionID != nil {
+		query = query.Where("region_id = ?", *regionID)
+	} else {
+		query = query.Where("region_id IS NULL")
+	}
+	if cityID != nil {
+		query = query.Where("city_id = ?", *cityID)
+	} else {
+		query = query.Where("city_id IS NULL")
+	}
+
+	result := query.Delete(&models.AdminUserRole{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// RemoveRolesExcept drops every assignment, scoped or not, of roles missing
+// from roleIDs
+func (r *adminRepository) RemoveRolesExcept(tx *gorm.DB, adminID uint, roleIDs []uint) error {
+	query := tx.Where("admin_id = ?", adminID)
+	if len(roleIDs) > 0 {
+		query = query.Where("role_id NOT IN ?", roleIDs)
 	}
-	return tx.Table("admin_user_roles").Create(&AdminUserRole{
-		AdminID: adminID,
-		RoleID:  roleID,
-	}).Error
+	return query.Delete(&models.AdminUserRole{}).Error
 }
 
 func (r *adminRepository) ClearAdminRoles(tx *gorm.DB, adminID uint) error {
 	return tx.Table("admin_user_roles").Where("admin_id = ?", adminID).Delete(nil).Error
 }
+
+// MarkTOTPStepUsed records the time step of an accepted TOTP code. It only
+// moves forward, so false means the code was already used.
+func (r *adminRepository) MarkTOTPStepUsed(tx *gorm.DB, adminID uint, step int64) (bool, error) {
+	result := tx.Model(&models.Admin{}).
+		Where("id = ? AND totp_last_used_step < ?", adminID, step).
+		Update("totp_last_used_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpgradePasswordHash swaps in a rehash of the same password. It only applies
+// while oldHash is still current, so a concurrent password change wins.
+func (r *adminRepository) UpgradePasswordHash(tx *gorm.DB, id uint, oldHash, newHash string) error {
+	return tx.Model(&models.Admin{}).
+		Where("id = ? AND password_hash = ?", id, oldHash).
+		Update("password_hash", newHash).Error
+}
+
+// BumpAuthzVersion makes the admin's outstanding access tokens stale
+func (r *adminRepository) BumpAuthzVersion(tx *gorm.DB, adminID uint) error {
+	return tx.Model(&models.Admin{}).
+		Where("id = ?", adminID).
+		UpdateColumn("authz_version", gorm.Expr("authz_version + 1")).Error
+}
+
+// BumpAuthzVersionForRole makes every holder of the role, or of a role that
+// inherits from it, stale
+func (r *adminRepository) BumpAuthzVersionForRole(tx *gorm.DB, roleID uint) error {
+	return bumpAuthzVersionForRoleTree(tx, "SELECT ?", roleID)
+}
+
+// BumpAuthzVersionForPermission makes every admin holding the permission
+// through any role, directly or inherited, stale
+func (r *adminRepository) BumpAuthzVersionForPermission(tx *gorm.DB, permissionID uint) error {
+	return bumpAuthzVersionForRoleTree(tx, "SELECT role_id FROM admin_role_permissions WHERE permission_id = ?", permissionID)
+}
+
+// bumpAuthzVersionForRoleTree bumps holders of the roles picked by seed and
+// of all their descendants
+func bumpAuthzVersionForRoleTree(tx *gorm.DB, seed string, arg interface{}) error {
+	return tx.Exec(`
+		WITH RECURSIVE role_tree AS (
+			SELECT id FROM admin_roles WHERE id IN (`+seed+`)
+			UNION
+			SELECT r.id FROM admin_roles r JOIN role_tree t ON r.parent_id = t.id
+		)
+		UPDATE admins SET authz_version = authz_version + 1
+		WHERE id IN (SELECT admin_id FROM admin_user_roles WHERE role_id IN (SELECT id FROM role_tree))`, arg).Error
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/jafoor/carhub/libs/database"
 	"github.com/jafoor/carhub/libs/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AdminRoleRepository interface {
@@ -11,6 +12,7 @@ type AdminRoleRepository interface {
 	FindAll() ([]models.AdminRole, error)
 	FindByID(id uint) (*models.AdminRole, error)
 	FindByName(name string) (*models.AdminRole, error)
+	FindByIDForUpdate(tx *gorm.DB, id uint) (*models.AdminRole, error)
 	Update(tx *gorm.DB, role *models.AdminRole) error
 	Delete(tx *gorm.DB, id uint) error
 }
@@ -55,6 +57,20 @@ func (r *adminRoleRepository) FindByName(name string) (*models.AdminRole, error)
 	return &role, nil
 }
 
+// FindByIDForUpdate loads the role inside tx and locks its row until the
+// transaction ends, reading the latest committed version
+func (r *adminRoleRepository) FindByIDForUpdate(tx *gorm.DB, id uint) (*models.AdminRole, error) {
+	var role models.AdminRole
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&role, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
 func (r *adminRoleRepository) Update(tx *gorm.DB, role *models.AdminRole) error {
 	return tx.Save(role).Error
 }
@@ -3,11 +3,16 @@ package routes
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/bruteforce"
 	"github.com/jafoor/carhub/libs/middleware"
+	"github.com/jafoor/carhub/libs/notifier"
 	adminRefreshTokenRepo "github.com/jafoor/carhub/libs/repository"
 	"github.com/jafoor/carhub/services/admin/controller"
 	"github.com/jafoor/carhub/services/admin/repository"
 	"github.com/jafoor/carhub/services/admin/service"
+	partnerRepository "github.com/jafoor/carhub/services/partner/repository"
+	settingsRepository "github.com/jafoor/carhub/services/settings/repository"
 )
 
 func RegisterAdminRoutes(app *fiber.App) {
@@ -18,23 +23,66 @@ func RegisterAdminRoutes(app *fiber.App) {
 	roleRepo := repository.NewAdminRoleRepository()
 	permissionRepo := repository.NewAdminPermissionRepository()
 	refreshTokenRepo := adminRefreshTokenRepo.NewAdminRefreshTokenRepository()
+	otpRepo := adminRefreshTokenRepo.NewOTPRepository()
+	recoveryCodeRepo := repository.NewAdminRecoveryCodeRepository()
+	notify := notifier.NewFromConfig()
+	revocations := auth.NewRevocationStore()
+	attempts := bruteforce.NewStoreFromConfig()
+	passwords := auth.NewPasswordPolicy(adminRefreshTokenRepo.NewPasswordHistoryRepository())
 
 	// Auth endpoints (public)
-	authService := service.NewAuthService(adminRepo, refreshTokenRepo)
+	twoFactorService := service.NewTwoFactorService(adminRepo, recoveryCodeRepo)
+	authService := service.NewAuthService(
+		adminRepo, refreshTokenRepo, revocations, twoFactorService,
+		bruteforce.NewGuard("admin_signin", attempts),
+		bruteforce.NewGuard("admin_2fa", attempts),
+		passwords,
+	)
 	authCtrl := controller.NewAuthController(authService)
 
 	v1.Post("/admin/signin", authCtrl.Signin)
+	v1.Post("/admin/signin/2fa", authCtrl.VerifyTwoFactor)
 	v1.Post("/admin/refresh", authCtrl.RefreshToken)
 
+	// Password reset endpoints (public)
+	passwordService := service.NewPasswordService(adminRepo, otpRepo, refreshTokenRepo, notify, passwords, bruteforce.NewGuard("admin_forgot_password", attempts))
+	passwordCtrl := controller.NewPasswordController(passwordService)
+	v1.Post("/admin/forgot-password", passwordCtrl.ForgotPassword)
+	v1.Post("/admin/reset-password", passwordCtrl.ResetPassword)
+
+	// Invitation acceptance (public, authorized by the link token)
+	invitationService := service.NewInvitationService(adminRepo, roleRepo, repository.NewAdminInvitationRepository(), notify, passwords)
+	invitationCtrl := controller.NewInvitationController(invitationService)
+	v1.Post("/admin/invitations/accept", invitationCtrl.Accept)
+
 	// Protected admin routes group
 	adminGroup := v1.Group("/admin", middleware.RequireAdminAuth())
 
+	// Destructive and security sensitive routes are off limits to impersonation tokens
+	noImpersonation := middleware.BlockImpersonation()
+
+	adminGroup.Post("/logout", authCtrl.Logout)
 	adminGroup.Get("/profile", authCtrl.GetProfile)
 	adminGroup.Put("/profile", authCtrl.UpdateProfile)
-	adminGroup.Put("/profile/password", authCtrl.UpdatePassword)
+	adminGroup.Put("/profile/password", noImpersonation, authCtrl.UpdatePassword)
+
+	// Session management (own sessions)
+	sessionService := service.NewSessionService(refreshTokenRepo, revocations)
+	sessionCtrl := controller.NewSessionController(sessionService)
+	adminGroup.Get("/sessions", sessionCtrl.ListSessions)
+	adminGroup.Delete("/sessions", noImpersonation, sessionCtrl.RevokeAllSessions)
+	adminGroup.Delete("/sessions/:sessionId", noImpersonation, sessionCtrl.RevokeSession)
+
+	// Two-factor authentication (own account)
+	twoFactorCtrl := controller.NewTwoFactorController(twoFactorService)
+	adminGroup.Get("/2fa", twoFactorCtrl.GetStatus)
+	adminGroup.Post("/2fa/enroll", noImpersonation, twoFactorCtrl.Enroll)
+	adminGroup.Post("/2fa/activate", noImpersonation, twoFactorCtrl.Activate)
+	adminGroup.Post("/2fa/recovery-codes", noImpersonation, twoFactorCtrl.RegenerateRecoveryCodes)
+	adminGroup.Delete("/2fa", noImpersonation, twoFactorCtrl.Disable)
 
 	// RBAC endpoints (require super admin)
-	rbacService := service.NewRBACService(adminRepo, roleRepo, permissionRepo)
+	rbacService := service.NewRBACService(adminRepo, roleRepo, permissionRepo, settingsRepository.NewSettingsRepository())
 	rbacCtrl := controller.NewRBACController(rbacService)
 
 	// RBAC management routes (super admin only)
@@ -44,19 +92,38 @@ func RegisterAdminRoutes(app *fiber.App) {
 	adminGroup.Put("/roles/:roleId", middleware.RequireSuperAdmin(), rbacCtrl.UpdateRole)
 	adminGroup.Delete("/roles/:roleId", middleware.RequireSuperAdmin(), rbacCtrl.DeleteRole)
 	adminGroup.Post("/roles/assign", middleware.RequireSuperAdmin(), rbacCtrl.AssignRoleToAdmin)
+	adminGroup.Post("/roles/unassign", middleware.RequireSuperAdmin(), rbacCtrl.UnassignRoleFromAdmin)
 	adminGroup.Post("/permissions/assign", middleware.RequireSuperAdmin(), rbacCtrl.AssignPermissionToRole)
+	adminGroup.Post("/permissions/unassign", middleware.RequireSuperAdmin(), rbacCtrl.UnassignPermissionFromRole)
 	adminGroup.Get("/:adminId/roles", middleware.RequireSuperAdmin(), rbacCtrl.GetAdminRoles)
+	adminGroup.Get("/:adminId/role-assignments", middleware.RequireSuperAdmin(), rbacCtrl.GetAdminRoleAssignments)
 	adminGroup.Get("/roles/:roleId/permissions", middleware.RequireSuperAdmin(), rbacCtrl.GetRolePermissions)
+	adminGroup.Put("/roles/:roleId/permissions", middleware.RequireSuperAdmin(), rbacCtrl.SetRolePermissions)
 	adminGroup.Get("/permissions", middleware.RequireSuperAdmin(), rbacCtrl.ListPermissions)
 	adminGroup.Post("/permissions", middleware.RequireSuperAdmin(), rbacCtrl.CreatePermission)
+	adminGroup.Get("/permissions/registry", middleware.RequireSuperAdmin(), rbacCtrl.GetPermissionRegistry)
 	adminGroup.Get("/permissions/:permissionId", middleware.RequireSuperAdmin(), rbacCtrl.GetPermission)
 	adminGroup.Put("/permissions/:permissionId", middleware.RequireSuperAdmin(), rbacCtrl.UpdatePermission)
 	adminGroup.Delete("/permissions/:permissionId", middleware.RequireSuperAdmin(), rbacCtrl.DeletePermission)
 
 	// User Management (Admin or Super Admin)
-	userCtrl := controller.NewAdminUserController()
-	adminGroup.Post("/users", middleware.RequireRoles("super_admin", "admin"), userCtrl.CreateAdminUser)
+	userCtrl := controller.NewAdminUserController(revocations, invitationService)
+	adminGroup.Post("/users", middleware.RequireRoles("super_admin", "admin"), noImpersonation, userCtrl.CreateAdminUser)
 	adminGroup.Get("/users", middleware.RequireRoles("super_admin", "admin"), userCtrl.ListAdminUsers)
-	adminGroup.Put("/users/:id", middleware.RequireRoles("super_admin", "admin"), userCtrl.UpdateAdminUser)
-	adminGroup.Delete("/users/:id", middleware.RequireRoles("super_admin", "admin"), userCtrl.DeleteAdminUser)
+	adminGroup.Put("/users/:id", middleware.RequireRoles("super_admin", "admin"), noImpersonation, userCtrl.UpdateAdminUser)
+	adminGroup.Delete("/users/:id", middleware.RequireRoles("super_admin", "admin"), noImpersonation, userCtrl.DeleteAdminUser)
+	adminGroup.Post("/users/:id/invitation", middleware.RequireRoles("super_admin", "admin"), noImpersonation, invitationCtrl.Resend)
+	adminGroup.Delete("/users/:id/invitation", middleware.RequireRoles("super_admin", "admin"), noImpersonation, invitationCtrl.Revoke)
+
+	// Impersonation (super admin only, and never from an impersonation token)
+	impersonationService := service.NewImpersonationService(adminRepo, partnerRepository.NewPartnerRepository())
+	impersonationCtrl := controller.NewImpersonationController(impersonationService)
+	adminGroup.Post("/impersonate/partners/:partnerId", middleware.RequireSuperAdmin(), noImpersonation, impersonationCtrl.ImpersonatePartner)
+	adminGroup.Post("/impersonate/admins/:adminId", middleware.RequireSuperAdmin(), noImpersonation, impersonationCtrl.ImpersonateAdmin)
+
+	// Partner API keys (Admin or Super Admin)
+	partnerAPIKeyService := service.NewPartnerAPIKeyService(adminRefreshTokenRepo.NewPartnerAPIKeyRepository())
+	partnerAPIKeyCtrl := controller.NewPartnerAPIKeyController(partnerAPIKeyService)
+	adminGroup.Get("/partners/:partnerId/api-keys", middleware.RequireRoles("super_admin", "admin"), partnerAPIKeyCtrl.ListPartnerAPIKeys)
+	adminGroup.Delete("/partners/:partnerId/api-keys/:keyId", middleware.RequireRoles("super_admin", "admin"), noImpersonation, partnerAPIKeyCtrl.RevokePartnerAPIKey)
 }
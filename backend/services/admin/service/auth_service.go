@@ -5,13 +5,18 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"strconv"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 
+	"github.com/jafoor/carhub/libs/audit"
 	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/authz"
+	"github.com/jafoor/carhub/libs/bruteforce"
 	"github.com/jafoor/carhub/libs/config"
 	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/logger"
 	"github.com/jafoor/carhub/libs/models"
 	adminRefreshTokenRepo "github.com/jafoor/carhub/libs/repository"
 	"github.com/jafoor/carhub/services/admin/repository"
@@ -19,15 +24,37 @@ import (
 )
 
 type SigninInput struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	DeviceName string `json:"device_name"`
 }
 
+// ClientInfo describes the device a session is used from
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// TwoFactorSigninInput completes a sign-in that returned a challenge token.
+// Either Code (from the authenticator app) or RecoveryCode is required.
+type TwoFactorSigninInput struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+	DeviceName     string `json:"device_name"`
+}
+
+// TokenResponse carries the session tokens, or only a challenge token when
+// the admin still has to pass the second factor
 type TokenResponse struct {
-	AccessToken  string   `json:"access_token"`
-	RefreshToken string   `json:"refresh_token"`
-	ExpiresIn    int64    `json:"expires_in"` // seconds
-	Roles        []string `json:"roles"`
+	AccessToken            string   `json:"access_token,omitempty"`
+	RefreshToken           string   `json:"refresh_token,omitempty"`
+	ExpiresIn              int64    `json:"expires_in"` // seconds
+	Roles                  []string `json:"roles,omitempty"`
+	PasswordChangeRequired bool     `json:"password_change_required"`
+	TwoFactorSetupRequired bool     `json:"two_factor_setup_required"`
+	TwoFactorRequired      bool     `json:"two_factor_required,omitempty"`
+	ChallengeToken         string   `json:"challenge_token,omitempty"`
 }
 
 type AdminProfileResponse struct {
@@ -53,8 +80,10 @@ type UpdatePasswordInput struct {
 }
 
 type AuthService interface {
-	Signin(req SigninInput) (*TokenResponse, error)
-	RefreshToken(refreshToken string) (*TokenResponse, error)
+	Signin(req SigninInput, client ClientInfo) (*TokenResponse, error)
+	VerifyTwoFactor(req TwoFactorSigninInput, client ClientInfo) (*TokenResponse, error)
+	RefreshToken(refreshToken string, client ClientInfo) (*TokenResponse, error)
+	Logout(claims *auth.AdminClaims) error
 	GetProfile(adminID uint) (*AdminProfileResponse, error)
 	UpdateProfile(adminID uint, input UpdateProfileInput) (*AdminProfileResponse, error)
 	UpdatePassword(adminID uint, input UpdatePasswordInput) error
@@ -63,15 +92,30 @@ type AuthService interface {
 type authService struct {
 	adminRepo        repository.AdminRepository
 	refreshTokenRepo adminRefreshTokenRepo.AdminRefreshTokenRepository
+	revocations      auth.RevocationStore
+	twoFactorService TwoFactorService
+	signinGuard      *bruteforce.Guard
+	twoFactorGuard   *bruteforce.Guard
+	passwords        auth.PasswordPolicy
 }
 
 func NewAuthService(
 	adminRepo repository.AdminRepository,
 	refreshTokenRepo adminRefreshTokenRepo.AdminRefreshTokenRepository,
+	revocations auth.RevocationStore,
+	twoFactorService TwoFactorService,
+	signinGuard *bruteforce.Guard,
+	twoFactorGuard *bruteforce.Guard,
+	passwords auth.PasswordPolicy,
 ) AuthService {
 	return &authService{
 		adminRepo:        adminRepo,
 		refreshTokenRepo: refreshTokenRepo,
+		revocations:      revocations,
+		twoFactorService: twoFactorService,
+		signinGuard:      signinGuard,
+		twoFactorGuard:   twoFactorGuard,
+		passwords:        passwords,
 	}
 }
 
@@ -113,16 +157,23 @@ func (s *authService) UpdateProfile(adminID uint, input UpdateProfileInput) (*Ad
 	}
 
 	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		columns := map[string]interface{}{}
 		if input.FirstName != "" {
 			admin.FirstName = input.FirstName
+			columns["first_name"] = admin.FirstName
 		}
 		if input.LastName != "" {
 			admin.LastName = input.LastName
+			columns["last_name"] = admin.LastName
 		}
 		if input.Phone != nil {
 			admin.Phone = input.Phone
+			columns["phone"] = admin.Phone
+		}
+		if len(columns) == 0 {
+			return nil
 		}
-		return s.adminRepo.Update(tx, admin)
+		return s.adminRepo.UpdateColumns(tx, admin.ID, columns)
 	})
 	if err != nil {
 		return nil, errors.New("failed_to_update_profile")
@@ -140,41 +191,51 @@ func (s *authService) UpdatePassword(adminID uint, input UpdatePasswordInput) er
 		return errors.New("admin_not_found")
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(input.CurrentPassword)); err != nil {
+	if ok, _ := auth.VerifyPassword(admin.PasswordHash, input.CurrentPassword); !ok {
 		return errors.New("invalid_current_password")
 	}
 
-	if len(input.NewPassword) < 8 {
-		return errors.New("weak_password")
-	}
-	if input.NewPassword == input.CurrentPassword {
-		return errors.New("weak_password")
+	subject := adminPasswordSubject(admin)
+	if err := s.passwords.Validate(input.NewPassword, subject); err != nil {
+		return err
 	}
 
 	now := time.Now()
-	newHash, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	newHash, err := auth.HashPassword(input.NewPassword)
 	if err != nil {
 		return errors.New("update_password_failed")
 	}
 
 	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
-		admin.PasswordHash = string(newHash)
+		admin.PasswordHash = newHash
 		admin.PasswordChanged = true
 		admin.LastPasswordChange = &now
 
+		if err := s.passwords.Record(tx, subject, admin.PasswordHash); err != nil {
+			return err
+		}
+
 		if err := s.refreshTokenRepo.DeleteByAdminID(tx, admin.ID); err != nil {
 			return err
 		}
 
-		if err := s.adminRepo.Update(tx, admin); err != nil {
+		err := s.adminRepo.UpdateColumns(tx, admin.ID, map[string]interface{}{
+			"password_hash":        admin.PasswordHash,
+			"password_changed":     true,
+			"last_password_change": now,
+		})
+		if err != nil {
 			return err
 		}
 
-		return nil
+		// The caller's access token still says a password change is required;
+		// a new version makes reauthorize refresh it on the next request
+		return s.adminRepo.BumpAuthzVersion(tx, admin.ID)
 	})
 	if err != nil {
 		return errors.New("update_password_failed")
 	}
+	authz.Permissions.Invalidate(admin.ID)
 
 	return nil
 }
@@ -185,11 +246,25 @@ func hashToken(token string) (string, error) {
 	return hex.EncodeToString(hash[:]), nil
 }
 
+// truncate keeps client supplied values within their column sizes
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
+}
+
 // Signin handles admin login
-func (s *authService) Signin(req SigninInput) (*TokenResponse, error) {
+func (s *authService) Signin(req SigninInput, client ClientInfo) (*TokenResponse, error) {
+	// Refuse locked-out accounts and IPs before touching the password
+	if err := s.signinGuard.Check(req.Email, client.IPAddress); err != nil {
+		return nil, err
+	}
+
 	// Find admin by email
 	admin, err := s.adminRepo.FindByEmail(req.Email)
 	if err != nil || admin == nil {
+		s.signinGuard.Fail(req.Email, client.IPAddress)
 		return nil, errors.New("invalid_credentials")
 	}
 
@@ -198,11 +273,89 @@ func (s *authService) Signin(req SigninInput) (*TokenResponse, error) {
 		return nil, errors.New("account_inactive")
 	}
 
+	// Invited admins have no password until they accept the invitation
+	if admin.Status == models.AdminStatusInvited {
+		s.signinGuard.Fail(req.Email, client.IPAddress)
+		return nil, errors.New("invalid_credentials")
+	}
+
 	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(req.Password)); err != nil {
+	ok, needsRehash := auth.VerifyPassword(admin.PasswordHash, req.Password)
+	if !ok {
+		s.signinGuard.Fail(req.Email, client.IPAddress)
 		return nil, errors.New("invalid_credentials")
 	}
+	s.signinGuard.Succeed(req.Email)
+
+	// The password is proven here even when a second factor follows
+	if needsRehash {
+		s.upgradePasswordHash(admin, req.Password)
+	}
+
+	// With 2FA on, the password only earns a challenge for the second step
+	if admin.TwoFactorEnabled {
+		challenge, err := auth.GenerateAdminChallengeToken(admin)
+		if err != nil {
+			return nil, errors.New("login_failed")
+		}
+		return &TokenResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+			ExpiresIn:         config.App.AdminTwoFactorChallengeTTL * 60,
+		}, nil
+	}
+
+	return s.startSession(admin, req.DeviceName, client)
+}
+
+// VerifyTwoFactor finishes a two-step sign-in with a TOTP or recovery code
+func (s *authService) VerifyTwoFactor(req TwoFactorSigninInput, client ClientInfo) (*TokenResponse, error) {
+	claims, err := auth.VerifyAdminToken(req.ChallengeToken)
+	if err != nil || claims.TokenType != "2fa_challenge" {
+		return nil, errors.New("invalid_challenge")
+	}
+
+	// A challenge is good for one successful sign-in only
+	revoked, err := s.revocations.IsRevoked(models.OwnerTypeAdmin, claims.AdminID, claims.ID, "", claims.IssuedAt.Time)
+	if err != nil {
+		return nil, errors.New("login_failed")
+	}
+	if revoked {
+		return nil, errors.New("invalid_challenge")
+	}
+
+	admin, err := s.adminRepo.FindByID(claims.AdminID)
+	if err != nil || admin == nil {
+		return nil, errors.New("invalid_challenge")
+	}
+	if !admin.IsActive {
+		return nil, errors.New("account_inactive")
+	}
+
+	// A stolen password still leaves only a handful of guesses per challenge window
+	account := strconv.FormatUint(uint64(admin.ID), 10)
+	if err := s.twoFactorGuard.Check(account, client.IPAddress); err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorService.VerifySecondFactor(admin, req.Code, req.RecoveryCode); err != nil {
+		if err.Error() == "two_factor_verification_failed" {
+			return nil, errors.New("login_failed")
+		}
+		s.twoFactorGuard.Fail(account, client.IPAddress)
+		return nil, errors.New("invalid_two_factor_code")
+	}
+	s.twoFactorGuard.Succeed(account)
+
+	if err := s.revocations.RevokeToken(models.OwnerTypeAdmin, admin.ID, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return nil, errors.New("login_failed")
+	}
+
+	return s.startSession(admin, req.DeviceName, client)
+}
 
+// startSession opens a new device session once every sign-in step has passed
+func (s *authService) startSession(admin *models.Admin, deviceName string, client ClientInfo) (*TokenResponse, error) {
 	// Get admin roles
 	roles, err := s.adminRepo.GetAdminRoles(admin.ID)
 	if err != nil {
@@ -211,21 +364,19 @@ func (s *authService) Signin(req SigninInput) (*TokenResponse, error) {
 
 	var resp *TokenResponse
 
+	// Every sign-in opens a new session; other devices stay signed in
+	sessionID := uuid.NewString()
+
 	// Execute in transaction
 	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
-		// Delete any existing refresh token first
-		if err := s.refreshTokenRepo.DeleteByAdminID(tx, admin.ID); err != nil {
-			return err
-		}
-
 		// Generate new access token
-		accessToken, err := auth.GenerateAdminAccessToken(admin, roles)
+		accessToken, err := auth.GenerateAdminAccessToken(admin, roles, sessionID)
 		if err != nil {
 			return err
 		}
 
 		// Generate new refresh token
-		refreshToken, err := auth.GenerateAdminRefreshToken(admin, roles)
+		refreshToken, err := auth.GenerateAdminRefreshToken(admin, roles, sessionID, 0)
 		if err != nil {
 			return err
 		}
@@ -236,34 +387,38 @@ func (s *authService) Signin(req SigninInput) (*TokenResponse, error) {
 			return err
 		}
 
-		// Create new refresh token record
+		// Create new session record
 		rt := &models.AdminRefreshToken{
-			AdminID:   admin.ID,
-			TokenHash: refreshHash,
-			ExpiresAt: time.Now().Add(time.Hour * 24 * time.Duration(config.App.AdminRefreshTokenTTL)),
+			AdminID:    admin.ID,
+			SessionID:  sessionID,
+			TokenHash:  refreshHash,
+			DeviceName: truncate(deviceName, 100),
+			IPAddress:  truncate(client.IPAddress, 45),
+			UserAgent:  truncate(client.UserAgent, 255),
+			LastUsedAt: time.Now(),
+			ExpiresAt:  time.Now().Add(time.Hour * 24 * time.Duration(config.App.AdminRefreshTokenTTL)),
 		}
 		if err := s.refreshTokenRepo.Create(tx, rt); err != nil {
 			return err
 		}
 
-		// Extract role names for response
-		roleNames := make([]string, len(roles))
-		for i, role := range roles {
-			roleNames[i] = role.Name
-		}
+		// Same names as the token claims: region-scoped roles aren't listed
+		roleNames := auth.AdminRoleNames(roles)
 
 		// Update last login time
 		now := time.Now()
 		admin.LastLoginAt = &now
-		if err := s.adminRepo.Update(tx, admin); err != nil {
+		if err := s.adminRepo.UpdateLastLogin(tx, admin.ID, now); err != nil {
 			return err
 		}
 
 		resp = &TokenResponse{
-			AccessToken:  accessToken,
-			RefreshToken: refreshToken,
-			ExpiresIn:    config.App.AdminAccessTokenTTL * 60, // convert minutes to seconds
-			Roles:        roleNames,
+			AccessToken:            accessToken,
+			RefreshToken:           refreshToken,
+			ExpiresIn:              config.App.AdminAccessTokenTTL * 60, // convert minutes to seconds
+			Roles:                  roleNames,
+			PasswordChangeRequired: !admin.PasswordChanged,
+			TwoFactorSetupRequired: !admin.TwoFactorEnabled && auth.RolesRequireTwoFactor(roles),
 		}
 		return nil
 	})
@@ -275,11 +430,33 @@ func (s *authService) Signin(req SigninInput) (*TokenResponse, error) {
 	return resp, nil
 }
 
+var errRefreshTokenReused = errors.New("refresh_token_reused")
+
+// revokeFamily ends a session whose refresh token was replayed and records it
+func (s *authService) revokeFamily(stored *models.AdminRefreshToken, client ClientInfo, presentedGeneration int) {
+	if err := s.refreshTokenRepo.DeleteBySessionID(database.WriteDB, stored.SessionID); err != nil {
+		logger.Error().Err(err).Str("session_id", stored.SessionID).Msg("Failed to revoke refresh token family")
+	}
+
+	audit.LogSecurityEvent(audit.SecurityEvent{
+		EventType: models.SecurityEventRefreshTokenReuse,
+		OwnerType: models.OwnerTypeAdmin,
+		OwnerID:   stored.AdminID,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Details: map[string]interface{}{
+			"session_id":           stored.SessionID,
+			"presented_generation": presentedGeneration,
+			"current_generation":   stored.Generation,
+		},
+	})
+}
+
 // RefreshToken handles token rotation
-func (s *authService) RefreshToken(refreshToken string) (*TokenResponse, error) {
+func (s *authService) RefreshToken(refreshToken string, client ClientInfo) (*TokenResponse, error) {
 	// Verify and parse refresh token
 	claims, err := auth.VerifyAdminToken(refreshToken)
-	if err != nil {
+	if err != nil || claims.TokenType != "refresh" || claims.SessionID == "" {
 		return nil, errors.New("invalid_refresh_token")
 	}
 
@@ -289,12 +466,19 @@ func (s *authService) RefreshToken(refreshToken string) (*TokenResponse, error)
 		return nil, errors.New("invalid_refresh_token")
 	}
 
-	// Find stored refresh token by admin ID
-	stored, err := s.refreshTokenRepo.FindByAdminID(claims.AdminID)
-	if err != nil || stored == nil {
+	// Find the session this token belongs to
+	stored, err := s.refreshTokenRepo.FindBySessionID(claims.SessionID)
+	if err != nil || stored == nil || stored.AdminID != claims.AdminID {
 		return nil, errors.New("invalid_refresh_token")
 	}
 
+	// A token from an older generation has already been rotated, so whoever
+	// presents it is replaying a copy: revoke the whole family.
+	if claims.Generation < stored.Generation {
+		s.revokeFamily(stored, client, claims.Generation)
+		return nil, errors.New("refresh_token_reused")
+	}
+
 	// Verify hash matches
 	if refreshHash != stored.TokenHash {
 		return nil, errors.New("invalid_refresh_token")
@@ -323,20 +507,16 @@ func (s *authService) RefreshToken(refreshToken string) (*TokenResponse, error)
 	}
 
 	var resp *TokenResponse
+	previousGeneration := stored.Generation
 
 	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
-		// Delete old refresh token
-		if err := s.refreshTokenRepo.DeleteByAdminID(tx, admin.ID); err != nil {
-			return err
-		}
-
-		// Generate new tokens
-		newAccessToken, err := auth.GenerateAdminAccessToken(admin, roles)
+		// Generate new tokens for the same session
+		newAccessToken, err := auth.GenerateAdminAccessToken(admin, roles, stored.SessionID)
 		if err != nil {
 			return err
 		}
 
-		newRefreshToken, err := auth.GenerateAdminRefreshToken(admin, roles)
+		newRefreshToken, err := auth.GenerateAdminRefreshToken(admin, roles, stored.SessionID, previousGeneration+1)
 		if err != nil {
 			return err
 		}
@@ -347,34 +527,78 @@ func (s *authService) RefreshToken(refreshToken string) (*TokenResponse, error)
 			return err
 		}
 
-		// Save new refresh token
-		newRT := &models.AdminRefreshToken{
-			AdminID:   admin.ID,
-			TokenHash: newRefreshHash,
-			ExpiresAt: time.Now().Add(time.Hour * 24 * time.Duration(config.App.AdminRefreshTokenTTL)),
-		}
-		if err := s.refreshTokenRepo.Create(tx, newRT); err != nil {
+		// Rotate only this session's token
+		stored.TokenHash = newRefreshHash
+		stored.Generation = previousGeneration + 1
+		stored.IPAddress = truncate(client.IPAddress, 45)
+		stored.UserAgent = truncate(client.UserAgent, 255)
+		stored.LastUsedAt = time.Now()
+		stored.ExpiresAt = time.Now().Add(time.Hour * 24 * time.Duration(config.App.AdminRefreshTokenTTL))
+		rotated, err := s.refreshTokenRepo.Rotate(tx, stored, previousGeneration)
+		if err != nil {
 			return err
 		}
-
-		// Extract role names for response
-		roleNames := make([]string, len(roles))
-		for i, role := range roles {
-			roleNames[i] = role.Name
+		if !rotated {
+			return errRefreshTokenReused
 		}
 
+		// Same names as the token claims: region-scoped roles aren't listed
+		roleNames := auth.AdminRoleNames(roles)
+
 		resp = &TokenResponse{
-			AccessToken:  newAccessToken,
-			RefreshToken: newRefreshToken,
-			ExpiresIn:    config.App.AdminAccessTokenTTL * 60,
-			Roles:        roleNames,
+			AccessToken:            newAccessToken,
+			RefreshToken:           newRefreshToken,
+			ExpiresIn:              config.App.AdminAccessTokenTTL * 60,
+			Roles:                  roleNames,
+			PasswordChangeRequired: !admin.PasswordChanged,
+			TwoFactorSetupRequired: !admin.TwoFactorEnabled && auth.RolesRequireTwoFactor(roles),
 		}
 		return nil
 	})
 
+	if errors.Is(err, errRefreshTokenReused) {
+		// The same token was rotated concurrently, so it was used twice
+		s.revokeFamily(stored, client, claims.Generation)
+		return nil, errRefreshTokenReused
+	}
 	if err != nil {
 		return nil, errors.New("token_refresh_failed")
 	}
 
 	return resp, nil
 }
+
+// Logout ends the caller's session: the access token is refused from now on
+// and the session's refresh token is deleted
+func (s *authService) Logout(claims *auth.AdminClaims) error {
+	if err := s.revocations.RevokeToken(models.OwnerTypeAdmin, claims.AdminID, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return errors.New("logout_failed")
+	}
+
+	if claims.SessionID == "" {
+		return nil
+	}
+	err := database.ExecuteTransaction(func(tx *gorm.DB) error {
+		return s.refreshTokenRepo.DeleteBySessionID(tx, claims.SessionID)
+	})
+	if err != nil {
+		return errors.New("logout_failed")
+	}
+	return nil
+}
+
+// upgradePasswordHash rehashes a legacy or under-cost hash with the current
+// argon2id settings. Failures are logged only; the sign-in already succeeded.
+func (s *authService) upgradePasswordHash(admin *models.Admin, password string) {
+	newHash, err := auth.HashPassword(password)
+	if err == nil {
+		err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+			return s.adminRepo.UpgradePasswordHash(tx, admin.ID, admin.PasswordHash, newHash)
+		})
+	}
+	if err != nil {
+		logger.Error().Err(err).Uint("admin_id", admin.ID).Msg("Failed to upgrade password hash")
+		return
+	}
+	admin.PasswordHash = newHash
+}
@@ -0,0 +1,152 @@
+// services/admin/service/impersonation_service.go
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jafoor/carhub/libs/audit"
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/config"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/services/admin/repository"
+	partnerRepository "github.com/jafoor/carhub/services/partner/repository"
+)
+
+type ImpersonationInput struct {
+	Reason string `json:"reason"` // kept in the audit trail
+}
+
+// ImpersonationResponse is an access token for the subject. It is marked
+// with token_type "impersonation" and an act claim, and can't be refreshed.
+type ImpersonationResponse struct {
+	AccessToken string           `json:"access_token"`
+	TokenType   string           `json:"token_type"`
+	ExpiresIn   int64            `json:"expires_in"` // seconds
+	SubjectType models.OwnerType `json:"subject_type"`
+	SubjectID   uint             `json:"subject_id"`
+}
+
+type ImpersonationService interface {
+	ImpersonatePartner(actorID, partnerID uint, input ImpersonationInput, client ClientInfo) (*ImpersonationResponse, error)
+	ImpersonateAdmin(actorID, adminID uint, input ImpersonationInput, client ClientInfo) (*ImpersonationResponse, error)
+}
+
+type impersonationService struct {
+	adminRepo   repository.AdminRepository
+	partnerRepo partnerRepository.PartnerRepository
+}
+
+func NewImpersonationService(adminRepo repository.AdminRepository, partnerRepo partnerRepository.PartnerRepository) ImpersonationService {
+	return &impersonationService{adminRepo: adminRepo, partnerRepo: partnerRepo}
+}
+
+func (s *impersonationService) ImpersonatePartner(actorID, partnerID uint, input ImpersonationInput, client ClientInfo) (*ImpersonationResponse, error) {
+	actor, reason, err := s.prepare(actorID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	partner, err := s.partnerRepo.FindByID(partnerID)
+	if err != nil {
+		return nil, errors.New("database_error")
+	}
+	if partner == nil {
+		return nil, errors.New("partner_not_found")
+	}
+
+	token, claims, err := auth.GeneratePartnerImpersonationToken(partner, actor)
+	if err != nil {
+		return nil, errors.New("failed_to_impersonate")
+	}
+
+	logImpersonationStarted(models.OwnerTypePartner, partner.ID, claims.Act, claims.ID, reason, client)
+	return &ImpersonationResponse{
+		AccessToken: token,
+		TokenType:   claims.TokenType,
+		ExpiresIn:   config.App.ImpersonationTTL * 60,
+		SubjectType: models.OwnerTypePartner,
+		SubjectID:   partner.ID,
+	}, nil
+}
+
+// ImpersonateAdmin only allows lower-privileged targets: active admins who
+// are not super admins, and never the actor themselves
+func (s *impersonationService) ImpersonateAdmin(actorID, adminID uint, input ImpersonationInput, client ClientInfo) (*ImpersonationResponse, error) {
+	actor, reason, err := s.prepare(actorID, input)
+	if err != nil {
+		return nil, err
+	}
+	if adminID == actorID {
+		return nil, errors.New("cannot_impersonate_self")
+	}
+
+	target, err := s.adminRepo.FindByID(adminID)
+	if err != nil {
+		return nil, errors.New("database_error")
+	}
+	if target == nil {
+		return nil, errors.New("admin_not_found")
+	}
+	if !target.IsActive || target.Status != models.AdminStatusActive {
+		return nil, errors.New("admin_not_active")
+	}
+
+	roles, err := s.adminRepo.GetAdminRoles(target.ID)
+	if err != nil {
+		return nil, errors.New("database_error")
+	}
+	for _, role := range roles {
+		if role.IsSuperAdmin || role.Name == "super_admin" {
+			return nil, errors.New("cannot_impersonate_super_admin")
+		}
+	}
+
+	token, claims, err := auth.GenerateAdminImpersonationToken(target, roles, actor)
+	if err != nil {
+		return nil, errors.New("failed_to_impersonate")
+	}
+
+	logImpersonationStarted(models.OwnerTypeAdmin, target.ID, claims.Act, claims.ID, reason, client)
+	return &ImpersonationResponse{
+		AccessToken: token,
+		TokenType:   claims.TokenType,
+		ExpiresIn:   config.App.ImpersonationTTL * 60,
+		SubjectType: models.OwnerTypeAdmin,
+		SubjectID:   target.ID,
+	}, nil
+}
+
+// prepare loads the acting super admin and checks the reason
+func (s *impersonationService) prepare(actorID uint, input ImpersonationInput) (*models.Admin, string, error) {
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" || len(reason) > 255 {
+		return nil, "", errors.New("invalid_reason")
+	}
+
+	actor, err := s.adminRepo.FindByID(actorID)
+	if err != nil {
+		return nil, "", errors.New("database_error")
+	}
+	if actor == nil || !actor.IsActive {
+		return nil, "", errors.New("admin_not_found")
+	}
+
+	return actor, reason, nil
+}
+
+func logImpersonationStarted(ownerType models.OwnerType, ownerID uint, actor *auth.Actor, jti, reason string, client ClientInfo) {
+	audit.LogSecurityEvent(audit.SecurityEvent{
+		EventType: models.SecurityEventImpersonationStarted,
+		OwnerType: ownerType,
+		OwnerID:   ownerID,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Details: map[string]interface{}{
+			"actor_admin_id": actor.AdminID,
+			"actor_email":    actor.Email,
+			"token_id":       jti,
+			"reason":         reason,
+		},
+	})
+}
@@ -0,0 +1,307 @@
+// services/admin/service/invitation_service.go
+package service
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/config"
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/logger"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/libs/notifier"
+	"github.com/jafoor/carhub/services/admin/repository"
+	"gorm.io/gorm"
+)
+
+type InviteAdminInput struct {
+	FirstName string  `json:"first_name"`
+	LastName  string  `json:"last_name"`
+	Email     string  `json:"email"`
+	Phone     *string `json:"phone,omitempty"`
+	RoleIDs   []uint  `json:"role_ids"`
+}
+
+type AcceptInvitationInput struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type InvitationService interface {
+	Invite(inviterID uint, input InviteAdminInput) (*models.Admin, error)
+	Resend(inviterID, adminID uint) (*models.AdminInvitation, error)
+	Revoke(adminID uint) error
+	Accept(input AcceptInvitationInput) error
+}
+
+type invitationService struct {
+	adminRepo      repository.AdminRepository
+	roleRepo       repository.AdminRoleRepository
+	invitationRepo repository.AdminInvitationRepository
+	notifier       notifier.Notifier
+	passwords      auth.PasswordPolicy
+}
+
+func NewInvitationService(
+	adminRepo repository.AdminRepository,
+	roleRepo repository.AdminRoleRepository,
+	invitationRepo repository.AdminInvitationRepository,
+	notify notifier.Notifier,
+	passwords auth.PasswordPolicy,
+) InvitationService {
+	return &invitationService{
+		adminRepo:      adminRepo,
+		roleRepo:       roleRepo,
+		invitationRepo: invitationRepo,
+		notifier:       notify,
+		passwords:      passwords,
+	}
+}
+
+// Invite creates the admin in the invited state, without a usable password,
+// and emails a single-use link to set one. When only the email fails the
+// admin is returned together with invitation_delivery_failed so the caller
+// can offer a resend.
+func (s *invitationService) Invite(inviterID uint, input InviteAdminInput) (*models.Admin, error) {
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+
+	existing, err := s.adminRepo.FindByEmailUnscoped(email)
+	if err != nil {
+		return nil, errors.New("database_error")
+	}
+	if existing != nil {
+		return nil, errors.New("email_already_exists")
+	}
+
+	for _, roleID := range input.RoleIDs {
+		role, err := s.roleRepo.FindByID(roleID)
+		if err != nil {
+			return nil, errors.New("database_error")
+		}
+		if role == nil {
+			return nil, errors.New("invalid_role")
+		}
+	}
+
+	admin := &models.Admin{
+		FirstName: input.FirstName,
+		LastName:  input.LastName,
+		Email:     email,
+		Phone:     input.Phone,
+		Status:    models.AdminStatusInvited,
+		IsActive:  true,
+	}
+
+	var token string
+	var invitation *models.AdminInvitation
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		if err := s.adminRepo.Create(tx, admin); err != nil {
+			return err
+		}
+
+		for _, roleID := range input.RoleIDs {
+			if err := s.adminRepo.AssignRoleToAdmin(tx, admin.ID, roleID); err != nil {
+				return err
+			}
+		}
+
+		var tokenID string
+		var expiresAt time.Time
+		token, tokenID, expiresAt, err = auth.GenerateAdminInviteToken(admin)
+		if err != nil {
+			return err
+		}
+
+		invitation = &models.AdminInvitation{
+			AdminID:    admin.ID,
+			TokenID:    tokenID,
+			InvitedBy:  inviterID,
+			SentCount:  1,
+			LastSentAt: time.Now(),
+			ExpiresAt:  expiresAt,
+		}
+		return s.invitationRepo.Create(tx, invitation)
+	})
+	if err != nil {
+		return nil, errors.New("failed_to_invite_admin")
+	}
+
+	invitation.State = invitation.CurrentState()
+	admin.Invitation = invitation
+
+	if err := s.sendInvite(inviterID, admin, token, invitation.ExpiresAt); err != nil {
+		return admin, err
+	}
+	return admin, nil
+}
+
+// Resend issues a fresh link, which also reopens a revoked or expired
+// invitation. Links sent before stop working.
+func (s *invitationService) Resend(inviterID, adminID uint) (*models.AdminInvitation, error) {
+	admin, invitation, err := s.findInvited(adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, tokenID, expiresAt, err := auth.GenerateAdminInviteToken(admin)
+	if err != nil {
+		return nil, errors.New("failed_to_resend_invitation")
+	}
+
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		invitation.TokenID = tokenID
+		invitation.ExpiresAt = expiresAt
+		invitation.SentCount++
+		invitation.LastSentAt = time.Now()
+		invitation.RevokedAt = nil
+		return s.invitationRepo.Update(tx, invitation)
+	})
+	if err != nil {
+		return nil, errors.New("failed_to_resend_invitation")
+	}
+	invitation.State = invitation.CurrentState()
+
+	if err := s.sendInvite(inviterID, admin, token, expiresAt); err != nil {
+		return invitation, err
+	}
+	return invitation, nil
+}
+
+// Revoke invalidates the outstanding link. The invited admin is kept so the
+// invitation can be resent later, or deleted like any other admin.
+func (s *invitationService) Revoke(adminID uint) error {
+	_, invitation, err := s.findInvited(adminID)
+	if err != nil {
+		return err
+	}
+	if invitation.RevokedAt != nil {
+		return errors.New("invitation_not_pending")
+	}
+
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		invitation.RevokedAt = &now
+		return s.invitationRepo.Update(tx, invitation)
+	})
+	if err != nil {
+		return errors.New("failed_to_revoke_invitation")
+	}
+
+	return nil
+}
+
+// Accept checks the link's token and lets the invitee choose their password.
+// The token must be the latest one issued and is burnt on success.
+func (s *invitationService) Accept(input AcceptInvitationInput) error {
+	claims, err := auth.VerifyAdminToken(input.Token)
+	if err != nil || claims.TokenType != "invite" {
+		return errors.New("invalid_or_expired_invitation")
+	}
+
+	admin, invitation, err := s.findInvited(claims.AdminID)
+	if err != nil {
+		if err.Error() == "database_error" {
+			return err
+		}
+		return errors.New("invalid_or_expired_invitation")
+	}
+	if !admin.IsActive || invitation.TokenID != claims.ID || invitation.CurrentState() != models.InvitationPending {
+		return errors.New("invalid_or_expired_invitation")
+	}
+
+	subject := adminPasswordSubject(admin)
+	if err := s.passwords.Validate(input.Password, subject); err != nil {
+		return err
+	}
+
+	hash, err := auth.HashPassword(input.Password)
+	if err != nil {
+		return errors.New("failed_to_accept_invitation")
+	}
+
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		invitation.AcceptedAt = &now
+		accepted, err := s.invitationRepo.Accept(tx, invitation)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return errors.New("invalid_or_expired_invitation")
+		}
+
+		// The password was chosen by the invitee, so no forced change on first sign-in
+		admin.PasswordHash = hash
+		admin.PasswordChanged = true
+		admin.LastPasswordChange = &now
+		admin.EmailVerified = true
+		admin.Status = models.AdminStatusActive
+		err = s.adminRepo.UpdateColumns(tx, admin.ID, map[string]interface{}{
+			"password_hash":        hash,
+			"password_changed":     true,
+			"last_password_change": now,
+			"email_verified":       true,
+			"status":               models.AdminStatusActive,
+		})
+		if err != nil {
+			return err
+		}
+
+		return s.passwords.Record(tx, subject, admin.PasswordHash)
+	})
+	if err != nil {
+		if err.Error() == "invalid_or_expired_invitation" {
+			return err
+		}
+		return errors.New("failed_to_accept_invitation")
+	}
+
+	return nil
+}
+
+// findInvited loads an admin still waiting on their invitation
+func (s *invitationService) findInvited(adminID uint) (*models.Admin, *models.AdminInvitation, error) {
+	admin, err := s.adminRepo.FindByID(adminID)
+	if err != nil {
+		return nil, nil, errors.New("database_error")
+	}
+	if admin == nil {
+		return nil, nil, errors.New("admin_not_found")
+	}
+	if admin.Status != models.AdminStatusInvited {
+		return nil, nil, errors.New("invitation_not_pending")
+	}
+
+	invitation, err := s.invitationRepo.FindByAdminID(adminID)
+	if err != nil {
+		return nil, nil, errors.New("database_error")
+	}
+	if invitation == nil || invitation.AcceptedAt != nil {
+		return nil, nil, errors.New("invitation_not_pending")
+	}
+
+	return admin, invitation, nil
+}
+
+func (s *invitationService) sendInvite(inviterID uint, admin *models.Admin, token string, expiresAt time.Time) error {
+	inviterName := "A CarHub administrator"
+	if inviter, err := s.adminRepo.FindByID(inviterID); err == nil && inviter != nil {
+		inviterName = strings.TrimSpace(inviter.FirstName + " " + inviter.LastName)
+	}
+
+	err := s.notifier.Notify(notifier.ChannelEmail, admin.Email, notifier.TemplateAdminInvite, map[string]interface{}{
+		"FirstName":      admin.FirstName,
+		"InviterName":    inviterName,
+		"Link":           config.App.AdminInviteURL + "?token=" + url.QueryEscape(token),
+		"ExpiresInHours": int(time.Until(expiresAt).Round(time.Hour).Hours()),
+	})
+	if err != nil {
+		logger.Error().Err(err).Uint("admin_id", admin.ID).Msg("Admin invitation delivery failed")
+		return errors.New("invitation_delivery_failed")
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+// services/admin/service/partner_api_key_service.go
+package service
+
+import (
+	"errors"
+
+	"github.com/jafoor/carhub/libs/audit"
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/libs/repository"
+	"gorm.io/gorm"
+)
+
+// PartnerAPIKeyService lets admins oversee the API keys partners create
+type PartnerAPIKeyService interface {
+	ListPartnerAPIKeys(partnerID uint) ([]models.PartnerAPIKey, error)
+	RevokePartnerAPIKey(adminID, partnerID, keyID uint, clientIP string) error
+}
+
+type partnerAPIKeyService struct {
+	apiKeyRepo repository.PartnerAPIKeyRepository
+}
+
+func NewPartnerAPIKeyService(apiKeyRepo repository.PartnerAPIKeyRepository) PartnerAPIKeyService {
+	return &partnerAPIKeyService{apiKeyRepo: apiKeyRepo}
+}
+
+func (s *partnerAPIKeyService) ListPartnerAPIKeys(partnerID uint) ([]models.PartnerAPIKey, error) {
+	keys, err := s.apiKeyRepo.ListByPartnerID(partnerID)
+	if err != nil {
+		return nil, errors.New("failed_to_list_api_keys")
+	}
+	return keys, nil
+}
+
+// RevokePartnerAPIKey revokes the key immediately and records which admin did it
+func (s *partnerAPIKeyService) RevokePartnerAPIKey(adminID, partnerID, keyID uint, clientIP string) error {
+	var found bool
+	err := database.ExecuteTransaction(func(tx *gorm.DB) error {
+		var err error
+		found, err = s.apiKeyRepo.Revoke(tx, partnerID, keyID, &adminID)
+		return err
+	})
+	if err != nil {
+		return errors.New("failed_to_revoke_api_key")
+	}
+	if !found {
+		return errors.New("api_key_not_found")
+	}
+
+	audit.LogSecurityEvent(audit.SecurityEvent{
+		EventType: models.SecurityEventAPIKeyRevokedByAdmin,
+		OwnerType: models.OwnerTypePartner,
+		OwnerID:   partnerID,
+		IPAddress: clientIP,
+		Details: map[string]interface{}{
+			"api_key_id": keyID,
+			"admin_id":   adminID,
+		},
+	})
+
+	return nil
+}
@@ -0,0 +1,158 @@
+// services/admin/service/password_service.go
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/bruteforce"
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/logger"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/libs/notifier"
+	libRepository "github.com/jafoor/carhub/libs/repository"
+	"github.com/jafoor/carhub/services/admin/repository"
+	"gorm.io/gorm"
+)
+
+type ResetPasswordInput struct {
+	Email       string `json:"email"`
+	OTPCode     string `json:"otp_code"`
+	NewPassword string `json:"new_password"`
+}
+
+type PasswordService interface {
+	RequestPasswordReset(email, clientIP string) error
+	ResetPassword(input ResetPasswordInput) error
+}
+
+type passwordService struct {
+	adminRepo        repository.AdminRepository
+	otpRepo          libRepository.OTPRepository
+	refreshTokenRepo libRepository.AdminRefreshTokenRepository
+	notifier         notifier.Notifier
+	passwords        auth.PasswordPolicy
+	requestGuard     *bruteforce.Guard
+}
+
+func NewPasswordService(
+	adminRepo repository.AdminRepository,
+	otpRepo libRepository.OTPRepository,
+	refreshTokenRepo libRepository.AdminRefreshTokenRepository,
+	notify notifier.Notifier,
+	passwords auth.PasswordPolicy,
+	requestGuard *bruteforce.Guard,
+) PasswordService {
+	return &passwordService{
+		adminRepo:        adminRepo,
+		otpRepo:          otpRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		notifier:         notify,
+		passwords:        passwords,
+		requestGuard:     requestGuard,
+	}
+}
+
+// RequestPasswordReset emails a password_reset OTP to an active admin.
+// Unknown or inactive accounts get the same response as valid ones, so OTP
+// limits and delivery failures, which only valid ones can hit, are logged
+// rather than returned. Every request counts against the client IP instead.
+func (s *passwordService) RequestPasswordReset(email, clientIP string) error {
+	if err := s.requestGuard.Check("", clientIP); err != nil {
+		return err
+	}
+	s.requestGuard.Fail("", clientIP)
+
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	admin, err := s.adminRepo.FindByEmail(email)
+	if err != nil {
+		return errors.New("database_error")
+	}
+	if admin == nil || !admin.IsActive || admin.Status == models.AdminStatusInvited {
+		return nil
+	}
+
+	otp, code, err := auth.IssueOTP(s.otpRepo, admin.ID, models.OwnerTypeAdmin, models.OTPPurposePasswordReset)
+	if err != nil {
+		logger.Warn().Err(err).Uint("admin_id", admin.ID).Msg("Password reset OTP not issued")
+		return nil
+	}
+
+	// SendOTP logs delivery failures itself
+	_ = auth.SendOTP(s.notifier, s.otpRepo, otp, code, notifier.ChannelEmail, admin.Email, admin.FirstName, notifier.TemplateOTPPasswordReset)
+	return nil
+}
+
+// ResetPassword sets a self-chosen password, which also satisfies the
+// first-login password change, and revokes existing refresh tokens.
+func (s *passwordService) ResetPassword(input ResetPasswordInput) error {
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+
+	admin, err := s.adminRepo.FindByEmail(email)
+	if err != nil {
+		return errors.New("database_error")
+	}
+	if admin == nil || !admin.IsActive || admin.Status == models.AdminStatusInvited {
+		return errors.New("invalid_or_expired_otp")
+	}
+
+	otp, err := auth.VerifyOTP(s.otpRepo, admin.ID, models.OwnerTypeAdmin, models.OTPPurposePasswordReset, input.OTPCode)
+	if err != nil {
+		return err
+	}
+
+	subject := adminPasswordSubject(admin)
+	if err := s.passwords.Validate(input.NewPassword, subject); err != nil {
+		return err
+	}
+
+	newHash, err := auth.HashPassword(input.NewPassword)
+	if err != nil {
+		return errors.New("password_reset_failed")
+	}
+
+	now := time.Now()
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		if err := s.otpRepo.MarkAsUsed(tx, otp.ID); err != nil {
+			return err
+		}
+
+		admin.PasswordHash = newHash
+		admin.PasswordChanged = true
+		admin.LastPasswordChange = &now
+		err := s.adminRepo.UpdateColumns(tx, admin.ID, map[string]interface{}{
+			"password_hash":        newHash,
+			"password_changed":     true,
+			"last_password_change": now,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := s.passwords.Record(tx, subject, admin.PasswordHash); err != nil {
+			return err
+		}
+
+		return s.refreshTokenRepo.DeleteByAdminID(tx, admin.ID)
+	})
+	if err != nil {
+		return errors.New("password_reset_failed")
+	}
+
+	return nil
+}
+
+// adminPasswordSubject describes an existing admin to the password policy
+func adminPasswordSubject(admin *models.Admin) auth.PasswordSubject {
+	return auth.PasswordSubject{
+		OwnerType:   models.OwnerTypeAdmin,
+		OwnerID:     admin.ID,
+		CurrentHash: admin.PasswordHash,
+		Email:       admin.Email,
+		FirstName:   admin.FirstName,
+		LastName:    admin.LastName,
+	}
+}
@@ -4,17 +4,23 @@ package service
 import (
 	"errors"
 
+	"github.com/jafoor/carhub/libs/authz"
 	"github.com/jafoor/carhub/libs/database"
 	"github.com/jafoor/carhub/libs/models"
 	"github.com/jafoor/carhub/services/admin/repository"
+	settingsRepository "github.com/jafoor/carhub/services/settings/repository"
 	"gorm.io/gorm"
 )
 
 type RBACService interface {
-	AssignRoleToAdmin(adminID, roleID uint) error
-	AssignPermissionToRole(roleID, permissionID uint) error
+	AssignRoleToAdmin(adminID, roleID uint, scope RoleScope) error
+	AssignPermissionToRole(roleID, permissionID uint, effect string) error
+	UnassignRoleFromAdmin(adminID, roleID uint, scope RoleScope) error
+	UnassignPermissionFromRole(roleID, permissionID uint) error
+	SetRolePermissions(roleID uint, allowIDs, denyIDs []uint) (*RolePermissionsDiff, error)
 	GetAdminRoles(adminID uint) ([]models.AdminRole, error)
-	GetRolePermissions(roleID uint) ([]models.AdminPermission, error)
+	GetAdminRoleAssignments(adminID uint) ([]models.AdminUserRole, error)
+	GetRolePermissions(roleID uint) (*RolePermissions, error)
 	CreateRole(input CreateRoleInput) (*models.AdminRole, error)
 	UpdateRole(roleID uint, input UpdateRoleInput) (*models.AdminRole, error)
 	DeleteRole(roleID uint) error
@@ -25,28 +31,41 @@ type RBACService interface {
 	DeletePermission(permissionID uint) error
 	GetPermission(permissionID uint) (*models.AdminPermission, error)
 	ListPermissions() ([]models.AdminPermission, error)
+	GetPermissionRegistry() (*PermissionRegistry, error)
 }
 
 type rbacService struct {
 	adminRepo        repository.AdminRepository
 	roleRepo         repository.AdminRoleRepository
 	permissionRepo   repository.AdminPermissionRepository
+	settingsRepo     settingsRepository.SettingsRepository
+}
+
+// RoleScope limits a role assignment to one region or one city. The zero
+// value assigns the role everywhere.
+type RoleScope struct {
+	RegionID *uint
+	CityID   *uint
 }
 
 type CreateRoleInput struct {
-	Name         string
-	DisplayName  string
-	Description  *string
-	IsDefault    bool
-	IsSuperAdmin bool
+	Name              string
+	DisplayName       string
+	Description       *string
+	IsDefault         bool
+	IsSuperAdmin      bool
+	RequiresTwoFactor bool
+	ParentID          *uint
 }
 
 type UpdateRoleInput struct {
-	Name         string
-	DisplayName  string
-	Description  *string
-	IsDefault    bool
-	IsSuperAdmin bool
+	Name              string
+	DisplayName       string
+	Description       *string
+	IsDefault         bool
+	IsSuperAdmin      bool
+	RequiresTwoFactor bool
+	ParentID          *uint
 }
 
 type CreatePermissionInput struct {
@@ -59,20 +78,78 @@ type UpdatePermissionInput struct {
 	Description *string
 }
 
+// RolePermissions separates what is assigned to a role from what it ends up
+// with once its ancestors' permissions are added
+type RolePermissions struct {
+	Direct    []models.AdminPermission `json:"direct"`
+	Effective []models.AdminPermission `json:"effective"`
+}
+
+// RolePermissionsDiff is what SetRolePermissions changed
+type RolePermissionsDiff struct {
+	Added   []models.AdminPermission `json:"added"`
+	Removed []models.AdminPermission `json:"removed"`
+}
+
+// PermissionRegistry is the permission catalog declared in code, the routes
+// that check each permission, and stored permissions nothing declares
+type PermissionRegistry struct {
+	Permissions []authz.PermissionDefinition `json:"permissions"`
+	Routes      []authz.RoutePermission      `json:"routes"`
+	Orphaned    []string                     `json:"orphaned"`
+}
+
 func NewRBACService(
 	adminRepo repository.AdminRepository,
 	roleRepo repository.AdminRoleRepository,
 	permissionRepo repository.AdminPermissionRepository,
+	settingsRepo settingsRepository.SettingsRepository,
 ) RBACService {
 	return &rbacService{
 		adminRepo:      adminRepo,
 		roleRepo:       roleRepo,
 		permissionRepo: permissionRepo,
+		settingsRepo:   settingsRepo,
 	}
 }
 
-// AssignRoleToAdmin assigns a role to an admin
-func (s *rbacService) AssignRoleToAdmin(adminID, roleID uint) error {
+// checkScope validates a role assignment's scope. Super-admin roles always
+// apply everywhere.
+func (s *rbacService) checkScope(role *models.AdminRole, scope RoleScope) error {
+	if scope.RegionID == nil && scope.CityID == nil {
+		return nil
+	}
+	if scope.RegionID != nil && scope.CityID != nil {
+		return errors.New("invalid_scope")
+	}
+	if role.IsSuperAdmin {
+		return errors.New("super_admin_role_cannot_be_scoped")
+	}
+
+	if scope.RegionID != nil {
+		region, err := s.settingsRepo.GetRegion(*scope.RegionID)
+		if err != nil {
+			return errors.New("failed_to_find_scope")
+		}
+		if region == nil {
+			return errors.New("region_not_found")
+		}
+		return nil
+	}
+
+	city, err := s.settingsRepo.GetCity(*scope.CityID)
+	if err != nil {
+		return errors.New("failed_to_find_scope")
+	}
+	if city == nil {
+		return errors.New("city_not_found")
+	}
+	return nil
+}
+
+// AssignRoleToAdmin assigns a role to an admin, everywhere or limited to the
+// scope's region or city. The same role may be held with several scopes.
+func (s *rbacService) AssignRoleToAdmin(adminID, roleID uint, scope RoleScope) error {
 	// Validate admin exists
 	admin, err := s.adminRepo.FindByID(adminID)
 	if err != nil {
@@ -91,25 +168,52 @@ func (s *rbacService) AssignRoleToAdmin(adminID, roleID uint) error {
 		return errors.New("role_not_found")
 	}
 
+	if err := s.checkScope(role, scope); err != nil {
+		return err
+	}
+
 	// Check if assignment already exists
-	existingRoles, err := s.adminRepo.GetAdminRoles(adminID)
+	existing, err := s.adminRepo.GetRoleAssignments(adminID)
 	if err != nil {
 		return errors.New("failed_to_check_existing_roles")
 	}
-	for _, r := range existingRoles {
-		if r.ID == roleID {
+	for _, a := range existing {
+		if a.RoleID == roleID && sameID(a.RegionID, scope.RegionID) && sameID(a.CityID, scope.CityID) {
 			return errors.New("role_already_assigned")
 		}
 	}
 
 	// Assign role in transaction
-	return database.ExecuteTransaction(func(tx *gorm.DB) error {
-		return s.adminRepo.AssignRoleToAdmin(tx, adminID, roleID)
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		assignment := &models.AdminUserRole{
+			AdminID:  adminID,
+			RoleID:   roleID,
+			RegionID: scope.RegionID,
+			CityID:   scope.CityID,
+		}
+		if err := s.adminRepo.CreateRoleAssignment(tx, assignment); err != nil {
+			return err
+		}
+		return s.adminRepo.BumpAuthzVersion(tx, adminID)
 	})
+	if err != nil {
+		return err
+	}
+
+	authz.Permissions.Invalidate(adminID)
+	return nil
 }
 
-// AssignPermissionToRole assigns a permission to a role
-func (s *rbacService) AssignPermissionToRole(roleID, permissionID uint) error {
+// AssignPermissionToRole assigns a permission to a role as an allow, or as a
+// deny that overrides allows of the same name or a matching wildcard
+func (s *rbacService) AssignPermissionToRole(roleID, permissionID uint, effect string) error {
+	if effect == "" {
+		effect = models.PermissionEffectAllow
+	}
+	if effect != models.PermissionEffectAllow && effect != models.PermissionEffectDeny {
+		return errors.New("invalid_effect")
+	}
+
 	// Validate role exists
 	role, err := s.roleRepo.FindByID(roleID)
 	if err != nil {
@@ -136,7 +240,7 @@ func (s *rbacService) AssignPermissionToRole(roleID, permissionID uint) error {
 	}
 
 	// Check if assignment already exists
-	existingPermissions, err := s.permissionRepo.GetRolePermissions(roleID)
+	existingPermissions, err := s.permissionRepo.GetDirectRolePermissions(roleID)
 	if err != nil {
 		return errors.New("failed_to_check_existing_permissions")
 	}
@@ -147,9 +251,212 @@ func (s *rbacService) AssignPermissionToRole(roleID, permissionID uint) error {
 	}
 
 	// Assign permission in transaction
-	return database.ExecuteTransaction(func(tx *gorm.DB) error {
-		return s.permissionRepo.AssignPermissionToRole(tx, roleID, permissionID)
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		if err := s.permissionRepo.AssignPermissionToRole(tx, roleID, permissionID, effect); err != nil {
+			return err
+		}
+		return s.adminRepo.BumpAuthzVersionForRole(tx, roleID)
 	})
+	if err != nil {
+		return err
+	}
+
+	authz.Permissions.InvalidateAll()
+	return nil
+}
+
+func sameID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// UnassignRoleFromAdmin removes the assignment of a role with exactly the
+// given scope. Other scopes of the same role are kept.
+func (s *rbacService) UnassignRoleFromAdmin(adminID, roleID uint, scope RoleScope) error {
+	admin, err := s.adminRepo.FindByID(adminID)
+	if err != nil {
+		return errors.New("failed_to_find_admin")
+	}
+	if admin == nil {
+		return errors.New("admin_not_found")
+	}
+
+	role, err := s.roleRepo.FindByID(roleID)
+	if err != nil {
+		return errors.New("failed_to_find_role")
+	}
+	if role == nil {
+		return errors.New("role_not_found")
+	}
+
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		removed, err := s.adminRepo.RemoveRoleFromAdmin(tx, adminID, roleID, scope.RegionID, scope.CityID)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return errors.New("role_not_assigned")
+		}
+		return s.adminRepo.BumpAuthzVersion(tx, adminID)
+	})
+	if err != nil {
+		if err.Error() == "role_not_assigned" {
+			return err
+		}
+		return errors.New("failed_to_unassign_role")
+	}
+
+	authz.Permissions.Invalidate(adminID)
+	return nil
+}
+
+// UnassignPermissionFromRole removes a permission from a role
+func (s *rbacService) UnassignPermissionFromRole(roleID, permissionID uint) error {
+	role, err := s.roleRepo.FindByID(roleID)
+	if err != nil {
+		return errors.New("failed_to_find_role")
+	}
+	if role == nil {
+		return errors.New("role_not_found")
+	}
+
+	permission, err := s.permissionRepo.FindByID(permissionID)
+	if err != nil {
+		return errors.New("failed_to_find_permission")
+	}
+	if permission == nil {
+		return errors.New("permission_not_found")
+	}
+
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		removed, err := s.permissionRepo.RemovePermissionFromRole(tx, roleID, permissionID)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return errors.New("permission_not_assigned")
+		}
+		return s.adminRepo.BumpAuthzVersionForRole(tx, roleID)
+	})
+	if err != nil {
+		if err.Error() == "permission_not_assigned" {
+			return err
+		}
+		return errors.New("failed_to_unassign_permission")
+	}
+
+	authz.Permissions.InvalidateAll()
+	return nil
+}
+
+// SetRolePermissions makes allowIDs and denyIDs the role's exact permission
+// set. The current set is read and changed in one transaction, and only the
+// difference is written; an unchanged set touches nothing. A permission whose
+// effect flips shows up as both removed and added.
+func (s *rbacService) SetRolePermissions(roleID uint, allowIDs, denyIDs []uint) (*RolePermissionsDiff, error) {
+	role, err := s.roleRepo.FindByID(roleID)
+	if err != nil {
+		return nil, errors.New("failed_to_find_role")
+	}
+	if role == nil {
+		return nil, errors.New("role_not_found")
+	}
+
+	wanted, ids, err := wantedEffects(allowIDs, denyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.permissionRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, errors.New("failed_to_check_permissions")
+	}
+	if len(permissions) != len(ids) {
+		return nil, errors.New("permission_not_found")
+	}
+
+	var diff *RolePermissionsDiff
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		current, err := s.permissionRepo.GetRolePermissionsForUpdate(tx, roleID)
+		if err != nil {
+			return err
+		}
+
+		diff = diffRolePermissions(current, permissions, wanted)
+		if len(diff.Added) == 0 && len(diff.Removed) == 0 {
+			return nil
+		}
+
+		for _, p := range diff.Removed {
+			if _, err := s.permissionRepo.RemovePermissionFromRole(tx, roleID, p.ID); err != nil {
+				return err
+			}
+		}
+		for _, p := range diff.Added {
+			if err := s.permissionRepo.AssignPermissionToRole(tx, roleID, p.ID, p.Effect); err != nil {
+				return err
+			}
+		}
+		return s.adminRepo.BumpAuthzVersionForRole(tx, roleID)
+	})
+	if err != nil {
+		return nil, errors.New("failed_to_set_permissions")
+	}
+
+	if len(diff.Added) > 0 || len(diff.Removed) > 0 {
+		authz.Permissions.InvalidateAll()
+	}
+	return diff, nil
+}
+
+// wantedEffects maps each requested permission to its effect, keeping the
+// first occurrence of duplicates. ids lists the permissions in request order.
+func wantedEffects(allowIDs, denyIDs []uint) (map[uint]string, []uint, error) {
+	wanted := make(map[uint]string, len(allowIDs)+len(denyIDs))
+	ids := make([]uint, 0, len(allowIDs)+len(denyIDs))
+	for _, id := range allowIDs {
+		if wanted[id] == "" {
+			wanted[id] = models.PermissionEffectAllow
+			ids = append(ids, id)
+		}
+	}
+	for _, id := range denyIDs {
+		switch wanted[id] {
+		case models.PermissionEffectAllow:
+			return nil, nil, errors.New("conflicting_effects")
+		case "":
+			wanted[id] = models.PermissionEffectDeny
+			ids = append(ids, id)
+		}
+	}
+	return wanted, ids, nil
+}
+
+// diffRolePermissions compares the role's current entries with the wanted
+// set. permissions are the wanted permissions as loaded; a changed effect
+// removes the old entry and adds the new one.
+func diffRolePermissions(current, permissions []models.AdminPermission, wanted map[uint]string) *RolePermissionsDiff {
+	diff := &RolePermissionsDiff{
+		Added:   []models.AdminPermission{},
+		Removed: []models.AdminPermission{},
+	}
+
+	held := make(map[uint]string, len(current))
+	for _, p := range current {
+		held[p.ID] = p.Effect
+		if wanted[p.ID] != p.Effect {
+			diff.Removed = append(diff.Removed, p)
+		}
+	}
+	for _, p := range permissions {
+		if held[p.ID] != wanted[p.ID] {
+			p.Effect = wanted[p.ID]
+			diff.Added = append(diff.Added, p)
+		}
+	}
+	return diff
 }
 
 // GetAdminRoles retrieves all roles assigned to an admin
@@ -166,8 +473,22 @@ func (s *rbacService) GetAdminRoles(adminID uint) ([]models.AdminRole, error) {
 	return s.adminRepo.GetAdminRoles(adminID)
 }
 
-// GetRolePermissions retrieves all permissions assigned to a role
-func (s *rbacService) GetRolePermissions(roleID uint) ([]models.AdminPermission, error) {
+// GetAdminRoleAssignments lists an admin's role assignments with their scopes
+func (s *rbacService) GetAdminRoleAssignments(adminID uint) ([]models.AdminUserRole, error) {
+	admin, err := s.adminRepo.FindByID(adminID)
+	if err != nil {
+		return nil, errors.New("failed_to_find_admin")
+	}
+	if admin == nil {
+		return nil, errors.New("admin_not_found")
+	}
+
+	return s.adminRepo.GetRoleAssignments(adminID)
+}
+
+// GetRolePermissions retrieves the permissions assigned to a role and those
+// it inherits
+func (s *rbacService) GetRolePermissions(roleID uint) (*RolePermissions, error) {
 	// Validate role exists
 	role, err := s.roleRepo.FindByID(roleID)
 	if err != nil {
@@ -177,7 +498,73 @@ func (s *rbacService) GetRolePermissions(roleID uint) ([]models.AdminPermission,
 		return nil, errors.New("role_not_found")
 	}
 
-	return s.permissionRepo.GetRolePermissions(roleID)
+	direct, err := s.permissionRepo.GetDirectRolePermissions(roleID)
+	if err != nil {
+		return nil, err
+	}
+	effective, err := s.permissionRepo.GetRolePermissions(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RolePermissions{Direct: direct, Effective: effective}, nil
+}
+
+// checkParent validates a role's new parent inside the transaction that
+// stores it. roleID is zero for roles being created, which can't be part of
+// a cycle yet. The role and every ancestor walked are locked, so of two
+// concurrent re-parents that would close a cycle, the second waits and then
+// sees the first.
+func (s *rbacService) checkParent(tx *gorm.DB, roleID uint, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+	if *parentID == roleID {
+		return errors.New("role_cycle")
+	}
+
+	if roleID != 0 {
+		if _, err := s.roleRepo.FindByIDForUpdate(tx, roleID); err != nil {
+			return errors.New("failed_to_find_role")
+		}
+	}
+
+	seen := make(map[uint]bool)
+	for id := parentID; id != nil; {
+		if *id == roleID {
+			return errors.New("role_cycle")
+		}
+		// Stored data should never hold a cycle, but don't loop if it does
+		if seen[*id] {
+			return nil
+		}
+		seen[*id] = true
+
+		role, err := s.roleRepo.FindByIDForUpdate(tx, *id)
+		if err != nil {
+			return errors.New("failed_to_find_role")
+		}
+		if role == nil {
+			if id == parentID {
+				return errors.New("parent_role_not_found")
+			}
+			return nil
+		}
+		if roleID == 0 {
+			return nil
+		}
+		id = role.ParentID
+	}
+	return nil
+}
+
+// parentError reports whether err came from checkParent
+func parentError(err error) bool {
+	switch err.Error() {
+	case "role_cycle", "parent_role_not_found", "failed_to_find_role":
+		return true
+	}
+	return false
 }
 
 func (s *rbacService) CreateRole(input CreateRoleInput) (*models.AdminRole, error) {
@@ -197,14 +584,22 @@ func (s *rbacService) CreateRole(input CreateRoleInput) (*models.AdminRole, erro
 		Name:         input.Name,
 		DisplayName:  input.DisplayName,
 		Description:  input.Description,
-		IsDefault:    input.IsDefault,
-		IsSuperAdmin: input.IsSuperAdmin,
+		IsDefault:         input.IsDefault,
+		IsSuperAdmin:      input.IsSuperAdmin,
+		RequiresTwoFactor: input.RequiresTwoFactor,
+		ParentID:          input.ParentID,
 	}
 
 	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		if err := s.checkParent(tx, 0, input.ParentID); err != nil {
+			return err
+		}
 		return s.roleRepo.Create(tx, role)
 	})
 	if err != nil {
+		if parentError(err) {
+			return nil, err
+		}
 		return nil, errors.New("failed_to_create_role")
 	}
 
@@ -235,17 +630,32 @@ func (s *rbacService) UpdateRole(roleID uint, input UpdateRoleInput) (*models.Ad
 	}
 
 	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		if err := s.checkParent(tx, roleID, input.ParentID); err != nil {
+			return err
+		}
+
 		role.Name = input.Name
 		role.DisplayName = input.DisplayName
 		role.Description = input.Description
 		role.IsDefault = input.IsDefault
 		role.IsSuperAdmin = input.IsSuperAdmin
-		return s.roleRepo.Update(tx, role)
+		role.RequiresTwoFactor = input.RequiresTwoFactor
+		role.ParentID = input.ParentID
+		if err := s.roleRepo.Update(tx, role); err != nil {
+			return err
+		}
+		// Name, super-admin and 2FA flags all end up in holders' tokens, and a
+		// new parent changes what the role and its descendants inherit
+		return s.adminRepo.BumpAuthzVersionForRole(tx, roleID)
 	})
 	if err != nil {
+		if parentError(err) {
+			return nil, err
+		}
 		return nil, errors.New("failed_to_update_role")
 	}
 
+	authz.Permissions.InvalidateAll()
 	return role, nil
 }
 
@@ -259,6 +669,10 @@ func (s *rbacService) DeleteRole(roleID uint) error {
 	}
 
 	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		// Bump while the holders are still known
+		if err := s.adminRepo.BumpAuthzVersionForRole(tx, roleID); err != nil {
+			return err
+		}
 		if err := tx.Table("admin_user_roles").Where("role_id = ?", roleID).Delete(nil).Error; err != nil {
 			return err
 		}
@@ -271,6 +685,7 @@ func (s *rbacService) DeleteRole(roleID uint) error {
 		return errors.New("failed_to_delete_role")
 	}
 
+	authz.Permissions.InvalidateAll()
 	return nil
 }
 
@@ -294,7 +709,7 @@ func (s *rbacService) ListRoles() ([]models.AdminRole, error) {
 }
 
 func (s *rbacService) CreatePermission(input CreatePermissionInput) (*models.AdminPermission, error) {
-	if input.Name == "" {
+	if !authz.ValidPermissionName(input.Name) {
 		return nil, errors.New("invalid_permission_data")
 	}
 
@@ -330,7 +745,7 @@ func (s *rbacService) UpdatePermission(permissionID uint, input UpdatePermission
 		return nil, errors.New("permission_not_found")
 	}
 
-	if input.Name == "" {
+	if !authz.ValidPermissionName(input.Name) {
 		return nil, errors.New("invalid_permission_data")
 	}
 
@@ -347,12 +762,16 @@ func (s *rbacService) UpdatePermission(permissionID uint, input UpdatePermission
 	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
 		permission.Name = input.Name
 		permission.Description = input.Description
-		return s.permissionRepo.Update(tx, permission)
+		if err := s.permissionRepo.Update(tx, permission); err != nil {
+			return err
+		}
+		return s.adminRepo.BumpAuthzVersionForPermission(tx, permissionID)
 	})
 	if err != nil {
 		return nil, errors.New("failed_to_update_permission")
 	}
 
+	authz.Permissions.InvalidateAll()
 	return permission, nil
 }
 
@@ -366,6 +785,10 @@ func (s *rbacService) DeletePermission(permissionID uint) error {
 	}
 
 	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		// Bump while the holders are still known
+		if err := s.adminRepo.BumpAuthzVersionForPermission(tx, permissionID); err != nil {
+			return err
+		}
 		if err := tx.Table("admin_role_permissions").Where("permission_id = ?", permissionID).Delete(nil).Error; err != nil {
 			return err
 		}
@@ -375,6 +798,7 @@ func (s *rbacService) DeletePermission(permissionID uint) error {
 		return errors.New("failed_to_delete_permission")
 	}
 
+	authz.Permissions.InvalidateAll()
 	return nil
 }
 
@@ -396,3 +820,16 @@ func (s *rbacService) ListPermissions() ([]models.AdminPermission, error) {
 	}
 	return permissions, nil
 }
+
+func (s *rbacService) GetPermissionRegistry() (*PermissionRegistry, error) {
+	stored, err := s.permissionRepo.FindAll()
+	if err != nil {
+		return nil, errors.New("failed_to_list_permissions")
+	}
+
+	return &PermissionRegistry{
+		Permissions: authz.Definitions(),
+		Routes:      authz.Routes(),
+		Orphaned:    authz.Orphans(stored),
+	}, nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/services/admin/repository"
+	"gorm.io/gorm"
+)
+
+// fakeRoleRepository holds a role tree as id -> parent id (0 for none). The
+// embedded interface is nil; calling anything else panics.
+type fakeRoleRepository struct {
+	repository.AdminRoleRepository
+	parents map[uint]uint
+	locked  []uint
+}
+
+func (r *fakeRoleRepository) FindByIDForUpdate(tx *gorm.DB, id uint) (*models.AdminRole, error) {
+	parent, ok := r.parents[id]
+	if !ok {
+		return nil, nil
+	}
+	r.locked = append(r.locked, id)
+	role := &models.AdminRole{ID: id}
+	if parent != 0 {
+		role.ParentID = &parent
+	}
+	return role, nil
+}
+
+func TestCheckParent(t *testing.T) {
+	// 1 <- 2 <- 3 <- 4, and 5 on its own
+	tree := map[uint]uint{1: 0, 2: 1, 3: 2, 4: 3, 5: 0}
+
+	tests := []struct {
+		name       string
+		roleID     uint
+		parentID   uint // 0 for none
+		wantErr    string
+		wantLocked []uint
+	}{
+		{"no parent", 2, 0, "", nil},
+		{"self parent", 2, 2, "role_cycle", nil},
+		{"direct two-role cycle", 1, 2, "role_cycle", []uint{1, 2}},
+		{"deep cycle", 1, 4, "role_cycle", []uint{1, 4, 3, 2}},
+		{"valid re-parent", 4, 5, "", []uint{4, 5}},
+		{"valid re-parent deeper", 5, 4, "", []uint{5, 4, 3, 2, 1}},
+		{"missing parent", 2, 9, "parent_role_not_found", []uint{2}},
+		{"new role", 0, 4, "", []uint{4}},
+		{"new role, missing parent", 0, 9, "parent_role_not_found", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRoleRepository{parents: tree}
+			s := &rbacService{roleRepo: repo}
+
+			var parentID *uint
+			if tt.parentID != 0 {
+				parentID = &tt.parentID
+			}
+
+			err := s.checkParent(nil, tt.roleID, parentID)
+			if gotErr := errString(err); gotErr != tt.wantErr {
+				t.Fatalf("checkParent() = %q, want %q", gotErr, tt.wantErr)
+			}
+			if !slices.Equal(repo.locked, tt.wantLocked) {
+				t.Errorf("locked %v, want %v", repo.locked, tt.wantLocked)
+			}
+		})
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestWantedEffects(t *testing.T) {
+	tests := []struct {
+		name     string
+		allowIDs []uint
+		denyIDs  []uint
+		wantIDs  []uint
+		wantErr  string
+	}{
+		{"empty", nil, nil, []uint{}, ""},
+		{"allows then denies", []uint{1, 2}, []uint{3}, []uint{1, 2, 3}, ""},
+		{"duplicates kept once", []uint{1, 1}, []uint{3, 3}, []uint{1, 3}, ""},
+		{"allowed and denied", []uint{1}, []uint{1}, nil, "conflicting_effects"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wanted, ids, err := wantedEffects(tt.allowIDs, tt.denyIDs)
+			if gotErr := errString(err); gotErr != tt.wantErr {
+				t.Fatalf("wantedEffects() error = %q, want %q", gotErr, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+			for _, id := range tt.allowIDs {
+				if wanted[id] != models.PermissionEffectAllow {
+					t.Errorf("wanted[%d] = %q, want allow", id, wanted[id])
+				}
+			}
+			for _, id := range tt.denyIDs {
+				if wanted[id] != models.PermissionEffectDeny {
+					t.Errorf("wanted[%d] = %q, want deny", id, wanted[id])
+				}
+			}
+		})
+	}
+}
+
+// entries writes role permissions as "<id>:<effect>"
+func entries(permissions []models.AdminPermission) []string {
+	out := make([]string, len(permissions))
+	for i, p := range permissions {
+		out[i] = fmt.Sprintf("%d:%s", p.ID, p.Effect)
+	}
+	return out
+}
+
+func TestDiffRolePermissions(t *testing.T) {
+	held := func(id uint, effect string) models.AdminPermission {
+		return models.AdminPermission{ID: id, Effect: effect}
+	}
+	allow, deny := models.PermissionEffectAllow, models.PermissionEffectDeny
+
+	tests := []struct {
+		name        string
+		current     []models.AdminPermission
+		allowIDs    []uint
+		denyIDs     []uint
+		wantAdded   []string
+		wantRemoved []string
+	}{
+		{"no-op", []models.AdminPermission{held(1, allow), held(2, deny)}, []uint{1}, []uint{2}, []string{}, []string{}},
+		{"empty to empty", nil, nil, nil, []string{}, []string{}},
+		{"add only", []models.AdminPermission{held(1, allow)}, []uint{1, 2}, []uint{3}, []string{"2:allow", "3:deny"}, []string{}},
+		{"remove only", []models.AdminPermission{held(1, allow), held(2, deny)}, []uint{1}, nil, []string{}, []string{"2:deny"}},
+		{"remove all", []models.AdminPermission{held(1, allow), held(2, deny)}, nil, nil, []string{}, []string{"1:allow", "2:deny"}},
+		{"allow switched to deny", []models.AdminPermission{held(1, allow)}, nil, []uint{1}, []string{"1:deny"}, []string{"1:allow"}},
+		{"deny switched to allow", []models.AdminPermission{held(1, deny)}, []uint{1}, nil, []string{"1:allow"}, []string{"1:deny"}},
+		{"mixed", []models.AdminPermission{held(1, allow), held(2, allow), held(3, deny)}, []uint{1, 4}, []uint{2}, []string{"2:deny", "4:allow"}, []string{"2:allow", "3:deny"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wanted, ids, err := wantedEffects(tt.allowIDs, tt.denyIDs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// As loaded by FindByIDs, without an effect
+			permissions := make([]models.AdminPermission, len(ids))
+			for i, id := range ids {
+				permissions[i] = models.AdminPermission{ID: id}
+			}
+			slices.SortFunc(permissions, func(a, b models.AdminPermission) int { return int(a.ID) - int(b.ID) })
+
+			diff := diffRolePermissions(tt.current, permissions, wanted)
+			if got := entries(diff.Added); !slices.Equal(got, tt.wantAdded) {
+				t.Errorf("added %v, want %v", got, tt.wantAdded)
+			}
+			if got := entries(diff.Removed); !slices.Equal(got, tt.wantRemoved) {
+				t.Errorf("removed %v, want %v", got, tt.wantRemoved)
+			}
+		})
+	}
+}
@@ -0,0 +1,361 @@
+// services/admin/service/two_factor_service.go
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/config"
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/services/admin/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	recoveryCodeCount    = 10
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789" // no 0/o, 1/l/i
+)
+
+type TwoFactorStatusResponse struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+type TwoFactorEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // encode as a QR code for authenticator apps
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorService interface {
+	GetStatus(adminID uint) (*TwoFactorStatusResponse, error)
+	Enroll(adminID uint) (*TwoFactorEnrollmentResponse, error)
+	Activate(adminID uint, code string) (*RecoveryCodesResponse, error)
+	RegenerateRecoveryCodes(adminID uint, code string) (*RecoveryCodesResponse, error)
+	Disable(adminID uint, code string) error
+	VerifySecondFactor(admin *models.Admin, code, recoveryCode string) error
+}
+
+type twoFactorService struct {
+	adminRepo        repository.AdminRepository
+	recoveryCodeRepo repository.AdminRecoveryCodeRepository
+}
+
+func NewTwoFactorService(
+	adminRepo repository.AdminRepository,
+	recoveryCodeRepo repository.AdminRecoveryCodeRepository,
+) TwoFactorService {
+	return &twoFactorService{
+		adminRepo:        adminRepo,
+		recoveryCodeRepo: recoveryCodeRepo,
+	}
+}
+
+func (s *twoFactorService) findAdmin(adminID uint) (*models.Admin, error) {
+	admin, err := s.adminRepo.FindByID(adminID)
+	if err != nil {
+		return nil, errors.New("failed_to_find_admin")
+	}
+	if admin == nil {
+		return nil, errors.New("admin_not_found")
+	}
+	return admin, nil
+}
+
+func (s *twoFactorService) GetStatus(adminID uint) (*TwoFactorStatusResponse, error) {
+	admin, err := s.findAdmin(adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := s.adminRepo.GetAdminRoles(adminID)
+	if err != nil {
+		return nil, errors.New("failed_to_get_roles")
+	}
+
+	remaining, err := s.recoveryCodeRepo.CountUnused(adminID)
+	if err != nil {
+		return nil, errors.New("failed_to_get_two_factor_status")
+	}
+
+	return &TwoFactorStatusResponse{
+		Enabled:                admin.TwoFactorEnabled,
+		Required:               auth.RolesRequireTwoFactor(roles),
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// Enroll creates a new TOTP secret. It only takes effect after Activate, so
+// calling Enroll again simply replaces an unconfirmed secret.
+func (s *twoFactorService) Enroll(adminID uint) (*TwoFactorEnrollmentResponse, error) {
+	admin, err := s.findAdmin(adminID)
+	if err != nil {
+		return nil, err
+	}
+	if admin.TwoFactorEnabled {
+		return nil, errors.New("two_factor_already_enabled")
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, errors.New("failed_to_enroll_two_factor")
+	}
+	sealed, err := auth.SealTOTPSecret(admin.ID, secret)
+	if err != nil {
+		return nil, errors.New("failed_to_enroll_two_factor")
+	}
+
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		admin.TOTPSecret = sealed
+		admin.TOTPLastUsedStep = 0
+		return s.adminRepo.UpdateColumns(tx, admin.ID, map[string]interface{}{
+			"totp_secret":         sealed,
+			"totp_last_used_step": 0,
+		})
+	})
+	if err != nil {
+		return nil, errors.New("failed_to_enroll_two_factor")
+	}
+
+	return &TwoFactorEnrollmentResponse{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(config.App.TOTPIssuer, admin.Email, secret),
+	}, nil
+}
+
+// Activate turns 2FA on once the admin proves their app produces valid codes,
+// and returns the recovery codes. They are shown only this once.
+func (s *twoFactorService) Activate(adminID uint, code string) (*RecoveryCodesResponse, error) {
+	admin, err := s.findAdmin(adminID)
+	if err != nil {
+		return nil, err
+	}
+	if admin.TwoFactorEnabled {
+		return nil, errors.New("two_factor_already_enabled")
+	}
+	if admin.TOTPSecret == "" {
+		return nil, errors.New("two_factor_not_enrolled")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, errors.New("failed_to_activate_two_factor")
+	}
+
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		if err := s.consumeTOTP(tx, admin, code); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		admin.TwoFactorEnabled = true
+		admin.TwoFactorEnabledAt = &now
+		err := s.adminRepo.UpdateColumns(tx, admin.ID, map[string]interface{}{
+			"two_factor_enabled":    true,
+			"two_factor_enabled_at": now,
+		})
+		if err != nil {
+			return err
+		}
+
+		return s.recoveryCodeRepo.ReplaceForAdmin(tx, admin.ID, hashes)
+	})
+	if err != nil {
+		if err.Error() == "invalid_two_factor_code" {
+			return nil, err
+		}
+		return nil, errors.New("failed_to_activate_two_factor")
+	}
+
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, used or not
+func (s *twoFactorService) RegenerateRecoveryCodes(adminID uint, code string) (*RecoveryCodesResponse, error) {
+	admin, err := s.findAdmin(adminID)
+	if err != nil {
+		return nil, err
+	}
+	if !admin.TwoFactorEnabled {
+		return nil, errors.New("two_factor_not_enabled")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, errors.New("failed_to_generate_recovery_codes")
+	}
+
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		if err := s.consumeTOTP(tx, admin, code); err != nil {
+			return err
+		}
+		return s.recoveryCodeRepo.ReplaceForAdmin(tx, admin.ID, hashes)
+	})
+	if err != nil {
+		if err.Error() == "invalid_two_factor_code" {
+			return nil, err
+		}
+		return nil, errors.New("failed_to_generate_recovery_codes")
+	}
+
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// Disable removes 2FA, unless one of the admin's roles requires it
+func (s *twoFactorService) Disable(adminID uint, code string) error {
+	admin, err := s.findAdmin(adminID)
+	if err != nil {
+		return err
+	}
+	if !admin.TwoFactorEnabled {
+		return errors.New("two_factor_not_enabled")
+	}
+
+	roles, err := s.adminRepo.GetAdminRoles(adminID)
+	if err != nil {
+		return errors.New("failed_to_get_roles")
+	}
+	if auth.RolesRequireTwoFactor(roles) {
+		return errors.New("two_factor_required_by_role")
+	}
+
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		if err := s.consumeTOTP(tx, admin, code); err != nil {
+			return err
+		}
+
+		admin.TwoFactorEnabled = false
+		admin.TwoFactorEnabledAt = nil
+		admin.TOTPSecret = ""
+		admin.TOTPLastUsedStep = 0
+		err := s.adminRepo.UpdateColumns(tx, admin.ID, map[string]interface{}{
+			"two_factor_enabled":    false,
+			"two_factor_enabled_at": nil,
+			"totp_secret":           "",
+			"totp_last_used_step":   0,
+		})
+		if err != nil {
+			return err
+		}
+
+		return s.recoveryCodeRepo.DeleteByAdminID(tx, admin.ID)
+	})
+	if err != nil {
+		if err.Error() == "invalid_two_factor_code" {
+			return err
+		}
+		return errors.New("failed_to_disable_two_factor")
+	}
+
+	return nil
+}
+
+// VerifySecondFactor checks the sign-in second step: a TOTP code, or a
+// recovery code which is burnt on use
+func (s *twoFactorService) VerifySecondFactor(admin *models.Admin, code, recoveryCode string) error {
+	if !admin.TwoFactorEnabled {
+		return errors.New("two_factor_not_enabled")
+	}
+
+	err := database.ExecuteTransaction(func(tx *gorm.DB) error {
+		if code != "" {
+			return s.consumeTOTP(tx, admin, code)
+		}
+
+		consumed, err := s.recoveryCodeRepo.Consume(tx, admin.ID, hashRecoveryCode(recoveryCode))
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			return errors.New("invalid_two_factor_code")
+		}
+		return nil
+	})
+	if err != nil {
+		if err.Error() == "invalid_two_factor_code" {
+			return err
+		}
+		return errors.New("two_factor_verification_failed")
+	}
+
+	return nil
+}
+
+// consumeTOTP validates a code and records its time step so it can't be
+// replayed, including by a concurrent request. A secret stored before
+// encryption is sealed on the way.
+func (s *twoFactorService) consumeTOTP(tx *gorm.DB, admin *models.Admin, code string) error {
+	secret, err := auth.OpenTOTPSecret(admin.ID, admin.TOTPSecret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := auth.ValidateTOTP(secret, strings.TrimSpace(code), admin.TOTPLastUsedStep)
+	if !ok {
+		return errors.New("invalid_two_factor_code")
+	}
+
+	marked, err := s.adminRepo.MarkTOTPStepUsed(tx, admin.ID, step)
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return errors.New("invalid_two_factor_code")
+	}
+
+	// Keep the loaded copy current for the caller
+	admin.TOTPLastUsedStep = step
+
+	if !auth.TOTPSecretSealed(admin.TOTPSecret) {
+		sealed, err := auth.SealTOTPSecret(admin.ID, secret)
+		if err != nil {
+			return err
+		}
+		admin.TOTPSecret = sealed
+		return s.adminRepo.UpdateColumns(tx, admin.ID, map[string]interface{}{"totp_secret": sealed})
+	}
+	return nil
+}
+
+// generateRecoveryCodes returns codes formatted for display and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+
+	for i := range codes {
+		var b strings.Builder
+		for j := 0; j < 10; j++ {
+			if j == 5 {
+				b.WriteByte('-')
+			}
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, nil, err
+			}
+			b.WriteByte(recoveryCodeAlphabet[n.Int64()])
+		}
+		codes[i] = b.String()
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes so codes can be typed loosely
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/config"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/services/admin/repository"
+	"gorm.io/gorm"
+)
+
+// fakeTOTPAdminRepository keeps the TOTP columns of one admin in memory. The
+// embedded interface is nil; calling anything else panics.
+type fakeTOTPAdminRepository struct {
+	repository.AdminRepository
+	lastUsedStep int64
+	updated      map[string]interface{}
+}
+
+func (r *fakeTOTPAdminRepository) MarkTOTPStepUsed(tx *gorm.DB, adminID uint, step int64) (bool, error) {
+	if step <= r.lastUsedStep {
+		return false, nil
+	}
+	r.lastUsedStep = step
+	return true, nil
+}
+
+func (r *fakeTOTPAdminRepository) UpdateColumns(tx *gorm.DB, id uint, columns map[string]interface{}) error {
+	r.updated = columns
+	return nil
+}
+
+// currentTOTPCode is the code an authenticator app shows for secret right now
+func currentTOTPCode(t *testing.T, secret string) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(time.Now().Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+func TestConsumeTOTPStepReplay(t *testing.T) {
+	saved := config.App
+	t.Cleanup(func() { config.App = saved })
+	config.App.TOTPEncryptionKey = "totp-key"
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret: %v", err)
+	}
+	sealed, err := auth.SealTOTPSecret(1, secret)
+	if err != nil {
+		t.Fatalf("SealTOTPSecret: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		stored string
+	}{
+		{"sealed secret", sealed},
+		{"legacy plaintext secret", secret},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeTOTPAdminRepository{}
+			s := &twoFactorService{adminRepo: repo}
+			admin := &models.Admin{ID: 1, TOTPSecret: tt.stored}
+			code := currentTOTPCode(t, secret)
+
+			if err := s.consumeTOTP(nil, admin, code); err != nil {
+				t.Fatalf("first consumeTOTP() = %v", err)
+			}
+			if admin.TOTPLastUsedStep != repo.lastUsedStep {
+				t.Errorf("admin step = %d, repository step = %d", admin.TOTPLastUsedStep, repo.lastUsedStep)
+			}
+			if !auth.TOTPSecretSealed(admin.TOTPSecret) {
+				t.Errorf("secret left unsealed after use")
+			}
+			if tt.stored == secret && repo.updated["totp_secret"] != admin.TOTPSecret {
+				t.Errorf("legacy secret sealed but not saved, got %v", repo.updated)
+			}
+
+			// Replaying the code, or a concurrent request that loaded the
+			// admin before the step was marked, must fail
+			if err := s.consumeTOTP(nil, admin, code); err == nil || err.Error() != "invalid_two_factor_code" {
+				t.Errorf("replayed consumeTOTP() = %v, want invalid_two_factor_code", err)
+			}
+			stale := &models.Admin{ID: 1, TOTPSecret: tt.stored}
+			if err := s.consumeTOTP(nil, stale, code); err == nil || err.Error() != "invalid_two_factor_code" {
+				t.Errorf("concurrent consumeTOTP() = %v, want invalid_two_factor_code", err)
+			}
+		})
+	}
+}
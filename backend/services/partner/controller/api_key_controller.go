@@ -0,0 +1,90 @@
+// services/partner/controller/api_key_controller.go
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/middleware"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/libs/utils"
+	"github.com/jafoor/carhub/services/partner/service"
+)
+
+type APIKeyController struct {
+	service service.APIKeyService
+}
+
+func NewAPIKeyController(s service.APIKeyService) *APIKeyController {
+	return &APIKeyController{service: s}
+}
+
+// CreateAPIKey issues a key for the partner's own software. The key is in
+// the response only this once.
+func (kc *APIKeyController) CreateAPIKey(c *fiber.Ctx) error {
+	partnerID, err := middleware.GetPartnerID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Partner authentication required", nil)
+	}
+
+	var input service.CreateAPIKeyInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+
+	key, err := kc.service.CreateAPIKey(partnerID, input)
+	if err != nil {
+		switch err.Error() {
+		case "invalid_name":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "name is required and must be at most 100 characters", nil)
+		case "invalid_scope":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "Invalid scopes", fiber.Map{"allowed_scopes": models.PartnerAPIKeyScopes})
+		case "invalid_expiry":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "expires_in_days must not be negative", nil)
+		case "api_key_limit_reached":
+			return utils.ErrorResponse(c, http.StatusConflict, "Active API key limit reached, revoke an unused key first", nil)
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create API key", nil)
+		}
+	}
+
+	return utils.SuccessResponse(c, "API key created, store it now as it will not be shown again", key)
+}
+
+func (kc *APIKeyController) ListAPIKeys(c *fiber.Ctx) error {
+	partnerID, err := middleware.GetPartnerID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Partner authentication required", nil)
+	}
+
+	keys, err := kc.service.ListAPIKeys(partnerID)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve API keys", nil)
+	}
+
+	return utils.SuccessResponse(c, "API keys retrieved successfully", keys)
+}
+
+func (kc *APIKeyController) RevokeAPIKey(c *fiber.Ctx) error {
+	partnerID, err := middleware.GetPartnerID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Partner authentication required", nil)
+	}
+
+	keyID, err := strconv.ParseUint(c.Params("keyId"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid key_id", nil)
+	}
+
+	if err := kc.service.RevokeAPIKey(partnerID, uint(keyID)); err != nil {
+		switch err.Error() {
+		case "api_key_not_found":
+			return utils.ErrorResponse(c, http.StatusNotFound, "API key not found", nil)
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke API key", nil)
+		}
+	}
+
+	return utils.SuccessResponse(c, "API key revoked successfully", nil)
+}
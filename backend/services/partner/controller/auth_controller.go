@@ -2,10 +2,13 @@
 package controller
 
 import (
-	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/bruteforce"
+	"github.com/jafoor/carhub/libs/middleware"
 	"github.com/jafoor/carhub/libs/utils"
 	"github.com/jafoor/carhub/services/partner/service"
 )
@@ -18,17 +21,103 @@ func NewAuthController(s service.AuthService) *AuthController {
 	return &AuthController{service: s}
 }
 
+// clientInfo captures the caller's device details for session tracking
+func clientInfo(c *fiber.Ctx) service.ClientInfo {
+	return service.ClientInfo{
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}
+}
+
 func (ac *AuthController) Signin(c *fiber.Ctx) error {
 	var input service.SigninInput
 	if err := c.BodyParser(&input); err != nil {
 		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
 	}
 
-	token, err := ac.service.Signin(input)
+	token, err := ac.service.Signin(input, clientInfo(c))
 	if err != nil {
 		switch err.Error() {
 		case "invalid_credentials", "email_not_verified":
 			return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid credentials", nil)
+		case "too_many_attempts":
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(bruteforce.RetryAfterSeconds(err)))
+			return utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many failed attempts, try again later", nil)
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Login failed", nil)
+		}
+	}
+
+	return utils.SuccessResponse(c, "Login successful", token)
+}
+
+func (ac *AuthController) SigninWithPhone(c *fiber.Ctx) error {
+	var input service.PhoneSigninInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+
+	if input.Phone == "" || input.OTPCode == "" {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "phone and otp_code are required", nil)
+	}
+
+	token, err := ac.service.SigninWithPhone(input, clientInfo(c))
+	if err != nil {
+		switch err.Error() {
+		case "invalid_credentials":
+			return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired OTP", nil)
+		case "otp_attempts_exceeded":
+			return utils.ErrorResponse(c, http.StatusUnauthorized, "Too many incorrect codes, request a new OTP", nil)
+		case "too_many_attempts":
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(bruteforce.RetryAfterSeconds(err)))
+			return utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many failed attempts, try again later", nil)
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Login failed", nil)
+		}
+	}
+
+	return utils.SuccessResponse(c, "Login successful", token)
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+// RequestMagicLink always answers the same way for valid input, so it can't
+// be used to find out which emails have an eligible account. Only a failed
+// account lookup, which doesn't depend on the email, is reported.
+func (ac *AuthController) RequestMagicLink(c *fiber.Ctx) error {
+	var req MagicLinkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+
+	if req.Email == "" {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "email is required", nil)
+	}
+
+	if err := ac.service.RequestMagicLink(req.Email); err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to send sign-in link", nil)
+	}
+
+	return utils.SuccessResponse(c, "If the email belongs to an eligible account, a sign-in link has been sent", nil)
+}
+
+func (ac *AuthController) SigninWithMagicLink(c *fiber.Ctx) error {
+	var input service.MagicLinkSigninInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+
+	if input.Token == "" {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "token is required", nil)
+	}
+
+	token, err := ac.service.SigninWithMagicLink(input, clientInfo(c))
+	if err != nil {
+		switch err.Error() {
+		case "invalid_or_expired_link":
+			return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired sign-in link", nil)
 		default:
 			return utils.ErrorResponse(c, http.StatusInternalServerError, "Login failed", nil)
 		}
@@ -51,12 +140,125 @@ func (ac *AuthController) RefreshToken(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, http.StatusBadRequest, "refresh_token is required", nil)
 	}
 
-	token, err := ac.service.RefreshToken(req.RefreshToken)
-	fmt.Println("Token:", token)
-	fmt.Println("Error:", err)
+	token, err := ac.service.RefreshToken(req.RefreshToken, clientInfo(c))
 	if err != nil {
 		return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired refresh token", nil)
 	}
 
 	return utils.SuccessResponse(c, "Token refreshed", token)
-}
\ No newline at end of file
+}
+
+// Logout revokes the presented access token and ends its session
+func (ac *AuthController) Logout(c *fiber.Ctx) error {
+	claims, err := middleware.GetPartnerClaims(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Partner authentication required", nil)
+	}
+
+	if err := ac.service.Logout(claims); err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "Logout failed", nil)
+	}
+
+	return utils.SuccessResponse(c, "Logged out successfully", nil)
+}
+
+func (ac *AuthController) GetProfile(c *fiber.Ctx) error {
+	partnerID, err := middleware.GetPartnerID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Partner authentication required", nil)
+	}
+
+	profile, err := ac.service.GetProfile(partnerID)
+	if err != nil {
+		switch err.Error() {
+		case "partner_not_found":
+			return utils.ErrorResponse(c, http.StatusNotFound, "Partner not found", nil)
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve profile", nil)
+		}
+	}
+
+	return utils.SuccessResponse(c, "Profile retrieved successfully", profile)
+}
+
+type UpdateProfileRequest struct {
+	FirstName string  `json:"first_name"`
+	LastName  string  `json:"last_name"`
+	Phone     *string `json:"phone,omitempty"`
+}
+
+func (ac *AuthController) UpdateProfile(c *fiber.Ctx) error {
+	partnerID, err := middleware.GetPartnerID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Partner authentication required", nil)
+	}
+
+	var req UpdateProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+
+	input := service.UpdateProfileInput{
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Phone:     req.Phone,
+	}
+
+	profile, err := ac.service.UpdateProfile(partnerID, input)
+	if err != nil {
+		switch err.Error() {
+		case "partner_not_found":
+			return utils.ErrorResponse(c, http.StatusNotFound, "Partner not found", nil)
+		case "phone_already_in_use":
+			return utils.ErrorResponse(c, http.StatusConflict, "Phone number is already in use", nil)
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update profile", nil)
+		}
+	}
+
+	return utils.SuccessResponse(c, "Profile updated successfully", profile)
+}
+
+type UpdatePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+func (ac *AuthController) UpdatePassword(c *fiber.Ctx) error {
+	partnerID, err := middleware.GetPartnerID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Partner authentication required", nil)
+	}
+
+	var req UpdatePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "current_password and new_password are required", nil)
+	}
+
+	input := service.UpdatePasswordInput{
+		CurrentPassword: req.CurrentPassword,
+		NewPassword:     req.NewPassword,
+	}
+
+	if err := ac.service.UpdatePassword(partnerID, input); err != nil {
+		switch err.Error() {
+		case "partner_not_found":
+			return utils.ErrorResponse(c, http.StatusNotFound, "Partner not found", nil)
+		case "invalid_current_password":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "Current password is incorrect", nil)
+		case "too_many_attempts":
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(bruteforce.RetryAfterSeconds(err)))
+			return utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many failed attempts, try again later", nil)
+		case "weak_password":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "Password does not meet requirements", auth.PasswordViolations(err))
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update password", nil)
+		}
+	}
+
+	return utils.SuccessResponse(c, "Password updated successfully", nil)
+}
@@ -3,8 +3,10 @@ package controller
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/bruteforce"
 	"github.com/jafoor/carhub/libs/utils"
 	"github.com/jafoor/carhub/services/partner/service"
 )
@@ -33,10 +35,15 @@ func (oc *OTPController) VerifyOTP(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
 	}
 
-	if err := oc.service.VerifyOTP(req.Email, req.OTPCode); err != nil {
+	if err := oc.service.VerifyOTP(req.Email, req.OTPCode, c.IP()); err != nil {
 		switch err.Error() {
 		case "partner_not_found", "invalid_or_expired_otp":
 			return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired OTP", nil)
+		case "otp_attempts_exceeded":
+			return utils.ErrorResponse(c, http.StatusUnauthorized, "Too many incorrect codes, request a new OTP", nil)
+		case "too_many_attempts":
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(bruteforce.RetryAfterSeconds(err)))
+			return utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many failed attempts, try again later", nil)
 		case "database_error":
 			return utils.ErrorResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
 		default:
@@ -60,9 +67,11 @@ func (oc *OTPController) ResendOTP(c *fiber.Ctx) error {
 			return utils.ErrorResponse(c, http.StatusNotFound, "Partner not found", nil)
 		case "email_already_verified":
 			return utils.ErrorResponse(c, http.StatusBadRequest, "Email already verified", nil)
-		case "otp_already_sent_wait_for_expiry":
-			return utils.ErrorResponse(c, http.StatusTooManyRequests, "Please wait until current OTP expires", nil)
-		case "database_error", "otp_generation_failed":
+		case "otp_resend_cooldown":
+			return utils.ErrorResponse(c, http.StatusTooManyRequests, "Please wait before requesting another OTP", nil)
+		case "otp_daily_limit_reached":
+			return utils.ErrorResponse(c, http.StatusTooManyRequests, "Daily OTP limit reached, try again tomorrow", nil)
+		case "database_error", "otp_generation_failed", "otp_delivery_failed":
 			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to send OTP", nil)
 		default:
 			return utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
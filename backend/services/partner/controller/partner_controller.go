@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/auth"
 	"github.com/jafoor/carhub/libs/utils"
 	"github.com/jafoor/carhub/services/partner/service"
 )
@@ -26,6 +27,8 @@ func (pc *PartnerController) Signup(c *fiber.Ctx) error {
 	resp, err := pc.service.Signup(input)
 	if err != nil {
 		switch err.Error() {
+		case "weak_password":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "Password does not meet requirements", auth.PasswordViolations(err))
 		case "email_already_registered":
 			return utils.ErrorResponse(c, http.StatusConflict, "Email already registered.", nil)
 		case "signup_failed", "database_error", "password_hash_failed":
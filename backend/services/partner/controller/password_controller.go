@@ -0,0 +1,77 @@
+// services/partner/controller/password_controller.go
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/bruteforce"
+	"github.com/jafoor/carhub/libs/utils"
+	"github.com/jafoor/carhub/services/partner/service"
+)
+
+type PasswordController struct {
+	service service.PasswordService
+}
+
+func NewPasswordController(s service.PasswordService) *PasswordController {
+	return &PasswordController{service: s}
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ForgotPassword sends a password reset OTP to the partner's email. The
+// answer is the same whether or not the email is registered.
+func (pc *PasswordController) ForgotPassword(c *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+
+	if req.Email == "" {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "email is required", nil)
+	}
+
+	if err := pc.service.RequestPasswordReset(req.Email, c.IP()); err != nil {
+		switch err.Error() {
+		case "too_many_attempts":
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(bruteforce.RetryAfterSeconds(err)))
+			return utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many requests, try again later", nil)
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to send OTP", nil)
+		}
+	}
+
+	return utils.SuccessResponse(c, "If the email is registered, a reset code has been sent", nil)
+}
+
+// ResetPassword sets a new password using the reset OTP
+func (pc *PasswordController) ResetPassword(c *fiber.Ctx) error {
+	var input service.ResetPasswordInput
+	if err := c.BodyParser(&input); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+
+	if input.Email == "" || input.OTPCode == "" || input.NewPassword == "" {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "email, otp_code and new_password are required", nil)
+	}
+
+	if err := pc.service.ResetPassword(input); err != nil {
+		switch err.Error() {
+		case "invalid_or_expired_otp":
+			return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired OTP", nil)
+		case "otp_attempts_exceeded":
+			return utils.ErrorResponse(c, http.StatusUnauthorized, "Too many incorrect codes, request a new OTP", nil)
+		case "weak_password":
+			return utils.ErrorResponse(c, http.StatusBadRequest, "Password does not meet requirements", auth.PasswordViolations(err))
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to reset password", nil)
+		}
+	}
+
+	return utils.SuccessResponse(c, "Password reset successfully", nil)
+}
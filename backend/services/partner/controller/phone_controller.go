@@ -0,0 +1,107 @@
+// services/partner/controller/phone_controller.go
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/bruteforce"
+	"github.com/jafoor/carhub/libs/middleware"
+	"github.com/jafoor/carhub/libs/utils"
+	"github.com/jafoor/carhub/services/partner/service"
+)
+
+type PhoneController struct {
+	service service.PhoneService
+}
+
+func NewPhoneController(s service.PhoneService) *PhoneController {
+	return &PhoneController{service: s}
+}
+
+type ConfirmPhoneRequest struct {
+	OTPCode string `json:"otp_code"`
+}
+
+type PhoneSigninOTPRequest struct {
+	Phone string `json:"phone"`
+}
+
+// phoneOTPError maps the errors shared by the phone OTP endpoints
+func phoneOTPError(c *fiber.Ctx, err error, fallback string) error {
+	switch err.Error() {
+	case "partner_not_found":
+		return utils.ErrorResponse(c, http.StatusNotFound, "Partner not found", nil)
+	case "phone_not_set":
+		return utils.ErrorResponse(c, http.StatusBadRequest, "Add a phone number to your profile first", nil)
+	case "phone_already_verified":
+		return utils.ErrorResponse(c, http.StatusBadRequest, "Phone already verified", nil)
+	case "invalid_or_expired_otp":
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired OTP", nil)
+	case "otp_attempts_exceeded":
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Too many incorrect codes, request a new OTP", nil)
+	case "too_many_attempts":
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(bruteforce.RetryAfterSeconds(err)))
+		return utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many failed attempts, try again later", nil)
+	case "otp_resend_cooldown":
+		return utils.ErrorResponse(c, http.StatusTooManyRequests, "Please wait before requesting another OTP", nil)
+	case "otp_daily_limit_reached":
+		return utils.ErrorResponse(c, http.StatusTooManyRequests, "Daily OTP limit reached, try again tomorrow", nil)
+	default:
+		return utils.ErrorResponse(c, http.StatusInternalServerError, fallback, nil)
+	}
+}
+
+// RequestVerification texts a verification code to the partner's phone
+func (pc *PhoneController) RequestVerification(c *fiber.Ctx) error {
+	partnerID, err := middleware.GetPartnerID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Partner authentication required", nil)
+	}
+
+	if err := pc.service.RequestVerification(partnerID); err != nil {
+		return phoneOTPError(c, err, "Failed to send OTP")
+	}
+
+	return utils.SuccessResponse(c, "Verification code sent", nil)
+}
+
+func (pc *PhoneController) ConfirmVerification(c *fiber.Ctx) error {
+	partnerID, err := middleware.GetPartnerID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Partner authentication required", nil)
+	}
+
+	var req ConfirmPhoneRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+	if req.OTPCode == "" {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "otp_code is required", nil)
+	}
+
+	if err := pc.service.ConfirmVerification(partnerID, req.OTPCode, c.IP()); err != nil {
+		return phoneOTPError(c, err, "Failed to verify phone")
+	}
+
+	return utils.SuccessResponse(c, "Phone verified successfully", nil)
+}
+
+// RequestSigninOTP answers the same way for every number, registered or not.
+// Only a failed partner lookup, which doesn't depend on the number, is reported.
+func (pc *PhoneController) RequestSigninOTP(c *fiber.Ctx) error {
+	var req PhoneSigninOTPRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid request", nil)
+	}
+	if req.Phone == "" {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "phone is required", nil)
+	}
+
+	if err := pc.service.RequestSigninOTP(req.Phone); err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to send OTP", nil)
+	}
+
+	return utils.SuccessResponse(c, "If the number is registered and verified, a sign-in code has been sent", nil)
+}
@@ -0,0 +1,73 @@
+// services/partner/controller/session_controller.go
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/middleware"
+	"github.com/jafoor/carhub/libs/utils"
+	"github.com/jafoor/carhub/services/partner/service"
+)
+
+type SessionController struct {
+	service service.SessionService
+}
+
+func NewSessionController(s service.SessionService) *SessionController {
+	return &SessionController{service: s}
+}
+
+// ListSessions returns the signed-in partner's devices
+func (sc *SessionController) ListSessions(c *fiber.Ctx) error {
+	claims, err := middleware.GetPartnerClaims(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Partner authentication required", nil)
+	}
+
+	sessions, err := sc.service.ListSessions(claims.PartnerID, claims.SessionID)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve sessions", nil)
+	}
+
+	return utils.SuccessResponse(c, "Sessions retrieved successfully", sessions)
+}
+
+// RevokeSession signs a single device out
+func (sc *SessionController) RevokeSession(c *fiber.Ctx) error {
+	partnerID, err := middleware.GetPartnerID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Partner authentication required", nil)
+	}
+
+	sessionID, err := strconv.ParseUint(c.Params("sessionId"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusBadRequest, "invalid session_id", nil)
+	}
+
+	if err := sc.service.RevokeSession(partnerID, uint(sessionID)); err != nil {
+		switch err.Error() {
+		case "session_not_found":
+			return utils.ErrorResponse(c, http.StatusNotFound, "Session not found", nil)
+		default:
+			return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke session", nil)
+		}
+	}
+
+	return utils.SuccessResponse(c, "Session revoked successfully", nil)
+}
+
+// RevokeAllSessions signs every device out
+func (sc *SessionController) RevokeAllSessions(c *fiber.Ctx) error {
+	partnerID, err := middleware.GetPartnerID(c)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Partner authentication required", nil)
+	}
+
+	if err := sc.service.RevokeAllSessions(partnerID); err != nil {
+		return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke sessions", nil)
+	}
+
+	return utils.SuccessResponse(c, "All sessions revoked successfully", nil)
+}
@@ -14,6 +14,8 @@ type PartnerRepository interface {
 	FindByEmail(email string) (*models.Partner, error)
 	Update(tx *gorm.DB, partner *models.Partner) error
 	FindByID(id uint) (*models.Partner, error)
+	FindByPhone(phone string) (*models.Partner, error)
+	UpgradePasswordHash(tx *gorm.DB, id uint, oldHash, newHash string) error
 }
 
 type partnerRepository struct{}
@@ -53,4 +55,24 @@ func (r *partnerRepository) FindByID(id uint) (*models.Partner, error) {
 		return nil, err
 	}
 	return &p, nil
-}
\ No newline at end of file
+}
+
+func (r *partnerRepository) FindByPhone(phone string) (*models.Partner, error) {
+	var p models.Partner
+	err := database.ReadDB.Where("phone = ?", phone).First(&p).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// UpgradePasswordHash swaps in a rehash of the same password. It only applies
+// while oldHash is still current, so a concurrent password change wins.
+func (r *partnerRepository) UpgradePasswordHash(tx *gorm.DB, id uint, oldHash, newHash string) error {
+	return tx.Model(&models.Partner{}).
+		Where("id = ? AND password_hash = ?", id, oldHash).
+		Update("password_hash", newHash).Error
+}
@@ -3,6 +3,11 @@ package routes
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/bruteforce"
+	"github.com/jafoor/carhub/libs/middleware"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/libs/notifier"
 	otpRepository "github.com/jafoor/carhub/libs/repository"
 	"github.com/jafoor/carhub/services/partner/controller"
 	"github.com/jafoor/carhub/services/partner/repository"
@@ -16,23 +21,71 @@ func RegisterPartnerRoutes(app *fiber.App) {
 	partnerRepo := repository.NewPartnerRepository()
 	otpRepo := otpRepository.NewOTPRepository()
 	refreshTokenRepo := otpRepository.NewPartnerRefreshTokenRepository()
+	notify := notifier.NewFromConfig()
+	revocations := auth.NewRevocationStore()
+	attempts := bruteforce.NewStoreFromConfig()
+	passwords := auth.NewPasswordPolicy(otpRepository.NewPasswordHistoryRepository())
 
-	partnerService := service.NewPartnerService(partnerRepo, otpRepo)
+	partnerService := service.NewPartnerService(partnerRepo, otpRepo, notify, passwords)
 	partnerCtrl := controller.NewPartnerController(partnerService)
 
 	// Public routes
 	v1.Post("/partners/signup", partnerCtrl.Signup)
 
 	// OTP endpoints
-	otpService := service.NewOTPService(partnerRepo, otpRepo)
+	otpService := service.NewOTPService(partnerRepo, otpRepo, notify, bruteforce.NewGuard("partner_verify_otp", attempts))
 	otpCtrl := controller.NewOTPController(otpService)
 	v1.Post("/partners/verify-otp", otpCtrl.VerifyOTP)
 	v1.Post("/partners/resend-otp", otpCtrl.ResendOTP)
 
+	// Password reset endpoints
+	passwordService := service.NewPasswordService(partnerRepo, otpRepo, refreshTokenRepo, notify, passwords, bruteforce.NewGuard("partner_forgot_password", attempts))
+	passwordCtrl := controller.NewPasswordController(passwordService)
+	v1.Post("/partners/forgot-password", passwordCtrl.ForgotPassword)
+	v1.Post("/partners/reset-password", passwordCtrl.ResetPassword)
+
 	// Add to RegisterPartnerRoutes
-	authService := service.NewAuthService(partnerRepo, refreshTokenRepo)
+	authService := service.NewAuthService(partnerRepo, refreshTokenRepo, otpRepo, revocations, bruteforce.NewGuard("partner_signin", attempts), bruteforce.NewGuard("partner_update_password", attempts), passwords, notify)
 	authCtrl := controller.NewAuthController(authService)
 
 	v1.Post("/partners/signin", authCtrl.Signin)
 	v1.Post("/partners/refresh", authCtrl.RefreshToken)
-}
\ No newline at end of file
+
+	// Phone sign-in (verified phones only)
+	phoneService := service.NewPhoneService(partnerRepo, otpRepo, notify, bruteforce.NewGuard("partner_verify_phone", attempts))
+	phoneCtrl := controller.NewPhoneController(phoneService)
+	v1.Post("/partners/signin/phone/otp", phoneCtrl.RequestSigninOTP)
+	v1.Post("/partners/signin/phone", authCtrl.SigninWithPhone)
+
+	// Magic-link sign-in (verified, non-suspended partners only)
+	v1.Post("/partners/signin/magic-link", authCtrl.RequestMagicLink)
+	v1.Post("/partners/signin/magic-link/verify", authCtrl.SigninWithMagicLink)
+
+	// Logout, profile and session management (partner access token or API key required).
+	// API keys only reach routes guarded by RequirePartnerScope.
+	sessionService := service.NewSessionService(refreshTokenRepo, revocations)
+	sessionCtrl := controller.NewSessionController(sessionService)
+	partnerGroup := v1.Group("/partners", middleware.RequirePartnerAuth())
+	session := middleware.RequirePartnerSession()
+	noImpersonation := middleware.BlockImpersonation()
+	partnerGroup.Post("/logout", session, authCtrl.Logout)
+
+	// Self-service profile
+	partnerGroup.Get("/me", middleware.RequirePartnerScope(models.APIKeyScopeProfileRead), authCtrl.GetProfile)
+	partnerGroup.Put("/me", middleware.RequirePartnerScope(models.APIKeyScopeProfileWrite), authCtrl.UpdateProfile)
+	partnerGroup.Put("/me/password", session, noImpersonation, authCtrl.UpdatePassword)
+	partnerGroup.Post("/me/phone/verify", session, phoneCtrl.RequestVerification)
+	partnerGroup.Post("/me/phone/confirm", session, phoneCtrl.ConfirmVerification)
+
+	// Sessions
+	partnerGroup.Get("/sessions", session, sessionCtrl.ListSessions)
+	partnerGroup.Delete("/sessions", session, noImpersonation, sessionCtrl.RevokeAllSessions)
+	partnerGroup.Delete("/sessions/:sessionId", session, noImpersonation, sessionCtrl.RevokeSession)
+
+	// API keys for the partner's own integrations
+	apiKeyService := service.NewAPIKeyService(otpRepository.NewPartnerAPIKeyRepository())
+	apiKeyCtrl := controller.NewAPIKeyController(apiKeyService)
+	partnerGroup.Get("/me/api-keys", session, apiKeyCtrl.ListAPIKeys)
+	partnerGroup.Post("/me/api-keys", session, noImpersonation, apiKeyCtrl.CreateAPIKey)
+	partnerGroup.Delete("/me/api-keys/:keyId", session, noImpersonation, apiKeyCtrl.RevokeAPIKey)
+}
@@ -0,0 +1,125 @@
+// services/partner/service/api_key_service.go
+package service
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/config"
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/models"
+	otpRepo "github.com/jafoor/carhub/libs/repository"
+	"gorm.io/gorm"
+)
+
+type CreateAPIKeyInput struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty"` // 0 never expires
+}
+
+// CreatedAPIKeyResponse carries the full key, which is never shown again
+type CreatedAPIKeyResponse struct {
+	Key string `json:"key"`
+	*models.PartnerAPIKey
+}
+
+type APIKeyService interface {
+	CreateAPIKey(partnerID uint, input CreateAPIKeyInput) (*CreatedAPIKeyResponse, error)
+	ListAPIKeys(partnerID uint) ([]models.PartnerAPIKey, error)
+	RevokeAPIKey(partnerID, keyID uint) error
+}
+
+type apiKeyService struct {
+	apiKeyRepo otpRepo.PartnerAPIKeyRepository
+}
+
+func NewAPIKeyService(apiKeyRepo otpRepo.PartnerAPIKeyRepository) APIKeyService {
+	return &apiKeyService{apiKeyRepo: apiKeyRepo}
+}
+
+func (s *apiKeyService) CreateAPIKey(partnerID uint, input CreateAPIKeyInput) (*CreatedAPIKeyResponse, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 100 {
+		return nil, errors.New("invalid_name")
+	}
+
+	if len(input.Scopes) == 0 {
+		return nil, errors.New("invalid_scope")
+	}
+	scopes := []string{}
+	for _, scope := range input.Scopes {
+		if !slices.Contains(models.PartnerAPIKeyScopes, scope) {
+			return nil, errors.New("invalid_scope")
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if input.ExpiresInDays < 0 {
+		return nil, errors.New("invalid_expiry")
+	}
+
+	active, err := s.apiKeyRepo.CountActiveByPartnerID(partnerID)
+	if err != nil {
+		return nil, errors.New("failed_to_create_api_key")
+	}
+	if config.App.PartnerAPIKeyLimit > 0 && active >= int64(config.App.PartnerAPIKeyLimit) {
+		return nil, errors.New("api_key_limit_reached")
+	}
+
+	key, prefix, hash, err := auth.GeneratePartnerAPIKey()
+	if err != nil {
+		return nil, errors.New("failed_to_create_api_key")
+	}
+
+	apiKey := &models.PartnerAPIKey{
+		PartnerID: partnerID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    scopes,
+	}
+	if input.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, input.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		return s.apiKeyRepo.Create(tx, apiKey)
+	})
+	if err != nil {
+		return nil, errors.New("failed_to_create_api_key")
+	}
+	apiKey.State = apiKey.CurrentState()
+
+	return &CreatedAPIKeyResponse{Key: key, PartnerAPIKey: apiKey}, nil
+}
+
+func (s *apiKeyService) ListAPIKeys(partnerID uint) ([]models.PartnerAPIKey, error) {
+	keys, err := s.apiKeyRepo.ListByPartnerID(partnerID)
+	if err != nil {
+		return nil, errors.New("failed_to_list_api_keys")
+	}
+	return keys, nil
+}
+
+func (s *apiKeyService) RevokeAPIKey(partnerID, keyID uint) error {
+	var found bool
+	err := database.ExecuteTransaction(func(tx *gorm.DB) error {
+		var err error
+		found, err = s.apiKeyRepo.Revoke(tx, partnerID, keyID, nil)
+		return err
+	})
+	if err != nil {
+		return errors.New("failed_to_revoke_api_key")
+	}
+	if !found {
+		return errors.New("api_key_not_found")
+	}
+	return nil
+}
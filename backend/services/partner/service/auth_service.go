@@ -5,22 +5,49 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 
+	"github.com/jafoor/carhub/libs/audit"
 	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/bruteforce"
 	"github.com/jafoor/carhub/libs/config"
 	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/logger"
 	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/libs/notifier"
 	otpRepo "github.com/jafoor/carhub/libs/repository"
 	"github.com/jafoor/carhub/services/partner/repository"
 	"gorm.io/gorm"
 )
 
 type SigninInput struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	DeviceName string `json:"device_name"`
+}
+
+// PhoneSigninInput signs in with a verified phone and a phone_signin OTP
+type PhoneSigninInput struct {
+	Phone      string `json:"phone"`
+	OTPCode    string `json:"otp_code"`
+	DeviceName string `json:"device_name"`
+}
+
+// MagicLinkSigninInput exchanges the token from a sign-in link for a session
+type MagicLinkSigninInput struct {
+	Token      string `json:"token"`
+	DeviceName string `json:"device_name"`
+}
+
+// ClientInfo describes the device a session is used from
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
 }
 
 type TokenResponse struct {
@@ -29,23 +56,71 @@ type TokenResponse struct {
 	ExpiresIn    int64  `json:"expires_in"` // seconds
 }
 
+type PartnerProfileResponse struct {
+	ID            uint                 `json:"id"`
+	FirstName     string               `json:"first_name"`
+	LastName      string               `json:"last_name"`
+	Email         string               `json:"email"`
+	Phone         *string              `json:"phone,omitempty"`
+	Status        models.PartnerStatus `json:"status"`
+	EmailVerified bool                 `json:"email_verified"`
+	PhoneVerified bool                 `json:"phone_verified"`
+	LastLoginAt   *time.Time           `json:"last_login_at,omitempty"`
+}
+
+type UpdateProfileInput struct {
+	FirstName string  `json:"first_name"`
+	LastName  string  `json:"last_name"`
+	Phone     *string `json:"phone,omitempty"` // an empty string removes the phone
+}
+
+type UpdatePasswordInput struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
 type AuthService interface {
-	Signin(req SigninInput) (*TokenResponse, error)
-	RefreshToken(refreshToken string) (*TokenResponse, error)
+	Signin(req SigninInput, client ClientInfo) (*TokenResponse, error)
+	SigninWithPhone(req PhoneSigninInput, client ClientInfo) (*TokenResponse, error)
+	RequestMagicLink(email string) error
+	SigninWithMagicLink(req MagicLinkSigninInput, client ClientInfo) (*TokenResponse, error)
+	RefreshToken(refreshToken string, client ClientInfo) (*TokenResponse, error)
+	Logout(claims *auth.PartnerClaims) error
+	GetProfile(partnerID uint) (*PartnerProfileResponse, error)
+	UpdateProfile(partnerID uint, input UpdateProfileInput) (*PartnerProfileResponse, error)
+	UpdatePassword(partnerID uint, input UpdatePasswordInput) error
 }
 
 type authService struct {
-	partnerRepo        repository.PartnerRepository
-	refreshTokenRepo   otpRepo.PartnerRefreshTokenRepository
+	partnerRepo      repository.PartnerRepository
+	refreshTokenRepo otpRepo.PartnerRefreshTokenRepository
+	otpRepo          otpRepo.OTPRepository
+	revocations      auth.RevocationStore
+	signinGuard      *bruteforce.Guard
+	passwordGuard    *bruteforce.Guard
+	passwords        auth.PasswordPolicy
+	notifier         notifier.Notifier
 }
 
 func NewAuthService(
 	partnerRepo repository.PartnerRepository,
 	refreshTokenRepo otpRepo.PartnerRefreshTokenRepository,
+	otpRepository otpRepo.OTPRepository,
+	revocations auth.RevocationStore,
+	signinGuard *bruteforce.Guard,
+	passwordGuard *bruteforce.Guard,
+	passwords auth.PasswordPolicy,
+	notify notifier.Notifier,
 ) AuthService {
 	return &authService{
 		partnerRepo:      partnerRepo,
 		refreshTokenRepo: refreshTokenRepo,
+		otpRepo:          otpRepository,
+		revocations:      revocations,
+		signinGuard:      signinGuard,
+		passwordGuard:    passwordGuard,
+		passwords:        passwords,
+		notifier:         notify,
 	}
 }
 
@@ -55,11 +130,25 @@ func hashToken(token string) (string, error) {
 	return hex.EncodeToString(hash[:]), nil
 }
 
+// truncate keeps client supplied values within their column sizes
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
+}
+
 // Signin handles partner login with email verification check
-func (s *authService) Signin(req SigninInput) (*TokenResponse, error) {
+func (s *authService) Signin(req SigninInput, client ClientInfo) (*TokenResponse, error) {
+	// Refuse locked-out accounts and IPs before touching the password
+	if err := s.signinGuard.Check(req.Email, client.IPAddress); err != nil {
+		return nil, err
+	}
+
 	// Find partner by email
 	partner, err := s.partnerRepo.FindByEmail(req.Email)
 	if err != nil || partner == nil {
+		s.signinGuard.Fail(req.Email, client.IPAddress)
 		return nil, errors.New("invalid_credentials")
 	}
 
@@ -69,27 +158,153 @@ func (s *authService) Signin(req SigninInput) (*TokenResponse, error) {
 	}
 
 	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(partner.PasswordHash), []byte(req.Password)); err != nil {
+	ok, needsRehash := auth.VerifyPassword(partner.PasswordHash, req.Password)
+	if !ok {
+		s.signinGuard.Fail(req.Email, client.IPAddress)
 		return nil, errors.New("invalid_credentials")
 	}
+	s.signinGuard.Succeed(req.Email)
 
-	var resp *TokenResponse
+	if needsRehash {
+		s.upgradePasswordHash(partner, req.Password)
+	}
 
-	// Execute in transaction
-	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
-		// 🔥 CRITICAL: Delete any existing refresh token first
-		if err := s.refreshTokenRepo.DeleteByPartnerID(tx, partner.ID); err != nil {
-			return err
+	return s.startSession(partner, req.DeviceName, client)
+}
+
+// SigninWithPhone signs in with a phone_signin OTP sent to a verified phone.
+// It shares the password sign-in lockout, counted against the phone number.
+func (s *authService) SigninWithPhone(req PhoneSigninInput, client ClientInfo) (*TokenResponse, error) {
+	phone := strings.TrimSpace(req.Phone)
+
+	if err := s.signinGuard.Check(phone, client.IPAddress); err != nil {
+		return nil, err
+	}
+
+	partner, err := s.partnerRepo.FindByPhone(phone)
+	if err != nil {
+		return nil, errors.New("login_failed")
+	}
+	if partner == nil || !partner.PhoneVerified || !partner.EmailVerified {
+		s.signinGuard.Fail(phone, client.IPAddress)
+		return nil, errors.New("invalid_credentials")
+	}
+
+	otp, err := auth.VerifyOTP(s.otpRepo, partner.ID, models.OwnerTypePartner, models.OTPPurposePhoneSignin, req.OTPCode)
+	if err != nil {
+		switch err.Error() {
+		case "database_error":
+			return nil, errors.New("login_failed")
+		case "otp_attempts_exceeded":
+			s.signinGuard.Fail(phone, client.IPAddress)
+			return nil, err
+		default:
+			s.signinGuard.Fail(phone, client.IPAddress)
+			return nil, errors.New("invalid_credentials")
 		}
+	}
+	s.signinGuard.Succeed(phone)
+
+	// Of two requests racing with the same code only one may get a session
+	var consumed bool
+	if err := database.ExecuteTransaction(func(tx *gorm.DB) error {
+		consumed, err = s.otpRepo.Consume(tx, otp.ID)
+		return err
+	}); err != nil {
+		return nil, errors.New("login_failed")
+	}
+	if !consumed {
+		return nil, errors.New("invalid_credentials")
+	}
 
+	return s.startSession(partner, req.DeviceName, client)
+}
+
+// magicLinkEligible reports whether a partner may sign in by email link
+func magicLinkEligible(partner *models.Partner) bool {
+	return partner.EmailVerified && partner.Status != models.StatusSuspended
+}
+
+// RequestMagicLink emails a single-use sign-in link. Unknown and ineligible
+// addresses get the same response so accounts can't be probed; link limits
+// and delivery failures only happen to eligible ones, so they are logged and
+// not returned.
+func (s *authService) RequestMagicLink(email string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	partner, err := s.partnerRepo.FindByEmail(email)
+	if err != nil {
+		return errors.New("database_error")
+	}
+	if partner == nil || !magicLinkEligible(partner) {
+		return nil
+	}
+
+	otp, token, err := auth.IssuePartnerMagicLink(s.otpRepo, partner)
+	if err != nil {
+		logger.Warn().Err(err).Uint("partner_id", partner.ID).Msg("Magic link not issued")
+		return nil
+	}
+
+	err = s.notifier.Notify(notifier.ChannelEmail, partner.Email, notifier.TemplateMagicLink, map[string]interface{}{
+		"FirstName":        partner.FirstName,
+		"Link":             config.App.PartnerMagicLinkURL + "?token=" + url.QueryEscape(token),
+		"ExpiresInMinutes": int(time.Until(otp.ExpiresAt).Round(time.Minute).Minutes()),
+	})
+	if err != nil {
+		logger.Error().Err(err).Uint("partner_id", partner.ID).Msg("Magic link delivery failed")
+
+		// An undelivered link must not stay redeemable
+		if err := database.ExecuteTransaction(func(tx *gorm.DB) error {
+			return s.otpRepo.MarkAsUsed(tx, otp.ID)
+		}); err != nil {
+			logger.Error().Err(err).Uint("otp_id", otp.ID).Msg("Failed to invalidate undelivered magic link")
+		}
+	}
+
+	return nil
+}
+
+// SigninWithMagicLink exchanges a sign-in link's token for a new session. The
+// token is burnt first, and eligibility is checked again in case the partner
+// was suspended after the link was sent.
+func (s *authService) SigninWithMagicLink(req MagicLinkSigninInput, client ClientInfo) (*TokenResponse, error) {
+	partnerID, err := auth.RedeemPartnerMagicLink(s.otpRepo, req.Token)
+	if err != nil {
+		if err.Error() == "database_error" {
+			return nil, errors.New("login_failed")
+		}
+		return nil, err
+	}
+
+	partner, err := s.partnerRepo.FindByID(partnerID)
+	if err != nil {
+		return nil, errors.New("login_failed")
+	}
+	if partner == nil || !magicLinkEligible(partner) {
+		return nil, errors.New("invalid_or_expired_link")
+	}
+
+	return s.startSession(partner, req.DeviceName, client)
+}
+
+// startSession issues the token pair for a new session; every sign-in opens
+// one and other devices stay signed in
+func (s *authService) startSession(partner *models.Partner, deviceName string, client ClientInfo) (*TokenResponse, error) {
+	var resp *TokenResponse
+
+	sessionID := uuid.NewString()
+
+	// Execute in transaction
+	err := database.ExecuteTransaction(func(tx *gorm.DB) error {
 		// Generate new access token
-		accessToken, err := auth.GeneratePartnerAccessToken(partner)
+		accessToken, err := auth.GeneratePartnerAccessToken(partner, sessionID)
 		if err != nil {
 			return err
 		}
 
 		// Generate new refresh token
-		refreshToken, err := auth.GeneratePartnerRefreshToken(partner)
+		refreshToken, err := auth.GeneratePartnerRefreshToken(partner, sessionID, 0)
 		if err != nil {
 			return err
 		}
@@ -100,11 +315,16 @@ func (s *authService) Signin(req SigninInput) (*TokenResponse, error) {
 			return err
 		}
 
-		// Create new refresh token record
+		// Create new session record
 		rt := &models.PartnerRefreshToken{
-			PartnerID: partner.ID,
-			TokenHash: refreshHash,
-			ExpiresAt: time.Now().Add(time.Hour * 24 * time.Duration(config.App.RefreshTokenTTL)),
+			PartnerID:  partner.ID,
+			SessionID:  sessionID,
+			TokenHash:  refreshHash,
+			DeviceName: truncate(deviceName, 100),
+			IPAddress:  truncate(client.IPAddress, 45),
+			UserAgent:  truncate(client.UserAgent, 255),
+			LastUsedAt: time.Now(),
+			ExpiresAt:  time.Now().Add(time.Hour * 24 * time.Duration(config.App.RefreshTokenTTL)),
 		}
 		if err := s.refreshTokenRepo.Create(tx, rt); err != nil {
 			return err
@@ -125,11 +345,33 @@ func (s *authService) Signin(req SigninInput) (*TokenResponse, error) {
 	return resp, nil
 }
 
+var errRefreshTokenReused = errors.New("refresh_token_reused")
+
+// revokeFamily ends a session whose refresh token was replayed and records it
+func (s *authService) revokeFamily(stored *models.PartnerRefreshToken, client ClientInfo, presentedGeneration int) {
+	if err := s.refreshTokenRepo.DeleteBySessionID(database.WriteDB, stored.SessionID); err != nil {
+		logger.Error().Err(err).Str("session_id", stored.SessionID).Msg("Failed to revoke refresh token family")
+	}
+
+	audit.LogSecurityEvent(audit.SecurityEvent{
+		EventType: models.SecurityEventRefreshTokenReuse,
+		OwnerType: models.OwnerTypePartner,
+		OwnerID:   stored.PartnerID,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Details: map[string]interface{}{
+			"session_id":           stored.SessionID,
+			"presented_generation": presentedGeneration,
+			"current_generation":   stored.Generation,
+		},
+	})
+}
+
 // RefreshToken handles token rotation
-func (s *authService) RefreshToken(refreshToken string) (*TokenResponse, error) {
+func (s *authService) RefreshToken(refreshToken string, client ClientInfo) (*TokenResponse, error) {
 	// Verify and parse refresh token
 	claims, err := auth.VerifyPartnerToken(refreshToken)
-	if err != nil {
+	if err != nil || claims.TokenType != "refresh" || claims.SessionID == "" {
 		return nil, errors.New("invalid_refresh_token")
 	}
 
@@ -139,12 +381,19 @@ func (s *authService) RefreshToken(refreshToken string) (*TokenResponse, error)
 		return nil, errors.New("invalid_refresh_token")
 	}
 
-	// Find stored refresh token by partner ID
-	stored, err := s.refreshTokenRepo.FindByPartnerID(claims.PartnerID)
-	if err != nil || stored == nil {
+	// Find the session this token belongs to
+	stored, err := s.refreshTokenRepo.FindBySessionID(claims.SessionID)
+	if err != nil || stored == nil || stored.PartnerID != claims.PartnerID {
 		return nil, errors.New("invalid_refresh_token")
 	}
 
+	// A token from an older generation has already been rotated, so whoever
+	// presents it is replaying a copy: revoke the whole family.
+	if claims.Generation < stored.Generation {
+		s.revokeFamily(stored, client, claims.Generation)
+		return nil, errors.New("refresh_token_reused")
+	}
+
 	// Verify hash matches
 	if refreshHash != stored.TokenHash {
 		return nil, errors.New("invalid_refresh_token")
@@ -162,20 +411,16 @@ func (s *authService) RefreshToken(refreshToken string) (*TokenResponse, error)
 	}
 
 	var resp *TokenResponse
+	previousGeneration := stored.Generation
 
 	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
-		// 🔥 Delete old refresh token
-		if err := s.refreshTokenRepo.DeleteByPartnerID(tx, partner.ID); err != nil {
-			return err
-		}
-
-		// Generate new tokens
-		newAccessToken, err := auth.GeneratePartnerAccessToken(partner)
+		// Generate new tokens for the same session
+		newAccessToken, err := auth.GeneratePartnerAccessToken(partner, stored.SessionID)
 		if err != nil {
 			return err
 		}
 
-		newRefreshToken, err := auth.GeneratePartnerRefreshToken(partner)
+		newRefreshToken, err := auth.GeneratePartnerRefreshToken(partner, stored.SessionID, previousGeneration+1)
 		if err != nil {
 			return err
 		}
@@ -186,15 +431,20 @@ func (s *authService) RefreshToken(refreshToken string) (*TokenResponse, error)
 			return err
 		}
 
-		// Save new refresh token
-		newRT := &models.PartnerRefreshToken{
-			PartnerID: partner.ID,
-			TokenHash: newRefreshHash,
-			ExpiresAt: time.Now().Add(time.Hour * 24 * time.Duration(config.App.RefreshTokenTTL)),
-		}
-		if err := s.refreshTokenRepo.Create(tx, newRT); err != nil {
+		// Rotate only this session's token
+		stored.TokenHash = newRefreshHash
+		stored.Generation = previousGeneration + 1
+		stored.IPAddress = truncate(client.IPAddress, 45)
+		stored.UserAgent = truncate(client.UserAgent, 255)
+		stored.LastUsedAt = time.Now()
+		stored.ExpiresAt = time.Now().Add(time.Hour * 24 * time.Duration(config.App.RefreshTokenTTL))
+		rotated, err := s.refreshTokenRepo.Rotate(tx, stored, previousGeneration)
+		if err != nil {
 			return err
 		}
+		if !rotated {
+			return errRefreshTokenReused
+		}
 
 		resp = &TokenResponse{
 			AccessToken:  newAccessToken,
@@ -204,9 +454,192 @@ func (s *authService) RefreshToken(refreshToken string) (*TokenResponse, error)
 		return nil
 	})
 
+	if errors.Is(err, errRefreshTokenReused) {
+		// The same token was rotated concurrently, so it was used twice
+		s.revokeFamily(stored, client, claims.Generation)
+		return nil, errRefreshTokenReused
+	}
 	if err != nil {
 		return nil, errors.New("token_refresh_failed")
 	}
 
 	return resp, nil
-}
\ No newline at end of file
+}
+
+// Logout ends the caller's session: the access token is refused from now on
+// and the session's refresh token is deleted
+func (s *authService) Logout(claims *auth.PartnerClaims) error {
+	if err := s.revocations.RevokeToken(models.OwnerTypePartner, claims.PartnerID, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return errors.New("logout_failed")
+	}
+
+	if claims.SessionID == "" {
+		return nil
+	}
+	err := database.ExecuteTransaction(func(tx *gorm.DB) error {
+		return s.refreshTokenRepo.DeleteBySessionID(tx, claims.SessionID)
+	})
+	if err != nil {
+		return errors.New("logout_failed")
+	}
+	return nil
+}
+
+func toPartnerProfileResponse(partner *models.Partner) *PartnerProfileResponse {
+	if partner == nil {
+		return nil
+	}
+	return &PartnerProfileResponse{
+		ID:            partner.ID,
+		FirstName:     partner.FirstName,
+		LastName:      partner.LastName,
+		Email:         partner.Email,
+		Phone:         partner.Phone,
+		Status:        partner.Status,
+		EmailVerified: partner.EmailVerified,
+		PhoneVerified: partner.PhoneVerified,
+		LastLoginAt:   partner.LastLoginAt,
+	}
+}
+
+func (s *authService) GetProfile(partnerID uint) (*PartnerProfileResponse, error) {
+	partner, err := s.partnerRepo.FindByID(partnerID)
+	if err != nil {
+		return nil, errors.New("failed_to_get_profile")
+	}
+	if partner == nil {
+		return nil, errors.New("partner_not_found")
+	}
+
+	return toPartnerProfileResponse(partner), nil
+}
+
+// UpdateProfile changes name and phone. The email is the sign-in identity
+// and can't be changed here.
+func (s *authService) UpdateProfile(partnerID uint, input UpdateProfileInput) (*PartnerProfileResponse, error) {
+	partner, err := s.partnerRepo.FindByID(partnerID)
+	if err != nil {
+		return nil, errors.New("failed_to_update_profile")
+	}
+	if partner == nil {
+		return nil, errors.New("partner_not_found")
+	}
+
+	var phone *string
+	if input.Phone != nil {
+		if trimmed := strings.TrimSpace(*input.Phone); trimmed != "" {
+			owner, err := s.partnerRepo.FindByPhone(trimmed)
+			if err != nil {
+				return nil, errors.New("failed_to_update_profile")
+			}
+			if owner != nil && owner.ID != partner.ID {
+				return nil, errors.New("phone_already_in_use")
+			}
+			phone = &trimmed
+		}
+	}
+
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		if input.FirstName != "" {
+			partner.FirstName = input.FirstName
+		}
+		if input.LastName != "" {
+			partner.LastName = input.LastName
+		}
+		if input.Phone != nil && !samePhone(partner.Phone, phone) {
+			partner.Phone = phone
+			partner.PhoneVerified = false
+			partner.PhoneVerifiedAt = nil
+
+			// Codes sent to the old number must not verify or sign in the new one
+			for _, purpose := range []string{models.OTPPurposePhoneVerification, models.OTPPurposePhoneSignin} {
+				if err := s.otpRepo.InvalidateActive(tx, partner.ID, models.OwnerTypePartner, purpose); err != nil {
+					return err
+				}
+			}
+		}
+		return s.partnerRepo.Update(tx, partner)
+	})
+	if err != nil {
+		return nil, errors.New("failed_to_update_profile")
+	}
+
+	return toPartnerProfileResponse(partner), nil
+}
+
+func samePhone(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// UpdatePassword changes the password and signs the partner out of every
+// session, so a stolen refresh token stops working with the old password.
+// Wrong current passwords count towards a per-partner lockout, so a stolen
+// access token can't be used to guess it.
+func (s *authService) UpdatePassword(partnerID uint, input UpdatePasswordInput) error {
+	account := strconv.FormatUint(uint64(partnerID), 10)
+	if err := s.passwordGuard.Check(account, ""); err != nil {
+		return err
+	}
+
+	partner, err := s.partnerRepo.FindByID(partnerID)
+	if err != nil {
+		return errors.New("update_password_failed")
+	}
+	if partner == nil {
+		return errors.New("partner_not_found")
+	}
+
+	if ok, _ := auth.VerifyPassword(partner.PasswordHash, input.CurrentPassword); !ok {
+		s.passwordGuard.Fail(account, "")
+		return errors.New("invalid_current_password")
+	}
+	s.passwordGuard.Succeed(account)
+
+	subject := partnerPasswordSubject(partner)
+	if err := s.passwords.Validate(input.NewPassword, subject); err != nil {
+		return err
+	}
+
+	newHash, err := auth.HashPassword(input.NewPassword)
+	if err != nil {
+		return errors.New("update_password_failed")
+	}
+
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		partner.PasswordHash = newHash
+
+		if err := s.passwords.Record(tx, subject, newHash); err != nil {
+			return err
+		}
+
+		if err := s.refreshTokenRepo.DeleteByPartnerID(tx, partner.ID); err != nil {
+			return err
+		}
+
+		return s.partnerRepo.Update(tx, partner)
+	})
+	if err != nil {
+		return errors.New("update_password_failed")
+	}
+
+	return nil
+}
+
+// upgradePasswordHash rehashes a legacy or under-cost hash with the current
+// argon2id settings. Failures are logged only; the sign-in already succeeded.
+func (s *authService) upgradePasswordHash(partner *models.Partner, password string) {
+	newHash, err := auth.HashPassword(password)
+	if err == nil {
+		err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+			return s.partnerRepo.UpgradePasswordHash(tx, partner.ID, partner.PasswordHash, newHash)
+		})
+	}
+	if err != nil {
+		logger.Error().Err(err).Uint("partner_id", partner.ID).Msg("Failed to upgrade password hash")
+		return
+	}
+	partner.PasswordHash = newHash
+}
@@ -2,69 +2,63 @@
 package service
 
 import (
-	"crypto/rand"
 	"errors"
-	"math/big"
-	"time"
 
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/bruteforce"
 	"github.com/jafoor/carhub/libs/database" // ← for ExecuteTransaction & ReadDB
 	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/libs/notifier"
 	otpRepository "github.com/jafoor/carhub/libs/repository"
 	"github.com/jafoor/carhub/services/partner/repository"
 	"gorm.io/gorm"
 )
 
 type OTPService interface {
-	VerifyOTP(email, otpCode string) error
+	VerifyOTP(email, otpCode, clientIP string) error
 	ResendOTP(email string) error
 }
 
 type otpService struct {
 	partnerRepo repository.PartnerRepository
 	otpRepo     otpRepository.OTPRepository
+	notifier    notifier.Notifier
+	verifyGuard *bruteforce.Guard
 }
 
 func NewOTPService(
 	partnerRepo repository.PartnerRepository,
 	otpRepo otpRepository.OTPRepository,
+	notify notifier.Notifier,
+	verifyGuard *bruteforce.Guard,
 ) OTPService {
 	return &otpService{
 		partnerRepo: partnerRepo,
 		otpRepo:     otpRepo,
+		notifier:    notify,
+		verifyGuard: verifyGuard,
 	}
 }
 
-func generateSecureOTP(length int) (string, error) {
-	const digits = "0123456789"
-	otp := make([]byte, length)
-	for i := range otp {
-		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(digits))))
-		if err != nil {
-			return "", err
-		}
-		otp[i] = digits[num.Int64()]
+func (s *otpService) VerifyOTP(email, otpCode, clientIP string) error {
+	if err := s.verifyGuard.Check(email, clientIP); err != nil {
+		return err
 	}
-	return string(otp), nil
-}
 
-func (s *otpService) VerifyOTP(email, otpCode string) error {
 	partner, err := s.partnerRepo.FindByEmail(email)
 	if err != nil || partner == nil {
+		s.verifyGuard.Fail(email, clientIP)
 		return errors.New("partner_not_found")
 	}
 
-	otp, err := s.otpRepo.FindValidOTP(
-		partner.ID,
-		models.OwnerTypePartner,
-		otpCode,
-		"email_verification",
-	)
+	otp, err := auth.VerifyOTP(s.otpRepo, partner.ID, models.OwnerTypePartner, models.OTPPurposeEmailVerification, otpCode)
 	if err != nil {
-		return errors.New("database_error")
-	}
-	if otp == nil {
-		return errors.New("invalid_or_expired_otp")
+		if err.Error() != "database_error" {
+			s.verifyGuard.Fail(email, clientIP)
+		}
+		return err
 	}
+	s.verifyGuard.Succeed(email)
 
 	// ✅ USE SHARED TRANSACTION HELPER
 	return database.ExecuteTransaction(func(tx *gorm.DB) error {
@@ -87,36 +81,10 @@ func (s *otpService) ResendOTP(email string) error {
 		return errors.New("email_already_verified")
 	}
 
-	var count int64
-	err = database.ReadDB.Model(&models.OTP{}). // 👈 use shared ReadDB
-		Where("owner_id = ? AND owner_type = ? AND purpose = ? AND used = ? AND expires_at > ?",
-			partner.ID, models.OwnerTypePartner, "email_verification", false, time.Now()).
-		Count(&count).Error
-
+	otp, code, err := auth.IssueOTP(s.otpRepo, partner.ID, models.OwnerTypePartner, models.OTPPurposeEmailVerification)
 	if err != nil {
-		return errors.New("database_error")
-	}
-
-	if count > 0 {
-		return errors.New("otp_already_sent_wait_for_expiry")
+		return err
 	}
 
-	code, err := generateSecureOTP(6)
-	if err != nil {
-		return errors.New("otp_generation_failed")
-	}
-
-	otp := &models.OTP{
-		OwnerID:   partner.ID,
-		OwnerType: models.OwnerTypePartner,
-		Code:      code,
-		Purpose:   "email_verification",
-		ExpiresAt: time.Now().Add(10 * time.Minute),
-		Used:      false,
-	}
-
-	// ✅ USE SHARED TRANSACTION HELPER
-	return database.ExecuteTransaction(func(tx *gorm.DB) error {
-		return s.otpRepo.Create(tx, otp)
-	})
+	return auth.SendOTP(s.notifier, s.otpRepo, otp, code, notifier.ChannelEmail, partner.Email, partner.FirstName, notifier.TemplateOTPEmailVerification)
 }
\ No newline at end of file
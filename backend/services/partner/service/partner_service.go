@@ -2,17 +2,15 @@
 package service
 
 import (
-	"crypto/rand"
 	"errors"
-	"math/big"
 	"strings"
-	"time"
 
+	"github.com/jafoor/carhub/libs/auth"
 	"github.com/jafoor/carhub/libs/database" // ← for ExecuteTransaction & DB
 	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/libs/notifier"
 	otpRepository "github.com/jafoor/carhub/libs/repository"
 	"github.com/jafoor/carhub/services/partner/repository"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -36,36 +34,24 @@ type PartnerService interface {
 type partnerService struct {
 	partnerRepo repository.PartnerRepository
 	otpRepo     otpRepository.OTPRepository
+	notifier    notifier.Notifier
+	passwords   auth.PasswordPolicy
 }
 
 func NewPartnerService(
 	partnerRepo repository.PartnerRepository,
 	otpRepo otpRepository.OTPRepository,
+	notify notifier.Notifier,
+	passwords auth.PasswordPolicy,
 ) PartnerService {
 	return &partnerService{
 		partnerRepo: partnerRepo,
 		otpRepo:     otpRepo,
+		notifier:    notify,
+		passwords:   passwords,
 	}
 }
 
-func hashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
-}
-
-func generateOTP(length int) (string, error) {
-	const digits = "0123456789"
-	otp := make([]byte, length)
-	for i := range otp {
-		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(digits))))
-		if err != nil {
-			return "", err
-		}
-		otp[i] = digits[num.Int64()]
-	}
-	return string(otp), nil
-}
-
 func (s *partnerService) Signup(req SignupInput) (*SignupResponse, error) {
 	email := strings.ToLower(strings.TrimSpace(req.Email))
 
@@ -84,7 +70,17 @@ func (s *partnerService) Signup(req SignupInput) (*SignupResponse, error) {
 		return nil, errors.New("email_already_registered")
 	}
 
-	pwHash, err := hashPassword(req.Password)
+	subject := auth.PasswordSubject{
+		OwnerType: models.OwnerTypePartner,
+		Email:     email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+	}
+	if err := s.passwords.Validate(req.Password, subject); err != nil {
+		return nil, err
+	}
+
+	pwHash, err := auth.HashPassword(req.Password)
 	if err != nil {
 		return nil, errors.New("password_hash_failed")
 	}
@@ -100,6 +96,8 @@ func (s *partnerService) Signup(req SignupInput) (*SignupResponse, error) {
 	}
 
 	var resp *SignupResponse
+	var otp *models.OTP
+	var code string
 
 	// ✅ USE SHARED TRANSACTION HELPER
 	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
@@ -107,21 +105,14 @@ func (s *partnerService) Signup(req SignupInput) (*SignupResponse, error) {
 			return err
 		}
 
-		code, err := generateOTP(6)
-		if err != nil {
+		subject.OwnerID = partner.ID
+		if err := s.passwords.Record(tx, subject, pwHash); err != nil {
 			return err
 		}
 
-		otp := &models.OTP{
-			OwnerID:   partner.ID,
-			OwnerType: models.OwnerTypePartner, // 👈 use typed constant
-			Code:      code,
-			Purpose:   "email_verification",
-			ExpiresAt: time.Now().Add(10 * time.Minute),
-			Used:      false,
-		}
-
-		if err := s.otpRepo.Create(tx, otp); err != nil {
+		var err error
+		otp, code, err = auth.CreateOTP(tx, s.otpRepo, partner.ID, models.OwnerTypePartner, models.OTPPurposeEmailVerification)
+		if err != nil {
 			return err
 		}
 
@@ -136,5 +127,9 @@ func (s *partnerService) Signup(req SignupInput) (*SignupResponse, error) {
 		return nil, errors.New("signup_failed")
 	}
 
+	// Delivery happens after commit. A failed send burns the code so the
+	// partner can request a fresh one through resend-otp.
+	_ = auth.SendOTP(s.notifier, s.otpRepo, otp, code, notifier.ChannelEmail, partner.Email, partner.FirstName, notifier.TemplateOTPEmailVerification)
+
 	return resp, nil
 }
\ No newline at end of file
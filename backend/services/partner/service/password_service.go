@@ -0,0 +1,151 @@
+// services/partner/service/password_service.go
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/bruteforce"
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/logger"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/libs/notifier"
+	otpRepository "github.com/jafoor/carhub/libs/repository"
+	"github.com/jafoor/carhub/services/partner/repository"
+	"gorm.io/gorm"
+)
+
+type ResetPasswordInput struct {
+	Email       string `json:"email"`
+	OTPCode     string `json:"otp_code"`
+	NewPassword string `json:"new_password"`
+}
+
+type PasswordService interface {
+	RequestPasswordReset(email, clientIP string) error
+	ResetPassword(req ResetPasswordInput) error
+}
+
+type passwordService struct {
+	partnerRepo      repository.PartnerRepository
+	otpRepo          otpRepository.OTPRepository
+	refreshTokenRepo otpRepository.PartnerRefreshTokenRepository
+	notifier         notifier.Notifier
+	passwords        auth.PasswordPolicy
+	requestGuard     *bruteforce.Guard
+}
+
+func NewPasswordService(
+	partnerRepo repository.PartnerRepository,
+	otpRepo otpRepository.OTPRepository,
+	refreshTokenRepo otpRepository.PartnerRefreshTokenRepository,
+	notify notifier.Notifier,
+	passwords auth.PasswordPolicy,
+	requestGuard *bruteforce.Guard,
+) PasswordService {
+	return &passwordService{
+		partnerRepo:      partnerRepo,
+		otpRepo:          otpRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		notifier:         notify,
+		passwords:        passwords,
+		requestGuard:     requestGuard,
+	}
+}
+
+// RequestPasswordReset sends a password_reset OTP. Unknown emails are
+// answered the same way as known ones so the endpoint can't be used to
+// enumerate partner accounts: OTP limits and delivery failures only happen
+// to real accounts, so they are logged rather than returned. Every request
+// counts against the client IP instead, which throttles it for any email.
+func (s *passwordService) RequestPasswordReset(email, clientIP string) error {
+	if err := s.requestGuard.Check("", clientIP); err != nil {
+		return err
+	}
+	s.requestGuard.Fail("", clientIP)
+
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	partner, err := s.partnerRepo.FindByEmail(email)
+	if err != nil {
+		return errors.New("database_error")
+	}
+	if partner == nil {
+		return nil
+	}
+
+	otp, code, err := auth.IssueOTP(s.otpRepo, partner.ID, models.OwnerTypePartner, models.OTPPurposePasswordReset)
+	if err != nil {
+		logger.Warn().Err(err).Uint("partner_id", partner.ID).Msg("Password reset OTP not issued")
+		return nil
+	}
+
+	// SendOTP logs delivery failures itself
+	_ = auth.SendOTP(s.notifier, s.otpRepo, otp, code, notifier.ChannelEmail, partner.Email, partner.FirstName, notifier.TemplateOTPPasswordReset)
+	return nil
+}
+
+// ResetPassword sets a new password after checking the reset OTP and signs
+// the partner out of every session.
+func (s *passwordService) ResetPassword(req ResetPasswordInput) error {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	partner, err := s.partnerRepo.FindByEmail(email)
+	if err != nil {
+		return errors.New("database_error")
+	}
+	if partner == nil {
+		return errors.New("invalid_or_expired_otp")
+	}
+
+	otp, err := auth.VerifyOTP(s.otpRepo, partner.ID, models.OwnerTypePartner, models.OTPPurposePasswordReset, req.OTPCode)
+	if err != nil {
+		return err
+	}
+
+	subject := partnerPasswordSubject(partner)
+	if err := s.passwords.Validate(req.NewPassword, subject); err != nil {
+		return err
+	}
+
+	pwHash, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		return errors.New("password_hash_failed")
+	}
+
+	err = database.ExecuteTransaction(func(tx *gorm.DB) error {
+		if err := s.otpRepo.MarkAsUsed(tx, otp.ID); err != nil {
+			return err
+		}
+
+		partner.PasswordHash = pwHash
+		if err := s.partnerRepo.Update(tx, partner); err != nil {
+			return err
+		}
+
+		if err := s.passwords.Record(tx, subject, pwHash); err != nil {
+			return err
+		}
+
+		// Revoke every refresh token issued with the old password
+		return s.refreshTokenRepo.DeleteByPartnerID(tx, partner.ID)
+	})
+	if err != nil {
+		return errors.New("password_reset_failed")
+	}
+
+	return nil
+}
+
+// partnerPasswordSubject describes an existing partner to the password policy
+func partnerPasswordSubject(partner *models.Partner) auth.PasswordSubject {
+	return auth.PasswordSubject{
+		OwnerType:   models.OwnerTypePartner,
+		OwnerID:     partner.ID,
+		CurrentHash: partner.PasswordHash,
+		Email:       partner.Email,
+		FirstName:   partner.FirstName,
+		LastName:    partner.LastName,
+	}
+}
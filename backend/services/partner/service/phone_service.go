@@ -0,0 +1,138 @@
+// services/partner/service/phone_service.go
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/bruteforce"
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/logger"
+	"github.com/jafoor/carhub/libs/models"
+	"github.com/jafoor/carhub/libs/notifier"
+	otpRepository "github.com/jafoor/carhub/libs/repository"
+	"github.com/jafoor/carhub/services/partner/repository"
+	"gorm.io/gorm"
+)
+
+type PhoneService interface {
+	RequestVerification(partnerID uint) error
+	ConfirmVerification(partnerID uint, otpCode, clientIP string) error
+	RequestSigninOTP(phone string) error
+}
+
+type phoneService struct {
+	partnerRepo repository.PartnerRepository
+	otpRepo     otpRepository.OTPRepository
+	notifier    notifier.Notifier
+	verifyGuard *bruteforce.Guard
+}
+
+func NewPhoneService(
+	partnerRepo repository.PartnerRepository,
+	otpRepo otpRepository.OTPRepository,
+	notify notifier.Notifier,
+	verifyGuard *bruteforce.Guard,
+) PhoneService {
+	return &phoneService{
+		partnerRepo: partnerRepo,
+		otpRepo:     otpRepo,
+		notifier:    notify,
+		verifyGuard: verifyGuard,
+	}
+}
+
+// RequestVerification texts a phone_verification OTP to the partner's phone
+func (s *phoneService) RequestVerification(partnerID uint) error {
+	partner, err := s.partnerRepo.FindByID(partnerID)
+	if err != nil {
+		return errors.New("database_error")
+	}
+	if partner == nil {
+		return errors.New("partner_not_found")
+	}
+	if partner.Phone == nil {
+		return errors.New("phone_not_set")
+	}
+	if partner.PhoneVerified {
+		return errors.New("phone_already_verified")
+	}
+
+	otp, code, err := auth.IssueOTP(s.otpRepo, partner.ID, models.OwnerTypePartner, models.OTPPurposePhoneVerification)
+	if err != nil {
+		return err
+	}
+
+	return auth.SendOTP(s.notifier, s.otpRepo, otp, code, notifier.ChannelSMS, *partner.Phone, partner.FirstName, notifier.TemplateOTPPhoneVerification)
+}
+
+// ConfirmVerification marks the phone verified. The OTP is bound to the
+// partner, and changing the phone burns it, so it can only confirm the
+// number it was sent to.
+func (s *phoneService) ConfirmVerification(partnerID uint, otpCode, clientIP string) error {
+	partner, err := s.partnerRepo.FindByID(partnerID)
+	if err != nil {
+		return errors.New("database_error")
+	}
+	if partner == nil {
+		return errors.New("partner_not_found")
+	}
+	if partner.Phone == nil {
+		return errors.New("phone_not_set")
+	}
+	if partner.PhoneVerified {
+		return errors.New("phone_already_verified")
+	}
+
+	if err := s.verifyGuard.Check(*partner.Phone, clientIP); err != nil {
+		return err
+	}
+
+	otp, err := auth.VerifyOTP(s.otpRepo, partner.ID, models.OwnerTypePartner, models.OTPPurposePhoneVerification, otpCode)
+	if err != nil {
+		if err.Error() != "database_error" {
+			s.verifyGuard.Fail(*partner.Phone, clientIP)
+		}
+		return err
+	}
+	s.verifyGuard.Succeed(*partner.Phone)
+
+	return database.ExecuteTransaction(func(tx *gorm.DB) error {
+		if err := s.otpRepo.MarkAsUsed(tx, otp.ID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		partner.PhoneVerified = true
+		partner.PhoneVerifiedAt = &now
+		return s.partnerRepo.Update(tx, partner)
+	})
+}
+
+// RequestSigninOTP texts a phone_signin OTP to a verified phone. Unknown or
+// unverified numbers get the same response so numbers can't be probed; OTP
+// limits and delivery failures only happen to verified ones, so they are
+// logged and not returned.
+func (s *phoneService) RequestSigninOTP(phone string) error {
+	phone = strings.TrimSpace(phone)
+
+	partner, err := s.partnerRepo.FindByPhone(phone)
+	if err != nil {
+		return errors.New("database_error")
+	}
+	if partner == nil || !partner.PhoneVerified || !partner.EmailVerified {
+		return nil
+	}
+
+	otp, code, err := auth.IssueOTP(s.otpRepo, partner.ID, models.OwnerTypePartner, models.OTPPurposePhoneSignin)
+	if err != nil {
+		logger.Warn().Err(err).Uint("partner_id", partner.ID).Msg("Phone sign-in OTP not issued")
+		return nil
+	}
+
+	// SendOTP logs delivery failures itself
+	_ = auth.SendOTP(s.notifier, s.otpRepo, otp, code, notifier.ChannelSMS, phone, partner.FirstName, notifier.TemplateOTPPhoneSignin)
+	return nil
+}
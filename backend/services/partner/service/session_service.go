@@ -0,0 +1,103 @@
+// services/partner/service/session_service.go
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jafoor/carhub/libs/auth"
+	"github.com/jafoor/carhub/libs/config"
+	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/models"
+	otpRepo "github.com/jafoor/carhub/libs/repository"
+	"gorm.io/gorm"
+)
+
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	DeviceName string    `json:"device_name"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+type SessionService interface {
+	ListSessions(partnerID uint, currentSessionID string) ([]SessionResponse, error)
+	RevokeSession(partnerID, sessionID uint) error
+	RevokeAllSessions(partnerID uint) error
+}
+
+type sessionService struct {
+	refreshTokenRepo otpRepo.PartnerRefreshTokenRepository
+	revocations      auth.RevocationStore
+}
+
+func NewSessionService(refreshTokenRepo otpRepo.PartnerRefreshTokenRepository, revocations auth.RevocationStore) SessionService {
+	return &sessionService{refreshTokenRepo: refreshTokenRepo, revocations: revocations}
+}
+
+// ListSessions returns the partner's active sessions, flagging the caller's own
+func (s *sessionService) ListSessions(partnerID uint, currentSessionID string) ([]SessionResponse, error) {
+	tokens, err := s.refreshTokenRepo.ListActiveByPartnerID(partnerID)
+	if err != nil {
+		return nil, errors.New("failed_to_list_sessions")
+	}
+
+	sessions := make([]SessionResponse, len(tokens))
+	for i, t := range tokens {
+		sessions[i] = SessionResponse{
+			ID:         t.ID,
+			DeviceName: t.DeviceName,
+			IPAddress:  t.IPAddress,
+			UserAgent:  t.UserAgent,
+			LastUsedAt: t.LastUsedAt,
+			CreatedAt:  t.CreatedAt,
+			ExpiresAt:  t.ExpiresAt,
+			Current:    t.SessionID == currentSessionID,
+		}
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends the session's refresh token and refuses the access
+// tokens already issued for it
+func (s *sessionService) RevokeSession(partnerID, sessionID uint) error {
+	var token *models.PartnerRefreshToken
+	err := database.ExecuteTransaction(func(tx *gorm.DB) error {
+		var err error
+		token, err = s.refreshTokenRepo.DeleteByID(tx, partnerID, sessionID)
+		return err
+	})
+	if err != nil {
+		return errors.New("failed_to_revoke_session")
+	}
+	if token == nil {
+		return errors.New("session_not_found")
+	}
+
+	// Access tokens outlive the session by at most their TTL
+	expiresAt := time.Now().Add(time.Duration(config.App.AccessTokenTTL) * time.Minute)
+	if err := s.revocations.RevokeSession(models.OwnerTypePartner, partnerID, token.SessionID, expiresAt); err != nil {
+		return errors.New("failed_to_revoke_session")
+	}
+	return nil
+}
+
+// RevokeAllSessions ends every session, including the caller's, and refuses
+// every access token issued so far
+func (s *sessionService) RevokeAllSessions(partnerID uint) error {
+	err := database.ExecuteTransaction(func(tx *gorm.DB) error {
+		return s.refreshTokenRepo.DeleteByPartnerID(tx, partnerID)
+	})
+	if err != nil {
+		return errors.New("failed_to_revoke_session")
+	}
+
+	if err := s.revocations.RevokeAllTokens(models.OwnerTypePartner, partnerID); err != nil {
+		return errors.New("failed_to_revoke_session")
+	}
+	return nil
+}
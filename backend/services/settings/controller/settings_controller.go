@@ -5,7 +5,9 @@ import (
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/authz"
 	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/middleware"
 	"github.com/jafoor/carhub/libs/models"
 	"github.com/jafoor/carhub/libs/utils"
 	"github.com/jafoor/carhub/services/settings/repository"
@@ -19,6 +21,44 @@ func NewSettingsController(repo repository.SettingsRepository) *SettingsControll
 	return &SettingsController{repo: repo}
 }
 
+// errOutOfScope answers writes outside the admin's regions and cities
+func errOutOfScope(ctx *fiber.Ctx) error {
+	return utils.ErrorResponse(ctx, http.StatusForbidden, "Outside your assigned regions and cities", nil)
+}
+
+// allowsCityID resolves the city's region to check it against scope
+func (c *SettingsController) allowsCityID(scope *authz.Scope, cityID uint) (bool, error) {
+	if scope.Global() {
+		return true, nil
+	}
+	city, err := c.repo.GetCity(cityID)
+	if err != nil {
+		return false, err
+	}
+	return city != nil && scope.AllowsCity(city.ID, city.RegionID), nil
+}
+
+// scopedRegion applies the list filter to a single region: it is visible in
+// the admin's regions, or trimmed to their cities when only those are in
+// scope. It returns nil when the region is out of scope.
+func scopedRegion(scope *authz.Scope, region *models.Region) *models.Region {
+	if scope.AllowsRegion(region.ID) {
+		return region
+	}
+
+	cities := make([]models.City, 0, len(region.Cities))
+	for _, city := range region.Cities {
+		if scope.AllowsCity(city.ID, city.RegionID) {
+			cities = append(cities, city)
+		}
+	}
+	if len(cities) == 0 {
+		return nil
+	}
+	region.Cities = cities
+	return region
+}
+
 // --- Region Handlers ---
 
 type CreateRegionInput struct {
@@ -37,6 +77,11 @@ func (c *SettingsController) CreateRegion(ctx *fiber.Ctx) error {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Name and Display Name are required", nil)
 	}
 
+	// New regions are outside every regional scope
+	if !middleware.GetPermissionScope(ctx).Global() {
+		return errOutOfScope(ctx)
+	}
+
 	isActive := true
 	if input.IsActive != nil {
 		isActive = *input.IsActive
@@ -68,7 +113,12 @@ func (c *SettingsController) ListRegions(ctx *fiber.Ctx) error {
 	}
 	offset := (page - 1) * limit
 
-	regions, total, err := c.repo.ListRegions(offset, limit, search)
+	scope, err := middleware.GetAdminScope(ctx)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to list regions", err.Error())
+	}
+
+	regions, total, err := c.repo.ListRegions(offset, limit, search, scope)
 	if err != nil {
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to list regions", err.Error())
 	}
@@ -87,10 +137,19 @@ func (c *SettingsController) GetRegion(ctx *fiber.Ctx) error {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid ID", nil)
 	}
 
+	scope, err := middleware.GetAdminScope(ctx)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get region", err.Error())
+	}
+
 	region, err := c.repo.GetRegion(uint(id))
 	if err != nil {
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get region", err.Error())
 	}
+	// Out-of-scope records are hidden as in ListRegions
+	if region != nil {
+		region = scopedRegion(scope, region)
+	}
 	if region == nil {
 		return utils.ErrorResponse(ctx, http.StatusNotFound, "Region not found", nil)
 	}
@@ -116,6 +175,9 @@ func (c *SettingsController) UpdateRegion(ctx *fiber.Ctx) error {
 	if region == nil {
 		return utils.ErrorResponse(ctx, http.StatusNotFound, "Region not found", nil)
 	}
+	if !middleware.GetPermissionScope(ctx).AllowsRegion(region.ID) {
+		return errOutOfScope(ctx)
+	}
 
 	if input.Name != "" {
 		region.Name = input.Name
@@ -147,6 +209,9 @@ func (c *SettingsController) DeleteRegion(ctx *fiber.Ctx) error {
 	if region == nil {
 		return utils.ErrorResponse(ctx, http.StatusNotFound, "Region not found", nil)
 	}
+	if !middleware.GetPermissionScope(ctx).AllowsRegion(region.ID) {
+		return errOutOfScope(ctx)
+	}
 
 	if err := c.repo.DeleteRegion(database.WriteDB, uint(id)); err != nil {
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to delete region", err.Error())
@@ -173,6 +238,9 @@ func (c *SettingsController) CreateCity(ctx *fiber.Ctx) error {
 	if input.Name == "" || input.DisplayName == "" || input.RegionID == 0 {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Name, Display Name and Region ID are required", nil)
 	}
+	if !middleware.GetPermissionScope(ctx).AllowsRegion(input.RegionID) {
+		return errOutOfScope(ctx)
+	}
 
 	isActive := true
 	if input.IsActive != nil {
@@ -215,7 +283,12 @@ func (c *SettingsController) ListCities(ctx *fiber.Ctx) error {
 	}
 	offset := (page - 1) * limit
 
-	cities, total, err := c.repo.ListCities(offset, limit, search, regionID)
+	scope, err := middleware.GetAdminScope(ctx)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to list cities", err.Error())
+	}
+
+	cities, total, err := c.repo.ListCities(offset, limit, search, regionID, scope)
 	if err != nil {
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to list cities", err.Error())
 	}
@@ -234,11 +307,17 @@ func (c *SettingsController) GetCity(ctx *fiber.Ctx) error {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid ID", nil)
 	}
 
+	scope, err := middleware.GetAdminScope(ctx)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get city", err.Error())
+	}
+
 	city, err := c.repo.GetCity(uint(id))
 	if err != nil {
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get city", err.Error())
 	}
-	if city == nil {
+	// Out-of-scope records are hidden as in ListCities
+	if city == nil || !scope.AllowsCity(city.ID, city.RegionID) {
 		return utils.ErrorResponse(ctx, http.StatusNotFound, "City not found", nil)
 	}
 
@@ -264,6 +343,15 @@ func (c *SettingsController) UpdateCity(ctx *fiber.Ctx) error {
 		return utils.ErrorResponse(ctx, http.StatusNotFound, "City not found", nil)
 	}
 
+	// Moving a city needs its new region to be in scope as well
+	scope := middleware.GetPermissionScope(ctx)
+	if !scope.AllowsCity(city.ID, city.RegionID) {
+		return errOutOfScope(ctx)
+	}
+	if input.RegionID != 0 && input.RegionID != city.RegionID && !scope.AllowsRegion(input.RegionID) {
+		return errOutOfScope(ctx)
+	}
+
 	if input.Name != "" {
 		city.Name = input.Name
 	}
@@ -297,6 +385,9 @@ func (c *SettingsController) DeleteCity(ctx *fiber.Ctx) error {
 	if city == nil {
 		return utils.ErrorResponse(ctx, http.StatusNotFound, "City not found", nil)
 	}
+	if !middleware.GetPermissionScope(ctx).AllowsCity(city.ID, city.RegionID) {
+		return errOutOfScope(ctx)
+	}
 
 	if err := c.repo.DeleteCity(database.WriteDB, uint(id)); err != nil {
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to delete city", err.Error())
@@ -324,6 +415,14 @@ func (c *SettingsController) CreateArea(ctx *fiber.Ctx) error {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Name, Display Name and City ID are required", nil)
 	}
 
+	allowed, err := c.allowsCityID(middleware.GetPermissionScope(ctx), input.CityID)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get city", err.Error())
+	}
+	if !allowed {
+		return errOutOfScope(ctx)
+	}
+
 	isActive := true
 	if input.IsActive != nil {
 		isActive = *input.IsActive
@@ -365,7 +464,12 @@ func (c *SettingsController) ListAreas(ctx *fiber.Ctx) error {
 	}
 	offset := (page - 1) * limit
 
-	areas, total, err := c.repo.ListAreas(offset, limit, search, cityID)
+	scope, err := middleware.GetAdminScope(ctx)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to list areas", err.Error())
+	}
+
+	areas, total, err := c.repo.ListAreas(offset, limit, search, cityID, scope)
 	if err != nil {
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to list areas", err.Error())
 	}
@@ -384,11 +488,17 @@ func (c *SettingsController) GetArea(ctx *fiber.Ctx) error {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid ID", nil)
 	}
 
+	scope, err := middleware.GetAdminScope(ctx)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get area", err.Error())
+	}
+
 	area, err := c.repo.GetArea(uint(id))
 	if err != nil {
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get area", err.Error())
 	}
-	if area == nil {
+	// Out-of-scope records are hidden as in ListAreas
+	if area == nil || (!scope.Global() && (area.City == nil || !scope.AllowsCity(area.CityID, area.City.RegionID))) {
 		return utils.ErrorResponse(ctx, http.StatusNotFound, "Area not found", nil)
 	}
 
@@ -414,6 +524,22 @@ func (c *SettingsController) UpdateArea(ctx *fiber.Ctx) error {
 		return utils.ErrorResponse(ctx, http.StatusNotFound, "Area not found", nil)
 	}
 
+	// Moving an area needs its new city to be in scope as well
+	scope := middleware.GetPermissionScope(ctx)
+	cityIDs := []uint{area.CityID}
+	if input.CityID != 0 && input.CityID != area.CityID {
+		cityIDs = append(cityIDs, input.CityID)
+	}
+	for _, cityID := range cityIDs {
+		allowed, err := c.allowsCityID(scope, cityID)
+		if err != nil {
+			return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get city", err.Error())
+		}
+		if !allowed {
+			return errOutOfScope(ctx)
+		}
+	}
+
 	if input.Name != "" {
 		area.Name = input.Name
 	}
@@ -448,6 +574,14 @@ func (c *SettingsController) DeleteArea(ctx *fiber.Ctx) error {
 		return utils.ErrorResponse(ctx, http.StatusNotFound, "Area not found", nil)
 	}
 
+	allowed, err := c.allowsCityID(middleware.GetPermissionScope(ctx), area.CityID)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get city", err.Error())
+	}
+	if !allowed {
+		return errOutOfScope(ctx)
+	}
+
 	if err := c.repo.DeleteArea(database.WriteDB, uint(id)); err != nil {
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to delete area", err.Error())
 	}
@@ -464,6 +598,11 @@ type CreateVehicleTypeInput struct {
 }
 
 func (c *SettingsController) CreateVehicleType(ctx *fiber.Ctx) error {
+	// Vehicle catalogues are shared by every region
+	if !middleware.GetPermissionScope(ctx).Global() {
+		return errOutOfScope(ctx)
+	}
+
 	var input CreateVehicleTypeInput
 	if err := ctx.BodyParser(&input); err != nil {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid input", err.Error())
@@ -535,6 +674,10 @@ func (c *SettingsController) GetVehicleType(ctx *fiber.Ctx) error {
 }
 
 func (c *SettingsController) UpdateVehicleType(ctx *fiber.Ctx) error {
+	if !middleware.GetPermissionScope(ctx).Global() {
+		return errOutOfScope(ctx)
+	}
+
 	id, err := strconv.Atoi(ctx.Params("id"))
 	if err != nil {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid ID", nil)
@@ -571,6 +714,10 @@ func (c *SettingsController) UpdateVehicleType(ctx *fiber.Ctx) error {
 }
 
 func (c *SettingsController) DeleteVehicleType(ctx *fiber.Ctx) error {
+	if !middleware.GetPermissionScope(ctx).Global() {
+		return errOutOfScope(ctx)
+	}
+
 	id, err := strconv.Atoi(ctx.Params("id"))
 	if err != nil {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid ID", nil)
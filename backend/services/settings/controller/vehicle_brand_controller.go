@@ -6,6 +6,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/jafoor/carhub/libs/database"
+	"github.com/jafoor/carhub/libs/middleware"
 	"github.com/jafoor/carhub/libs/models"
 	"github.com/jafoor/carhub/libs/utils"
 )
@@ -20,6 +21,10 @@ type CreateVehicleBrandInput struct {
 }
 
 func (c *SettingsController) CreateVehicleBrand(ctx *fiber.Ctx) error {
+	if !middleware.GetPermissionScope(ctx).Global() {
+		return errOutOfScope(ctx)
+	}
+
 	var input CreateVehicleBrandInput
 	if err := ctx.BodyParser(&input); err != nil {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid input", err.Error())
@@ -101,6 +106,10 @@ func (c *SettingsController) GetVehicleBrand(ctx *fiber.Ctx) error {
 }
 
 func (c *SettingsController) UpdateVehicleBrand(ctx *fiber.Ctx) error {
+	if !middleware.GetPermissionScope(ctx).Global() {
+		return errOutOfScope(ctx)
+	}
+
 	id, err := strconv.Atoi(ctx.Params("id"))
 	if err != nil {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid ID", nil)
@@ -140,6 +149,10 @@ func (c *SettingsController) UpdateVehicleBrand(ctx *fiber.Ctx) error {
 }
 
 func (c *SettingsController) DeleteVehicleBrand(ctx *fiber.Ctx) error {
+	if !middleware.GetPermissionScope(ctx).Global() {
+		return errOutOfScope(ctx)
+	}
+
 	id, err := strconv.Atoi(ctx.Params("id"))
 	if err != nil {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid ID", nil)
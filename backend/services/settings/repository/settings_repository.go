@@ -4,6 +4,7 @@ import (
 	"errors"
 	"strings"
 
+	"github.com/jafoor/carhub/libs/authz"
 	"github.com/jafoor/carhub/libs/database"
 	"github.com/jafoor/carhub/libs/models"
 	"gorm.io/gorm"
@@ -15,21 +16,21 @@ type SettingsRepository interface {
 	GetRegion(id uint) (*models.Region, error)
 	UpdateRegion(tx *gorm.DB, region *models.Region) error
 	DeleteRegion(tx *gorm.DB, id uint) error
-	ListRegions(offset, limit int, search string) ([]models.Region, int64, error)
+	ListRegions(offset, limit int, search string, scope *authz.Scope) ([]models.Region, int64, error)
 
 	// City
 	CreateCity(tx *gorm.DB, city *models.City) error
 	GetCity(id uint) (*models.City, error)
 	UpdateCity(tx *gorm.DB, city *models.City) error
 	DeleteCity(tx *gorm.DB, id uint) error
-	ListCities(offset, limit int, search string, regionID *uint) ([]models.City, int64, error)
+	ListCities(offset, limit int, search string, regionID *uint, scope *authz.Scope) ([]models.City, int64, error)
 
 	// Area
 	CreateArea(tx *gorm.DB, area *models.Area) error
 	GetArea(id uint) (*models.Area, error)
 	UpdateArea(tx *gorm.DB, area *models.Area) error
 	DeleteArea(tx *gorm.DB, id uint) error
-	ListAreas(offset, limit int, search string, cityID *uint) ([]models.Area, int64, error)
+	ListAreas(offset, limit int, search string, cityID *uint, scope *authz.Scope) ([]models.Area, int64, error)
 
 	// VehicleType
 	CreateVehicleType(tx *gorm.DB, vehicleType *models.VehicleType) error
@@ -79,11 +80,18 @@ func (r *settingsRepository) DeleteRegion(tx *gorm.DB, id uint) error {
 	return tx.Delete(&models.Region{}, id).Error
 }
 
-func (r *settingsRepository) ListRegions(offset, limit int, search string) ([]models.Region, int64, error) {
+// ListRegions limits a scoped admin to their regions and the regions of
+// their cities
+func (r *settingsRepository) ListRegions(offset, limit int, search string, scope *authz.Scope) ([]models.Region, int64, error) {
 	var regions []models.Region
 	var total int64
 	query := database.ReadDB.Model(&models.Region{})
 
+	if !scope.Global() {
+		query = query.Where("id IN ? OR id IN (?)", nonEmpty(scope.RegionIDs),
+			database.ReadDB.Model(&models.City{}).Select("region_id").Where("id IN ?", nonEmpty(scope.CityIDs)))
+	}
+
 	if search != "" {
 		searchLower := strings.ToLower(search)
 		query = query.Where("LOWER(name) LIKE ? OR LOWER(display_name) LIKE ?", "%"+searchLower+"%", "%"+searchLower+"%")
@@ -98,6 +106,14 @@ func (r *settingsRepository) ListRegions(offset, limit int, search string) ([]mo
 	return regions, total, err
 }
 
+// nonEmpty keeps "IN ?" valid SQL for an empty list; no row has ID 0
+func nonEmpty(ids []uint) []uint {
+	if len(ids) == 0 {
+		return []uint{0}
+	}
+	return ids
+}
+
 // --- City ---
 
 func (r *settingsRepository) CreateCity(tx *gorm.DB, city *models.City) error {
@@ -124,11 +140,15 @@ func (r *settingsRepository) DeleteCity(tx *gorm.DB, id uint) error {
 	return tx.Delete(&models.City{}, id).Error
 }
 
-func (r *settingsRepository) ListCities(offset, limit int, search string, regionID *uint) ([]models.City, int64, error) {
+func (r *settingsRepository) ListCities(offset, limit int, search string, regionID *uint, scope *authz.Scope) ([]models.City, int64, error) {
 	var cities []models.City
 	var total int64
 	query := database.ReadDB.Model(&models.City{}).Preload("Region")
 
+	if !scope.Global() {
+		query = query.Where("region_id IN ? OR id IN ?", nonEmpty(scope.RegionIDs), nonEmpty(scope.CityIDs))
+	}
+
 	if search != "" {
 		searchLower := strings.ToLower(search)
 		query = query.Where("LOWER(name) LIKE ? OR LOWER(display_name) LIKE ?", "%"+searchLower+"%", "%"+searchLower+"%")
@@ -172,11 +192,16 @@ func (r *settingsRepository) DeleteArea(tx *gorm.DB, id uint) error {
 	return tx.Delete(&models.Area{}, id).Error
 }
 
-func (r *settingsRepository) ListAreas(offset, limit int, search string, cityID *uint) ([]models.Area, int64, error) {
+func (r *settingsRepository) ListAreas(offset, limit int, search string, cityID *uint, scope *authz.Scope) ([]models.Area, int64, error) {
 	var areas []models.Area
 	var total int64
 	query := database.ReadDB.Model(&models.Area{}).Preload("City.Region")
 
+	if !scope.Global() {
+		query = query.Where("city_id IN ? OR city_id IN (?)", nonEmpty(scope.CityIDs),
+			database.ReadDB.Model(&models.City{}).Select("id").Where("region_id IN ?", nonEmpty(scope.RegionIDs)))
+	}
+
 	if search != "" {
 		searchLower := strings.ToLower(search)
 		query = query.Where("LOWER(name) LIKE ? OR LOWER(display_name) LIKE ?", "%"+searchLower+"%", "%"+searchLower+"%")
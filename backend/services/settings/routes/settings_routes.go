@@ -2,6 +2,7 @@ package routes
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/jafoor/carhub/libs/authz"
 	"github.com/jafoor/carhub/libs/middleware"
 	"github.com/jafoor/carhub/services/settings/controller"
 	"github.com/jafoor/carhub/services/settings/repository"
@@ -14,44 +15,44 @@ func RegisterSettingsRoutes(app *fiber.App) {
 	settingsRepo := repository.NewSettingsRepository()
 	settingsCtrl := controller.NewSettingsController(settingsRepo)
 
-	// Middleware to restrict modification to admin or super_admin
-	// Note: RequireRoles("admin") allows both "admin" and "super_admin" roles (super_admin bypass)
-	restrictModification := middleware.RequireRoles("admin")
+	// Writes require a declared permission; super admins pass every check.
+	// The permissions are seeded with a grant to the "admin" role.
+	noImpersonation := middleware.BlockImpersonation()
 
 	// Region Routes
-	settingsGroup.Post("/regions", restrictModification, settingsCtrl.CreateRegion)
+	middleware.PermissionRoute(settingsGroup, fiber.MethodPost, "/regions", authz.PermSettingsRegionsWrite, settingsCtrl.CreateRegion)
 	settingsGroup.Get("/regions", settingsCtrl.ListRegions)
 	settingsGroup.Get("/regions/:id", settingsCtrl.GetRegion)
-	settingsGroup.Put("/regions/:id", restrictModification, settingsCtrl.UpdateRegion)
-	settingsGroup.Delete("/regions/:id", restrictModification, settingsCtrl.DeleteRegion)
+	middleware.PermissionRoute(settingsGroup, fiber.MethodPut, "/regions/:id", authz.PermSettingsRegionsWrite, settingsCtrl.UpdateRegion)
+	middleware.PermissionRoute(settingsGroup, fiber.MethodDelete, "/regions/:id", authz.PermSettingsRegionsDelete, noImpersonation, settingsCtrl.DeleteRegion)
 
 	// City Routes
-	settingsGroup.Post("/cities", restrictModification, settingsCtrl.CreateCity)
+	middleware.PermissionRoute(settingsGroup, fiber.MethodPost, "/cities", authz.PermSettingsCitiesWrite, settingsCtrl.CreateCity)
 	settingsGroup.Get("/cities", settingsCtrl.ListCities)
 	settingsGroup.Get("/cities/:id", settingsCtrl.GetCity)
-	settingsGroup.Put("/cities/:id", restrictModification, settingsCtrl.UpdateCity)
-	settingsGroup.Delete("/cities/:id", restrictModification, settingsCtrl.DeleteCity)
+	middleware.PermissionRoute(settingsGroup, fiber.MethodPut, "/cities/:id", authz.PermSettingsCitiesWrite, settingsCtrl.UpdateCity)
+	middleware.PermissionRoute(settingsGroup, fiber.MethodDelete, "/cities/:id", authz.PermSettingsCitiesDelete, noImpersonation, settingsCtrl.DeleteCity)
 
 	// Area Routes
-	settingsGroup.Post("/areas", restrictModification, settingsCtrl.CreateArea)
+	middleware.PermissionRoute(settingsGroup, fiber.MethodPost, "/areas", authz.PermSettingsAreasWrite, settingsCtrl.CreateArea)
 	settingsGroup.Get("/areas", settingsCtrl.ListAreas)
 	settingsGroup.Get("/areas/:id", settingsCtrl.GetArea)
-	settingsGroup.Put("/areas/:id", restrictModification, settingsCtrl.UpdateArea)
-	settingsGroup.Delete("/areas/:id", restrictModification, settingsCtrl.DeleteArea)
+	middleware.PermissionRoute(settingsGroup, fiber.MethodPut, "/areas/:id", authz.PermSettingsAreasWrite, settingsCtrl.UpdateArea)
+	middleware.PermissionRoute(settingsGroup, fiber.MethodDelete, "/areas/:id", authz.PermSettingsAreasDelete, noImpersonation, settingsCtrl.DeleteArea)
 
 	// Vehicle Type Routes
-	settingsGroup.Post("/vehicle-types", restrictModification, settingsCtrl.CreateVehicleType)
+	middleware.PermissionRoute(settingsGroup, fiber.MethodPost, "/vehicle-types", authz.PermSettingsVehicleTypesWrite, settingsCtrl.CreateVehicleType)
 	settingsGroup.Get("/vehicle-types", settingsCtrl.ListVehicleTypes)
 	settingsGroup.Get("/vehicle-types/:id", settingsCtrl.GetVehicleType)
-	settingsGroup.Put("/vehicle-types/:id", restrictModification, settingsCtrl.UpdateVehicleType)
-	settingsGroup.Delete("/vehicle-types/:id", restrictModification, settingsCtrl.DeleteVehicleType)
+	middleware.PermissionRoute(settingsGroup, fiber.MethodPut, "/vehicle-types/:id", authz.PermSettingsVehicleTypesWrite, settingsCtrl.UpdateVehicleType)
+	middleware.PermissionRoute(settingsGroup, fiber.MethodDelete, "/vehicle-types/:id", authz.PermSettingsVehicleTypesDelete, noImpersonation, settingsCtrl.DeleteVehicleType)
 
 	// Vehicle Brand Routes
-	settingsGroup.Post("/vehicle-brands", restrictModification, settingsCtrl.CreateVehicleBrand)
+	middleware.PermissionRoute(settingsGroup, fiber.MethodPost, "/vehicle-brands", authz.PermSettingsVehicleBrandsWrite, settingsCtrl.CreateVehicleBrand)
 	settingsGroup.Get("/vehicle-brands", settingsCtrl.ListVehicleBrands)
 	settingsGroup.Get("/vehicle-brands/:id", settingsCtrl.GetVehicleBrand)
-	settingsGroup.Put("/vehicle-brands/:id", restrictModification, settingsCtrl.UpdateVehicleBrand)
-	settingsGroup.Delete("/vehicle-brands/:id", restrictModification, settingsCtrl.DeleteVehicleBrand)
+	middleware.PermissionRoute(settingsGroup, fiber.MethodPut, "/vehicle-brands/:id", authz.PermSettingsVehicleBrandsWrite, settingsCtrl.UpdateVehicleBrand)
+	middleware.PermissionRoute(settingsGroup, fiber.MethodDelete, "/vehicle-brands/:id", authz.PermSettingsVehicleBrandsDelete, noImpersonation, settingsCtrl.DeleteVehicleBrand)
 
 	// Public Routes (No Auth)
 	publicGroup := v1.Group("/public/settings")